@@ -0,0 +1,167 @@
+package swissarmyknife
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+)
+
+// DefaultCheckpointInterval is the interval the processor stores the checkpoint when none is set.
+const DefaultCheckpointInterval = 10 * time.Second
+
+// Checkpoint is the state of the process stored into the checkpoint file, to resume it from the position reached in
+// the input (see ChannelConveyorProcessor.WithCheckpoint).
+type Checkpoint struct {
+	// Position is the position in the input after the last record of the ones settled, all the records read up to
+	// it being written into the output, dropped or failed.
+	Position sakio.Position `json:"position"`
+	// Stats are the counters of the records settled up to the position.
+	Stats Stats `json:"stats"`
+}
+
+// LoadCheckpoint reads the checkpoint stored into the file.
+// Returns nil when the file does not exist.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var c Checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, &os.PathError{Op: "decode", Path: path, Err: err}
+	}
+
+	return &c, nil
+}
+
+// storeCheckpoint writes the checkpoint into the file, replaced atomically once the checkpoint is synced.
+func storeCheckpoint(path string, c Checkpoint) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name()) // nolint:errcheck
+	}
+
+	return err
+}
+
+// settlement is how a record left the process.
+type settlement int
+
+const (
+	written settlement = iota + 1
+	dropped
+	failed
+)
+
+// checkpointTracker tracks the records read until they are settled, written into the output, dropped or failed, to
+// find out the position in the input up to which all the records are settled, the checkpoint.
+//
+// The records are numbered in the order they are read, starting from 1. A nil tracker tracks nothing.
+type checkpointTracker struct {
+	mu sync.Mutex
+	// pending are the records read not settled yet, or settled before a previous one.
+	pending    map[int64]*trackedRecord
+	settled    int64
+	checkpoint Checkpoint
+}
+
+// trackedRecord is a record read, along with the position in the input after it.
+type trackedRecord struct {
+	position   sakio.Position
+	settlement settlement
+}
+
+// newCheckpointTracker creates the tracker, starting from the checkpoint.
+func newCheckpointTracker(checkpoint Checkpoint) *checkpointTracker {
+	return &checkpointTracker{
+		pending:    make(map[int64]*trackedRecord),
+		checkpoint: checkpoint,
+	}
+}
+
+// read tracks the record read, numbered seq, and the position in the input after it.
+func (t *checkpointTracker) read(seq int64, position sakio.Position) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending[seq] = &trackedRecord{position: position}
+}
+
+// settle settles the record numbered seq, moving the checkpoint forward along the records settled in order.
+func (t *checkpointTracker) settle(seq int64, s settlement) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending[seq].settlement = s
+
+	for {
+		r, ok := t.pending[t.settled+1]
+		if !ok || r.settlement == 0 {
+			return
+		}
+
+		t.settled++
+		delete(t.pending, t.settled)
+
+		t.checkpoint.Position = r.position
+		t.checkpoint.Stats.Read++
+
+		switch r.settlement {
+		case written:
+			t.checkpoint.Stats.Written++
+		case dropped:
+			t.checkpoint.Stats.Dropped++
+		case failed:
+			t.checkpoint.Stats.Failed++
+		}
+	}
+}
+
+// current returns the checkpoint reached.
+func (t *checkpointTracker) current() Checkpoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.checkpoint
+}
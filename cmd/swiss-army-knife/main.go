@@ -1,13 +1,17 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	swiss_army_knife "github.com/dohernandez/swiss-army-knife"
 	sakio "github.com/dohernandez/swiss-army-knife/io"
@@ -18,15 +22,78 @@ import (
 
 const (
 	filterKey    = "filter"
+	selectKey    = "select"
+	whereKey     = "where"
+	setKey       = "set"
 	appendKey    = "append"
 	removeKey    = "remove"
 	prefixingKey = "prefix"
+
+	inputKey          = "input"
+	annotateSourceKey = "annotate-source"
+	followKey         = "follow"
+	followOffsetKey   = "follow-offset"
+	listenKey         = "listen"
+	annotateConnKey   = "annotate-connection"
+	maxConnectionsKey = "max-connections"
+	maxRecordSizeKey  = "max-record-size"
+	inputFormatKey    = "input-format"
+	jsonPointerKey    = "json-pointer"
+	inputColumnsKey   = "input-columns"
+	inferTypesKey     = "infer-types"
+
+	protoDescriptorKey    = "proto-descriptor"
+	protoMessageKey       = "proto-message"
+	outputProtoMessageKey = "output-proto-message"
+
+	avroSchemaKey = "avro-schema"
+	avroCodecKey  = "avro-codec"
+
+	outputKey         = "output"
+	rotateSizeKey     = "rotate-size"
+	rotateRecordsKey  = "rotate-records"
+	rotateIntervalKey = "rotate-interval"
+	compressKey       = "compress"
+	outputFormatKey   = "output-format"
+	outputColumnsKey  = "output-columns"
+	httpBatchSizeKey  = "http-batch-size"
+	httpTimeoutKey    = "http-timeout"
+	httpRetriesKey    = "http-retries"
+
+	flushIntervalKey = "flush-interval"
+	workersKey       = "workers"
+	orderedKey       = "ordered"
+	deadLetterKey    = "dead-letter"
+	failFastKey      = "fail-fast"
+	maxErrorsKey     = "max-errors"
+	maxErrorRateKey  = "max-error-rate"
+	minRecordsKey    = "min-records"
+
+	checkpointKey         = "checkpoint"
+	checkpointIntervalKey = "checkpoint-interval"
+	resumeKey             = "resume"
 )
 
 var binaryName = "swiss-army-knife"
 
 var errInvalidPairKeyValue = errors.New("invalid pair key/value. Valid format key:value")
 
+// operationFlags are the flags creating registered operations, in the order the operations are applied.
+// nolint:gochecknoglobals
+var operationFlags = []struct {
+	flag      string
+	operation string
+	params    func(value string) (map[string]interface{}, error)
+}{
+	{flag: filterKey, operation: "filter", params: stringParam("criteria")},
+	{flag: selectKey, operation: "select", params: stringParam("criteria")},
+	{flag: whereKey, operation: "where", params: stringParam("expression")},
+	{flag: setKey, operation: "set", params: stringParam("expression")},
+	{flag: appendKey, operation: "append", params: pairsParam},
+	{flag: removeKey, operation: "remove", params: keysParam},
+	{flag: prefixingKey, operation: "prefix", params: pairsParam},
+}
+
 func main() {
 	if len(os.Args) > 1 && os.Args[1] == "version" {
 		fmt.Println(version.Info().String())
@@ -36,18 +103,40 @@ func main() {
 	ctx, cancelCtx := context.WithCancel(context.TODO())
 	defer cancelCtx()
 
+	// cancel the context on SIGINT/SIGTERM, the processor stops reading and flushes what was already processed.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		cancelCtx()
+	}()
+
 	app := cli.NewApp()
 	app.Version = version.Info().Version
 	app.Name = binaryName
 
 	app.Usage = "To give some background, the stream of JSON objects can be locations updates from drivers, comments about rides etc. "
-	app.UsageText = fmt.Sprintf("%s [arguments]", binaryName)
+	app.UsageText = fmt.Sprintf("%s [arguments]\n   %s run --%s pipeline.yaml", binaryName, binaryName, pipelineKey)
 	app.HideVersion = true
 
+	// keys are paths to nested values, i.e. driver.location.lat or stops[0].id
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
 			Name:  filterKey + ", f",
-			Usage: "Filter out base on key/value pair. Valid format key:value;keyn:valuen. Example id:347.",
+			Usage: "Filter out: DROP the records matching the criteria. Conditions are key<op>value with op one of ==, :, !=, <, <=, >, >=, ^= (prefix), $= (suffix), *= (contains), =~ (regex), !~, in [a,b], not in [a,b], exists or missing; combined with ; (and), | (or), ! (not) and parentheses. Example id:347;driver.location.city:paris or 'speed > 100 | status in [stopped,parked]'.",
+		},
+		cli.StringFlag{
+			Name:  selectKey + ", s",
+			Usage: "Select: KEEP only the records matching the criteria, the counterpart of --filter, same criteria syntax. Example id:347.",
+		},
+		cli.StringFlag{
+			Name:  whereKey,
+			Usage: "Keep only the records for which the expression is true. Example 'speed > 80 && city == \"paris\"'.",
+		},
+		cli.StringFlag{
+			Name:  setKey,
+			Usage: "Set keys to the value of expressions, applied after --where. Valid format key = expr;keyn = exprn. Example 'distance_km = dist_m / 1000'.",
 		},
 		cli.StringFlag{
 			Name:  appendKey + ", a",
@@ -61,96 +150,282 @@ func main() {
 			Name:  prefixingKey + ", p",
 			Usage: "Prefixing a key. Valid format key:value;keyn:valuen. Example id:347.",
 		},
+		cli.StringSliceFlag{
+			Name:  inputKey + ", i",
+			Usage: "Read the records from the files instead of stdin, in order. Files can be globs or directories, read recursively, and compressed with gzip, zstd or bzip2. Example 'dump/*.json_dump.gz'.",
+		},
+		cli.BoolFlag{
+			Name:  annotateSourceKey,
+			Usage: "Add the source file and line number of the records read from --input or --follow under _source_file and _source_line.",
+		},
+		cli.StringFlag{
+			Name:  followKey + ", F",
+			Usage: "Read the records of the file as it grows instead of stdin, until interrupted, like tail -F does, following the file created in its place when rotated and reading it again when truncated. Example /var/log/rides.ndjson.",
+		},
+		cli.StringFlag{
+			Name:  followOffsetKey,
+			Usage: "File the offset of the records processed from the --follow file is stored into, to resume from it after a restart, the records read but not processed being read again. Example rides.offset.",
+		},
+		cli.StringFlag{
+			Name:  listenKey,
+			Usage: "Read the records pushed by the peers connected to a TCP address or a Unix socket, formats ndjson, msgpack, cbor and protobuf, or posted to an HTTP endpoint as NDJSON or JSON arrays, instead of stdin, until interrupted. Example tcp://:7070, unix:///run/sak.sock or http://:8080/ingest.",
+		},
+		cli.BoolFlag{
+			Name:  annotateConnKey,
+			Usage: "Add the remote address and the ID of the connection of the records read from --listen under _remote_addr and _connection.",
+		},
+		cli.IntFlag{
+			Name:  maxConnectionsKey,
+			Usage: "Max amount of connections read at the same time by --listen, the following ones waiting until a connection is closed. Zero means no limit.",
+		},
+		cli.StringFlag{
+			Name:  inputFormatKey,
+			Usage: "Format of the records read: ndjson, one JSON value per line, json, the elements of a top level array or concatenated (i.e. pretty printed) JSON values, csv or tsv, one record per row keyed by the header row, only from stdin, msgpack or cbor, one value per frame prefixed by its length (32 bit big endian), protobuf, length-delimited messages of the --proto-message type, or avro, an Avro object container file decoded with its embedded schema.",
+			Value: "ndjson",
+		},
+		cli.StringFlag{
+			Name:  jsonPointerKey,
+			Usage: "JSON pointer to the nested array whose elements are the records read, with --input-format json. Example /data/items.",
+		},
+		cli.StringFlag{
+			Name:  inputColumnsKey,
+			Usage: "Column names of the csv or tsv rows read, the first row being then a record instead of the header. Example id,lat,lng.",
+		},
+		cli.BoolFlag{
+			Name:  inferTypesKey,
+			Usage: "Infer the type of the csv or tsv fields read: numbers, true, false and empty (null). Otherwise all the fields are strings.",
+		},
+		cli.StringFlag{
+			Name:  protoDescriptorKey,
+			Usage: "FileDescriptorSet file (protoc --include_imports --descriptor_set_out) declaring the message types of the protobuf format. Example rides.pb.",
+		},
+		cli.StringFlag{
+			Name:  protoMessageKey,
+			Usage: "Full name of the message type of the protobuf records read, and written unless --output-proto-message is given. Example rides.v1.Location.",
+		},
+		cli.StringFlag{
+			Name:  outputProtoMessageKey,
+			Usage: "Full name of the message type of the protobuf records written, i.e. declaring the keys appended or prefixed. The record keys not matching a field are discarded.",
+		},
+		cli.StringFlag{
+			Name:  avroSchemaKey,
+			Usage: "Avro schema file (.avsc) of the avro records written. By default inferred from the first record, all the fields nullable. The record keys not matching a field are discarded. Example ride.avsc.",
+		},
+		cli.StringFlag{
+			Name:  avroCodecKey,
+			Usage: "Codec compressing the blocks of the avro records written: null, deflate or snappy.",
+			Value: "null",
+		},
+		cli.IntFlag{
+			Name:  maxRecordSizeKey,
+			Usage: "Max size in bytes of the records read, the records exceeding it fail and are skipped, written to the --dead-letter file truncated to the max size.",
+			Value: sakio.DefaultMaxRecordSize,
+		},
+		cli.StringFlag{
+			Name:  outputKey + ", o",
+			Usage: "Write the records into files instead of stdout, named by the path template. %Y, %m, %d, %H, %M and %S are replaced by the UTC time the file is opened and {n} by the file number, required to rotate the files. Files are visible once complete. Example 'out/%Y-%m-%d/part-{n}.ndjson'. Or post them in batches as NDJSON to the http:// or https:// URL.",
+		},
+		cli.Int64Flag{
+			Name:  rotateSizeKey,
+			Usage: "Rotate the --output file once its size in bytes, before compression, is reached. Zero means no limit.",
+		},
+		cli.Int64Flag{
+			Name:  rotateRecordsKey,
+			Usage: "Rotate the --output file once the amount of records is reached. Zero means no limit.",
+		},
+		cli.DurationFlag{
+			Name:  rotateIntervalKey,
+			Usage: "Rotate the --output file once the interval since it was opened elapsed. Zero means no limit. Example 1h.",
+		},
+		cli.StringFlag{
+			Name:  compressKey,
+			Usage: "Compress the --output files with gzip or zstd, adding the .gz or .zst extension.",
+		},
+		cli.StringFlag{
+			Name:  outputFormatKey,
+			Usage: "Format of the records written: ndjson, one JSON value per line, csv or tsv, one row per record with nested keys flattened (i.e. driver.city), only to stdout, msgpack or cbor, one value per frame prefixed by its length (32 bit big endian), protobuf, length-delimited messages of the --output-proto-message type, or avro, an Avro object container file of the --avro-schema, only to stdout.",
+			Value: "ndjson",
+		},
+		cli.StringFlag{
+			Name:  outputColumnsKey,
+			Usage: "Columns, in order, of the csv or tsv rows written. By default the keys of the first record sorted. Example id,driver.city.",
+		},
+		cli.DurationFlag{
+			Name:  flushIntervalKey,
+			Usage: "Interval to flush the records written to stdout, or posted to --output URL even if the batch is not full. Zero flushes after each record, or posts only full batches. Example 500ms.",
+			Value: time.Second,
+		},
+		cli.IntFlag{
+			Name:  httpBatchSizeKey,
+			Usage: "Amount of records posted at once to --output URL.",
+			Value: sakio.DefaultHTTPBatchSize,
+		},
+		cli.DurationFlag{
+			Name:  httpTimeoutKey,
+			Usage: "Timeout of each request posting the records to --output URL.",
+			Value: sakio.DefaultHTTPTimeout,
+		},
+		cli.IntFlag{
+			Name:  httpRetriesKey,
+			Usage: "Amount of times a request posting the records to --output URL is retried on network errors, timeouts, 5xx and 429 status codes, with an exponential backoff.",
+			Value: sakio.DefaultHTTPRetries,
+		},
+		cli.IntFlag{
+			Name:  workersKey + ", w",
+			Usage: "Amount of workers applying each operation concurrently.",
+			Value: 1,
+		},
+		cli.BoolFlag{
+			Name:  orderedKey,
+			Usage: "Output the records in the same order they were inputted when using more than one worker.",
+		},
+		cli.StringFlag{
+			Name:  deadLetterKey,
+			Usage: "File where the records that failed processing are written to, as they were inputted. Example failed.ndjson.",
+		},
+		cli.BoolFlag{
+			Name:  failFastKey,
+			Usage: "Abort on the first record that failed processing.",
+		},
+		cli.Int64Flag{
+			Name:  maxErrorsKey,
+			Usage: "Abort once the amount of records that failed processing is reached. Zero means no limit.",
+		},
+		cli.Float64Flag{
+			Name:  maxErrorRateKey,
+			Usage: "Abort once the rate (from 0 to 1) of records that failed processing is exceeded. Zero means no limit. Example 0.1.",
+		},
+		cli.Int64Flag{
+			Name:  minRecordsKey,
+			Usage: "Amount of records read before the max error rate is taken into account.",
+			Value: swiss_army_knife.DefaultMinRecords,
+		},
+		cli.StringFlag{
+			Name:  checkpointKey,
+			Usage: "File the checkpoint is stored into, the position in the --input files up to which the records are written along with the counters, to resume from it. The --output files are made visible at each checkpoint, {n} is required in the path template. Example rides.checkpoint.",
+		},
+		cli.DurationFlag{
+			Name:  checkpointIntervalKey,
+			Usage: "Interval to store the --checkpoint, once the records written are flushed.",
+			Value: swiss_army_knife.DefaultCheckpointInterval,
+		},
+		cli.BoolFlag{
+			Name:  resumeKey,
+			Usage: "Resume from the --checkpoint, if stored, instead of reading the --input files from the beginning. The --dead-letter file is appended to.",
+		},
 	}
 
 	app.Action = func(cliCtx *cli.Context) error {
-		input := initInput()
-
-		output := initOutput()
+		input, err := initInput(inputConfig{
+			paths:           cliCtx.StringSlice(inputKey),
+			annotateSource:  cliCtx.Bool(annotateSourceKey),
+			maxRecordSize:   cliCtx.Int(maxRecordSizeKey),
+			format:          cliCtx.String(inputFormatKey),
+			jsonPointer:     cliCtx.String(jsonPointerKey),
+			columns:         splitColumns(cliCtx.String(inputColumnsKey)),
+			inferTypes:      cliCtx.Bool(inferTypesKey),
+			protoDescriptor: cliCtx.String(protoDescriptorKey),
+			protoMessage:    cliCtx.String(protoMessageKey),
+			follow:          cliCtx.String(followKey),
+			followOffset:    cliCtx.String(followOffsetKey),
+			listen:          cliCtx.String(listenKey),
+			annotateConn:    cliCtx.Bool(annotateConnKey),
+			maxConnections:  cliCtx.Int(maxConnectionsKey),
+		})
+		if err != nil {
+			return errors.Wrap(err, "input")
+		}
+		// nolint:errcheck
+		defer closeInput(input)
 
-		p := swiss_army_knife.ChannelConveyorProcessor{}
+		outputProtoMessage := cliCtx.String(outputProtoMessageKey)
+		if outputProtoMessage == "" {
+			outputProtoMessage = cliCtx.String(protoMessageKey)
+		}
 
-		// init operations
-		var operations []swiss_army_knife.Operation
+		output, err := initOutput(outputConfig{
+			format:           cliCtx.String(outputFormatKey),
+			columns:          splitColumns(cliCtx.String(outputColumnsKey)),
+			flushInterval:    cliCtx.Duration(flushIntervalKey),
+			path:             cliCtx.String(outputKey),
+			maxSize:          cliCtx.Int64(rotateSizeKey),
+			maxRecords:       cliCtx.Int64(rotateRecordsKey),
+			rotationInterval: cliCtx.Duration(rotateIntervalKey),
+			compression:      cliCtx.String(compressKey),
+			protoDescriptor:  cliCtx.String(protoDescriptorKey),
+			protoMessage:     outputProtoMessage,
+			avroSchema:       cliCtx.String(avroSchemaKey),
+			avroCodec:        cliCtx.String(avroCodecKey),
+			batchSize:        cliCtx.Int(httpBatchSizeKey),
+			timeout:          cliCtx.Duration(httpTimeoutKey),
+			retries:          cliCtx.Int(httpRetriesKey),
+		})
+		if err != nil {
+			return errors.Wrap(err, "output")
+		}
+		// nolint:errcheck
+		defer output.Close(ctx)
 
-		// Filter out base on key/value pair.
-		if cliCtx.String(filterKey) != "" {
-			value := cliCtx.String(filterKey)
+		errorPolicy := swiss_army_knife.ErrorPolicy{
+			MaxErrors:    cliCtx.Int64(maxErrorsKey),
+			MaxErrorRate: cliCtx.Float64(maxErrorRateKey),
+			MinRecords:   cliCtx.Int64(minRecordsKey),
+		}
+		if cliCtx.Bool(failFastKey) {
+			errorPolicy = swiss_army_knife.FailFast
+		}
 
-			kvs, err := splitPairs(value)
-			if err != nil {
-				return errors.Wrap(err, fmt.Sprintf("%s (%s)", filterKey, value))
-			}
+		p := initProcessor(cliCtx.Int(workersKey), cliCtx.Bool(orderedKey), errorPolicy)
 
-			var pairs []swiss_army_knife.PairKeyValue
-			for _, pair := range kvs {
-				pairs = append(pairs, swiss_army_knife.PairKeyValue{
-					Key:   swiss_army_knife.Key(pair[0]),
-					Value: swiss_army_knife.Value(pair[1]),
-				})
-			}
-			operations = append(operations, swiss_army_knife.NewFilteringOperation(ctx, pairs))
+		checkpoint := checkpointConfig{
+			file:     cliCtx.String(checkpointKey),
+			interval: cliCtx.Duration(checkpointIntervalKey),
+			resume:   cliCtx.Bool(resumeKey),
 		}
 
-		if cliCtx.String(appendKey) != "" {
-			value := cliCtx.String(appendKey)
+		if err := initCheckpoint(p, checkpoint, cliCtx.StringSlice(inputKey), cliCtx.String(outputKey)); err != nil {
+			return errors.Wrap(err, checkpointKey)
+		}
 
-			kvs, err := splitPairs(value)
+		if cliCtx.String(deadLetterKey) != "" {
+			deadLetter, err := initDeadLetter(cliCtx.String(deadLetterKey), formatFraming(cliCtx.String(inputFormatKey)), checkpoint.resume)
 			if err != nil {
-				return errors.Wrap(err, fmt.Sprintf("%s (%s)", appendKey, value))
+				return errors.Wrap(err, fmt.Sprintf("%s (%s)", deadLetterKey, cliCtx.String(deadLetterKey)))
 			}
+			// nolint:errcheck
+			defer deadLetter.Close(ctx)
 
-			var pairs []swiss_army_knife.PairKeyValue
-			for _, pair := range kvs {
-				pairs = append(pairs, swiss_army_knife.PairKeyValue{
-					Key:   swiss_army_knife.Key(pair[0]),
-					Value: swiss_army_knife.Value(pair[1]),
-				})
-			}
-			operations = append(operations, swiss_army_knife.NewAppendInformationOperation(ctx, pairs))
+			p.WithDeadLetter(deadLetter)
 		}
 
-		if cliCtx.String(removeKey) != "" {
-			var keys []swiss_army_knife.Key
-			for _, key := range strings.Split(cliCtx.String(removeKey), ":") {
-				keys = append(keys, swiss_army_knife.Key(key))
-			}
-			operations = append(operations, swiss_army_knife.NewRemoveInformationOperation(ctx, keys))
-		}
+		// init operations, applied in the order of operationFlags.
+		var operations []swiss_army_knife.Operation
 
-		// Prefixing a key.
-		if cliCtx.String(prefixingKey) != "" {
-			value := cliCtx.String(prefixingKey)
+		for _, of := range operationFlags {
+			value := cliCtx.String(of.flag)
+			if value == "" {
+				continue
+			}
 
-			kvs, err := splitPairs(value)
+			params, err := of.params(value)
 			if err != nil {
-				return errors.Wrap(err, fmt.Sprintf("%s (%s)", prefixingKey, value))
+				return errors.Wrap(err, fmt.Sprintf("%s (%s)", of.flag, value))
 			}
 
-			var pairs []swiss_army_knife.PairKeyPrefix
-			for _, pair := range kvs {
-				pairs = append(pairs, swiss_army_knife.PairKeyPrefix{
-					Key:    swiss_army_knife.Key(pair[0]),
-					Prefix: pair[1],
-				})
+			op, err := swiss_army_knife.NewRegisteredOperation(ctx, of.operation, params)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("%s (%s)", of.flag, value))
 			}
-			operations = append(operations, swiss_army_knife.NewPrefixKeyOperation(ctx, pairs))
-		}
 
-		// Process data
-		if err := p.Process(ctx, input, output, operations...); err != nil {
-			return err
+			operations = append(operations, op)
 		}
 
-		// Checking if there were any error while processing data
-		if len(p.Errors()) > 0 {
-			for _, err := range p.Errors() {
-				// TODO For the purpose of this iteration is enough printing the errors.
-				fmt.Println(err)
-			}
-		}
+		return process(ctx, p, input, output, operations)
+	}
 
-		return nil
+	app.Commands = []cli.Command{
+		runCommand(ctx),
+		operationsCommand(),
 	}
 
 	err := app.Run(os.Args)
@@ -159,51 +434,626 @@ func main() {
 	}
 }
 
-func initInput() *sakio.StdinInput {
+// initProcessor creates the processor, records are processed by the amount of workers given applying the error policy.
+func initProcessor(workers int, ordered bool, errorPolicy swiss_army_knife.ErrorPolicy) *swiss_army_knife.ChannelConveyorProcessor {
+	p := &swiss_army_knife.ChannelConveyorProcessor{}
+	// records are owned by one operation at a time, no need to copy them between operations.
+	p.WithCodec(swiss_army_knife.PassThroughCodec{}).
+		WithWorkers(workers).
+		WithErrorPolicy(errorPolicy)
+
+	if ordered {
+		p.WithOrderPreserved()
+	}
+
+	return p
+}
+
+// checkpointConfig is the configuration of the checkpoint of the process.
+type checkpointConfig struct {
+	// file is the file the checkpoint is stored into, none is stored when empty.
+	file     string
+	interval time.Duration
+	// resume resumes the process from the checkpoint stored into the file, if any.
+	resume bool
+}
+
+// initCheckpoint sets the processor to store the checkpoint, resuming from the one stored when asked. Only the input
+// files are resumable, and the output files must be numbered, being made visible at each checkpoint.
+func initCheckpoint(p *swiss_army_knife.ChannelConveyorProcessor, c checkpointConfig, inputPaths []string, outputPath string) error {
+	if c.file == "" {
+		if c.resume {
+			return errors.New("resume is only available with a checkpoint file")
+		}
+
+		return nil
+	}
+
+	if len(inputPaths) == 0 {
+		return errors.New("checkpoint is only available when reading input files")
+	}
+
+	isURL := strings.HasPrefix(outputPath, "http://") || strings.HasPrefix(outputPath, "https://")
+	if outputPath != "" && !isURL && !strings.Contains(outputPath, "{n}") {
+		return errors.New("checkpoint requires {n} in the output path template, the output files being made visible at each checkpoint")
+	}
+
+	p.WithCheckpoint(c.file, c.interval)
+
+	if !c.resume {
+		return nil
+	}
+
+	checkpoint, err := swiss_army_knife.LoadCheckpoint(c.file)
+	if err != nil {
+		return err
+	}
+
+	if checkpoint != nil {
+		p.WithResume(*checkpoint)
+	}
+
+	return nil
+}
+
+// process processes the input data into the output, reporting the errors and a summary of the records processed
+// to stderr when any record failed.
+func process(
+	ctx context.Context,
+	p *swiss_army_knife.ChannelConveyorProcessor,
+	input sakio.Input,
+	output sakio.Output,
+	operations []swiss_army_knife.Operation,
+) error {
+	// Process data, being cancelled by a signal is a graceful shutdown.
+	err := p.Process(ctx, input, output, operations...)
+	if err != nil && err != context.Canceled && err != swiss_army_knife.ErrAborted {
+		return err
+	}
+
+	// Checking if there were any error while processing data, reported to stderr to not mix them with the data.
+	for _, err := range p.Errors() {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	stats := p.Stats()
+	if stats.Failed == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(
+		os.Stderr,
+		"records read: %d, written: %d, dropped: %d, failed: %d\n",
+		stats.Read, stats.Written, stats.Dropped, stats.Failed,
+	)
+
+	if err == swiss_army_knife.ErrAborted {
+		return err
+	}
+
+	return errors.Errorf("%d records failed processing", stats.Failed)
+}
+
+// inputConfig is the configuration of the input.
+type inputConfig struct {
+	// paths are the files to read the records from, stdin when empty.
+	paths          []string
+	annotateSource bool
+	maxRecordSize  int
+	// follow is the file to read the records from as it grows, instead of the files or stdin, followOffset the file
+	// the offset reached is stored into.
+	follow       string
+	followOffset string
+	// listen is the address to read the records pushed by the connected peers from, instead of the files or stdin.
+	listen         string
+	annotateConn   bool
+	maxConnections int
+	// format is either ndjson, the default, json, csv, tsv, msgpack, cbor, protobuf or avro.
+	format      string
+	jsonPointer string
+	// columns are the column names of the csv or tsv rows, taken from the header row when empty.
+	columns    []string
+	inferTypes bool
+	// protoDescriptor is the FileDescriptorSet file declaring the protoMessage type of the protobuf records.
+	protoDescriptor string
+	protoMessage    string
+}
+
+// initInput creates the input, reading the records from the files matching the paths, annotated with their
+// source when asked, from the connections to the listen address, or from stdin when no path is given. The records
+// exceeding the max record size are skipped.
+func initInput(c inputConfig) (sakio.Input, error) {
+	unmarshal := unmarshalJSON
+
+	if c.listen != "" && len(c.paths) > 0 {
+		return nil, errors.New("input files and listen address are exclusive")
+	}
+
+	if c.follow != "" {
+		return initFollowInput(c)
+	}
+
+	if c.followOffset != "" {
+		return nil, errors.New("follow offset file is only available when following a file")
+	}
+
+	switch c.format {
+	case "", "ndjson", "json":
+	case "msgpack":
+		unmarshal = sakio.UnmarshalMsgpack
+	case "cbor":
+		unmarshal = sakio.UnmarshalCBOR
+	case "protobuf":
+		message, err := loadProtoMessage(c.protoDescriptor, c.protoMessage)
+		if err != nil {
+			return nil, err
+		}
+
+		unmarshal = message.Unmarshal
+	case "avro":
+		// the records are read from the files as JSON, see sakio.AvroRecordReader.
+	case "csv", "tsv":
+		if len(c.paths) > 0 || c.listen != "" {
+			return nil, errors.Errorf("%s input format is only available for stdin", c.format)
+		}
+
+		input := sakio.NewCSVInput(os.Stdin).
+			WithColumns(c.columns...)
+
+		if c.format == "tsv" {
+			input.WithDelimiter('\t')
+		}
+
+		if c.inferTypes {
+			input.WithTypeInference()
+		}
+
+		return input, nil
+	default:
+		return nil, errors.Errorf("unknown input format %q, valid are ndjson, json, csv, tsv, msgpack, cbor, protobuf and avro", c.format)
+	}
+
+	if strings.HasPrefix(c.listen, "http://") {
+		return initHTTPInput(c)
+	}
+
+	if c.listen != "" {
+		return initSocketInput(c, unmarshal)
+	}
+
+	if len(c.paths) > 0 {
+		input := sakio.NewFileInput(c.paths...).
+			WithMaxRecordSize(c.maxRecordSize).
+			WithFraming(formatFraming(c.format)).
+			WithUnmarshaling(unmarshal)
+
+		if c.format == "json" {
+			input.WithJSONStream(c.jsonPointer)
+		}
+
+		if c.format == "avro" {
+			input.WithAvro()
+		}
+
+		if c.annotateSource {
+			input.WithSourceAnnotation("_source_file", "_source_line")
+		}
+
+		return input, nil
+	}
+
+	if c.format == "json" {
+		return sakio.NewJSONStreamInput(os.Stdin).
+			WithPointer(c.jsonPointer).
+			WithUnmarshaling(unmarshalJSON), nil
+	}
+
+	if c.format == "avro" {
+		return sakio.NewAvroInput(os.Stdin), nil
+	}
+
+	if framing := formatFraming(c.format); framing != sakio.NoFraming {
+		return sakio.NewFrameInput(os.Stdin).
+			WithFraming(framing).
+			WithMaxFrameSize(c.maxRecordSize).
+			WithUnmarshaling(unmarshal), nil
+	}
+
 	// create input Stdin
-	scanner := bufio.NewScanner(os.Stdin)
-	input := sakio.NewStdinInput(scanner)
+	input := sakio.NewStdinReaderInput(os.Stdin).
+		WithMaxRecordSize(c.maxRecordSize)
 	// add unmarshal to decode input value
-	input.WithUnmarshaling(func(_ context.Context, i string) (interface{}, error) {
-		var a interface{}
+	input.WithUnmarshaling(unmarshalJSON)
+
+	return input, nil
+}
+
+// initFollowInput creates the input reading the records of the followed file as it grows, annotated with their
+// source when asked.
+func initFollowInput(c inputConfig) (sakio.Input, error) {
+	if len(c.paths) > 0 || c.listen != "" {
+		return nil, errors.New("followed file, input files and listen address are exclusive")
+	}
+
+	if c.format != "" && c.format != "ndjson" {
+		return nil, errors.Errorf("%s input format is not available when following a file", c.format)
+	}
+
+	// the offset stored is the one of the records acknowledged by the processor, read again after a restart otherwise.
+	input := sakio.NewFileInput(c.follow).
+		WithFollow(0).
+		WithOffsetFile(c.followOffset).
+		WithAcknowledgement().
+		WithMaxRecordSize(c.maxRecordSize).
+		WithUnmarshaling(unmarshalJSON)
+
+	if c.annotateSource {
+		input.WithSourceAnnotation("_source_file", "_source_line")
+	}
+
+	return input, nil
+}
+
+// initSocketInput creates the input reading the records from the connections to the listen address, annotated with
+// their connection when asked.
+func initSocketInput(c inputConfig, unmarshal sakio.UnmarshalInput) (sakio.Input, error) {
+	if c.format == "json" || c.format == "avro" {
+		return nil, errors.Errorf("%s input format is not available for listen", c.format)
+	}
+
+	network, address, err := sakio.ParseSocketAddress(c.listen)
+	if err != nil {
+		return nil, err
+	}
+
+	input := sakio.NewSocketInput(network, address).
+		WithMaxRecordSize(c.maxRecordSize).
+		WithFraming(formatFraming(c.format)).
+		WithMaxConnections(c.maxConnections).
+		WithUnmarshaling(unmarshal)
+
+	if c.annotateConn {
+		input.WithConnectionAnnotation("_remote_addr", "_connection")
+	}
+
+	// listening up front, so that the address in use is reported before processing.
+	if err := input.Listen(); err != nil {
+		return nil, err
+	}
+
+	return input, nil
+}
+
+// initHTTPInput creates the input reading the records posted to the listen endpoint, as NDJSON or JSON arrays.
+func initHTTPInput(c inputConfig) (sakio.Input, error) {
+	if c.format != "" && c.format != "ndjson" && c.format != "json" {
+		return nil, errors.Errorf("%s input format is not available for http listen", c.format)
+	}
+
+	if c.annotateConn || c.maxConnections != 0 {
+		return nil, errors.New("connection annotation and max connections are not available for http listen")
+	}
+
+	address, path, err := sakio.ParseHTTPAddress(c.listen)
+	if err != nil {
+		return nil, err
+	}
+
+	input := sakio.NewHTTPInput(address).
+		WithPath(path).
+		WithMaxRecordSize(c.maxRecordSize).
+		WithUnmarshaling(unmarshalJSON)
+
+	// listening up front, so that the address in use is reported before processing.
+	if err := input.Listen(); err != nil {
+		return nil, err
+	}
+
+	return input, nil
+}
+
+// formatFraming returns the Framing of the records of the format, the binary formats being read and written length
+// prefixed.
+func formatFraming(format string) sakio.Framing {
+	switch format {
+	case "msgpack", "cbor":
+		return sakio.Uint32Framing
+	case "protobuf":
+		return sakio.VarintFraming
+	}
+
+	return sakio.NoFraming
+}
+
+// loadProtoMessage loads the message type of the protobuf records from the FileDescriptorSet file.
+func loadProtoMessage(descriptorPath, name string) (*sakio.ProtoMessage, error) {
+	if descriptorPath == "" || name == "" {
+		return nil, errors.New("protobuf format requires a descriptor set and a message type")
+	}
+
+	data, err := ioutil.ReadFile(descriptorPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return sakio.LoadProtoMessage(data, name)
+}
+
+// closeInput closes the input when it holds resources, like the file being read.
+func closeInput(input sakio.Input) error {
+	if c, ok := input.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// unmarshalJSON decodes an input record.
+func unmarshalJSON(_ context.Context, i string) (interface{}, error) {
+	var a interface{}
+
+	if err := json.Unmarshal([]byte(i), &a); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// outputConfig is the configuration of the output.
+type outputConfig struct {
+	// format is either ndjson, the default, csv, tsv, msgpack, cbor, protobuf or avro.
+	format string
+	// columns are the columns of the csv or tsv rows, the keys of the first record when empty.
+	columns       []string
+	flushInterval time.Duration
+
+	// path is the path template of the files to write the records into, stdout when empty.
+	path             string
+	maxSize          int64
+	maxRecords       int64
+	rotationInterval time.Duration
+	compression      string
+
+	// protoDescriptor is the FileDescriptorSet file declaring the protoMessage type of the protobuf records.
+	protoDescriptor string
+	protoMessage    string
+
+	// avroSchema is the schema file of the avro records, inferred from the first record when empty.
+	avroSchema string
+	avroCodec  string
+
+	// batchSize is the amount of records posted at once when the path is an http:// or https:// URL.
+	batchSize int
+	timeout   time.Duration
+	retries   int
+}
+
+// initOutput creates the output, writing the records into the files named by the path template, rotated by size,
+// amount of records or interval, posting them in batches when the path is an http:// or https:// URL, or to stdout
+// when no path is given.
+func initOutput(c outputConfig) (sakio.StreamOutput, error) {
+	marshal := marshalJSON
 
-		if err := json.Unmarshal([]byte(i), &a); err != nil {
+	switch c.format {
+	case "", "ndjson":
+	case "msgpack":
+		marshal = sakio.MarshalMsgpack
+	case "cbor":
+		marshal = sakio.MarshalCBOR
+	case "protobuf":
+		message, err := loadProtoMessage(c.protoDescriptor, c.protoMessage)
+		if err != nil {
 			return nil, err
 		}
 
-		return a, nil
-	})
+		marshal = message.Marshal
+	case "avro":
+		return initAvroOutput(c)
+	case "csv", "tsv":
+		if c.path != "" {
+			return nil, errors.Errorf("%s output format is only available for stdout", c.format)
+		}
 
-	return input
-}
+		// create output Stdout writing the records as rows
+		output := sakio.NewCSVOutput(os.Stdout).
+			WithColumns(c.columns...).
+			WithFlushInterval(c.flushInterval)
+
+		if c.format == "tsv" {
+			output.WithDelimiter('\t')
+		}
+
+		return output, nil
+	default:
+		return nil, errors.Errorf("unknown output format %q, valid are ndjson, csv, tsv, msgpack, cbor, protobuf and avro", c.format)
+	}
+
+	if strings.HasPrefix(c.path, "http://") || strings.HasPrefix(c.path, "https://") {
+		if formatFraming(c.format) != sakio.NoFraming {
+			return nil, errors.Errorf("%s output format is not available for http output", c.format)
+		}
+
+		output := sakio.NewHTTPOutput(c.path).
+			WithMarshaling(marshal).
+			WithBatchSize(c.batchSize).
+			WithFlushInterval(c.flushInterval).
+			WithTimeout(c.timeout).
+			WithRetries(c.retries)
+
+		return output, nil
+	}
+
+	if c.path != "" {
+		compression, err := sakio.ParseCompression(c.compression)
+		if err != nil {
+			return nil, err
+		}
 
-func initOutput() *sakio.StdoutOutput {
-	// create output Stdout
-	output := sakio.StdoutOutput{}
+		if err := sakio.ValidFileRotation(c.path, c.maxSize, c.maxRecords, c.rotationInterval); err != nil {
+			return nil, err
+		}
+
+		output := sakio.NewFileOutput(c.path).
+			WithMarshaling(marshal).
+			WithMaxSize(c.maxSize).
+			WithMaxRecords(c.maxRecords).
+			WithRotationInterval(c.rotationInterval).
+			WithCompression(compression).
+			WithFraming(formatFraming(c.format))
+
+		return output, nil
+	}
+
+	// create output Stdout, records are written as soon as they are processed
+	output := sakio.NewWriterOutput(os.Stdout).
+		WithFlushInterval(c.flushInterval).
+		WithFraming(formatFraming(c.format))
 	// add marshal to encode output value
-	output.WithMarshaling(func(_ context.Context, i interface{}) (string, error) {
-		r, err := json.Marshal(i)
+	output.WithMarshaling(marshal)
+
+	return output, nil
+}
+
+// initAvroOutput creates the output writing the records to stdout as an Avro object container file, with the schema
+// of the schema file, if any.
+func initAvroOutput(c outputConfig) (sakio.StreamOutput, error) {
+	if c.path != "" {
+		return nil, errors.New("avro output format is only available for stdout")
+	}
+
+	if err := sakio.ValidAvroCompression(c.avroCodec); err != nil {
+		return nil, err
+	}
+
+	var schema []byte
+
+	if c.avroSchema != "" {
+		var err error
+
+		schema, err = ioutil.ReadFile(c.avroSchema)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
+	}
+
+	output := sakio.NewAvroOutput(os.Stdout).
+		WithSchema(string(schema)).
+		WithCompression(c.avroCodec).
+		WithFlushInterval(c.flushInterval)
+
+	return output, nil
+}
+
+// marshalJSON encodes an output record.
+func marshalJSON(_ context.Context, i interface{}) (string, error) {
+	r, err := json.Marshal(i)
+	if err != nil {
+		return "", err
+	}
+
+	return string(r), nil
+}
+
+// deadLetterOutput writes the records that failed into a file and closes it once done.
+type deadLetterOutput struct {
+	*sakio.WriterOutput
+
+	file *os.File
+}
+
+// Commit flushes the records that failed and syncs the file.
+func (o deadLetterOutput) Commit(ctx context.Context) error {
+	if err := o.WriterOutput.Flush(ctx); err != nil {
+		return err
+	}
+
+	return o.file.Sync()
+}
 
-		return string(r), nil
-	})
+// Close flushes the records that failed and closes the file.
+func (o deadLetterOutput) Close(ctx context.Context) error {
+	if err := o.WriterOutput.Close(ctx); err != nil {
+		return err
+	}
 
-	return &output
+	return o.file.Close()
 }
 
-func splitPairs(value string) (pairs [][]string, err error) {
-	kvs := strings.Split(value, ";")
-	for _, kv := range kvs {
+// initDeadLetter creates the dead letter file, or appends to it when resuming, the records are written with the
+// framing they are read with, so the binary formats are written length prefixed.
+func initDeadLetter(path string, framing sakio.Framing, resume bool) (*deadLetterOutput, error) {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if resume {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	f, err := os.OpenFile(path, flag, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	// write the records as they were inputted, so they can be replayed.
+	output := sakio.NewWriterOutput(f).
+		WithFlushInterval(time.Second).
+		WithMarshaling(func(_ context.Context, i interface{}) (string, error) {
+			rerr := i.(*swiss_army_knife.RecordError)
+			if rerr.Input != "" {
+				return rerr.Input, nil
+			}
+
+			r, err := json.Marshal(rerr.Record)
+			if err != nil {
+				return "", err
+			}
+
+			return string(r), nil
+		})
+
+	output.WithFraming(framing)
+
+	return &deadLetterOutput{WriterOutput: output, file: f}, nil
+}
+
+// splitColumns splits the comma separated column names, nil when empty.
+func splitColumns(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	columns := strings.Split(value, ",")
+	for i, c := range columns {
+		columns[i] = strings.TrimSpace(c)
+	}
+
+	return columns
+}
+
+// stringParam creates the params of an operation taking the flag value as the parameter given.
+func stringParam(name string) func(value string) (map[string]interface{}, error) {
+	return func(value string) (map[string]interface{}, error) {
+		return map[string]interface{}{name: value}, nil
+	}
+}
+
+// pairsParam creates the pairs param from the flag value, keeping the order of the pairs. Valid format
+// key:value;keyn:valuen.
+func pairsParam(value string) (map[string]interface{}, error) {
+	var pairs [][2]string
+
+	for _, kv := range strings.Split(value, ";") {
 		pair := strings.Split(kv, ":")
 
 		if len(pair) != 2 {
 			return nil, errInvalidPairKeyValue
 		}
 
-		pairs = append(pairs, []string{pair[0], pair[1]})
+		pairs = append(pairs, [2]string{pair[0], pair[1]})
 	}
 
-	return pairs, nil
+	return map[string]interface{}{"pairs": pairs}, nil
+}
+
+// keysParam creates the keys param from the flag value. Valid format key:keyn.
+func keysParam(value string) (map[string]interface{}, error) {
+	return map[string]interface{}{"keys": strings.Split(value, ":")}, nil
 }
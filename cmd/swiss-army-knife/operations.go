@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	swiss_army_knife "github.com/dohernandez/swiss-army-knife"
+	"github.com/urfave/cli"
+)
+
+// operationsCommand creates the command listing the registered operations.
+func operationsCommand() cli.Command {
+	return cli.Command{
+		Name:  "operations",
+		Usage: "Operations available for the pipeline definition files.",
+		Subcommands: []cli.Command{
+			{
+				Name:  "list",
+				Usage: "List the operations registered with their parameters.",
+				Action: func(cliCtx *cli.Context) error {
+					return listOperations(os.Stdout, swiss_army_knife.DefaultOperationRegistry)
+				},
+			},
+		},
+	}
+}
+
+// listOperations writes the operations registered with their parameters.
+func listOperations(w io.Writer, registry *swiss_army_knife.OperationRegistry) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "OPERATION\tPARAMETER\tTYPE\tREQUIRED\tDESCRIPTION")
+
+	for _, f := range registry.Factories() {
+		fmt.Fprintf(tw, "%s\t\t\t\t%s\n", f.Name, f.Description)
+
+		for _, p := range f.Params() {
+			required := "no"
+			if p.Required {
+				required = "yes"
+			}
+
+			fmt.Fprintf(tw, "\t%s\t%s\t%s\t%s\n", p.Name, p.Type, required, p.Description)
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/dohernandez/swiss-army-knife/pipeline"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+const pipelineKey = "pipeline"
+
+// runCommand creates the command running the pipeline declared in a definition file.
+func runCommand(ctx context.Context) cli.Command {
+	return cli.Command{
+		Name:      "run",
+		Usage:     "Run the pipeline declared in a definition file (YAML or JSON), applying the operations in the declared order.",
+		UsageText: fmt.Sprintf("%s run --%s pipeline.yaml", binaryName, pipelineKey),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  pipelineKey,
+				Usage: "Pipeline definition file. Example pipeline.yaml.",
+			},
+			cli.BoolFlag{
+				Name:  resumeKey,
+				Usage: "Resume from the checkpoint of the processor, if stored, instead of reading the input files from the beginning. The dead letter file is appended to.",
+			},
+		},
+		Action: func(cliCtx *cli.Context) error {
+			path := cliCtx.String(pipelineKey)
+			if path == "" {
+				return errors.Errorf("--%s is required", pipelineKey)
+			}
+
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			// the definition is validated up front, reporting all the errors found.
+			d, err := pipeline.Parse(data)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("%s (%s)", pipelineKey, path))
+			}
+
+			operations, err := d.BuildOperations(ctx)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("%s (%s)", pipelineKey, path))
+			}
+
+			config := inputConfig{
+				paths:           d.Input.Paths,
+				annotateSource:  d.Input.AnnotateSource,
+				maxRecordSize:   d.Input.MaxRecordSize,
+				format:          d.Input.Format,
+				jsonPointer:     d.Input.JSONPointer,
+				columns:         d.Input.Columns,
+				inferTypes:      d.Input.InferTypes,
+				protoDescriptor: d.Input.ProtoDescriptor,
+				protoMessage:    d.Input.ProtoMessage,
+				listen:          d.Input.Listen,
+				annotateConn:    d.Input.AnnotateConnection,
+				maxConnections:  d.Input.MaxConnections,
+			}
+
+			if d.Input.Follow {
+				config.follow, config.followOffset, config.paths = d.Input.Paths[0], d.Input.OffsetFile, nil
+			}
+
+			input, err := initInput(config)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("%s (%s)", pipelineKey, path))
+			}
+			// nolint:errcheck
+			defer closeInput(input)
+
+			flushInterval := time.Second
+			if d.Output.FlushInterval != nil {
+				flushInterval = *d.Output.FlushInterval
+			}
+
+			// the http output is told apart by the scheme of its url.
+			outputPath := d.Output.Path
+			if d.Output.Type == "http" {
+				outputPath = d.Output.URL
+			}
+
+			retries := sakio.DefaultHTTPRetries
+			if d.Output.Retries != nil {
+				retries = *d.Output.Retries
+			}
+
+			output, err := initOutput(outputConfig{
+				format:           d.Output.Format,
+				columns:          d.Output.Columns,
+				flushInterval:    flushInterval,
+				path:             outputPath,
+				maxSize:          d.Output.MaxSize,
+				maxRecords:       d.Output.MaxRecords,
+				rotationInterval: d.Output.RotationInterval,
+				compression:      d.Output.Compression,
+				protoDescriptor:  d.Output.ProtoDescriptor,
+				protoMessage:     d.Output.ProtoMessage,
+				avroSchema:       d.Output.AvroSchema,
+				avroCodec:        d.Output.AvroCodec,
+				batchSize:        d.Output.BatchSize,
+				timeout:          d.Output.Timeout,
+				retries:          retries,
+			})
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("%s (%s)", pipelineKey, path))
+			}
+			// nolint:errcheck
+			defer output.Close(ctx)
+
+			p := initProcessor(d.Processor.Workers, d.Processor.Ordered, d.Processor.ErrorPolicy())
+
+			checkpoint := checkpointConfig{
+				file:     d.Processor.Checkpoint,
+				interval: d.Processor.CheckpointInterval,
+				resume:   cliCtx.Bool(resumeKey),
+			}
+
+			if err := initCheckpoint(p, checkpoint, config.paths, outputPath); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("%s (%s)", pipelineKey, path))
+			}
+
+			if d.Processor.DeadLetter != "" {
+				deadLetter, err := initDeadLetter(d.Processor.DeadLetter, formatFraming(d.Input.Format), checkpoint.resume)
+				if err != nil {
+					return errors.Wrap(err, fmt.Sprintf("dead_letter (%s)", d.Processor.DeadLetter))
+				}
+				// nolint:errcheck
+				defer deadLetter.Close(ctx)
+
+				p.WithDeadLetter(deadLetter)
+			}
+
+			return process(ctx, p, input, output, operations)
+		},
+	}
+}
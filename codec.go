@@ -0,0 +1,226 @@
+package swissarmyknife
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack"
+)
+
+// Codec defines contract for transferring the items conveyed between operations.
+type Codec interface {
+	// Transfer stores the item conveyed into the value pointed to by v.
+	// Returns any error that occurred.
+	Transfer(item interface{}, v interface{}) error
+}
+
+// PassThroughCodec transfers the item as it is, without any copy. The item is shared between the emitter
+// and the acceptor, which is safe as long as the emitter does not modify the item after emitting it.
+//
+// v must be a pointer to a type the item (or the value the item points to) is assignable to.
+//
+// ErrTypeMismatch is returned if the item can not be assigned to v.
+type PassThroughCodec struct{}
+
+var _ Codec = PassThroughCodec{}
+
+// Transfer assigns the item to the value pointed to by v.
+func (PassThroughCodec) Transfer(item interface{}, v interface{}) error {
+	return assign(item, v)
+}
+
+// DeepCopyCodec transfers a deep copy of the item, so the emitter and the acceptor never share maps, slices
+// or pointers. Unexported struct fields are copied shallowly.
+//
+// v must be a pointer to a type the item (or the value the item points to) is assignable to.
+//
+// ErrTypeMismatch is returned if the item can not be assigned to v.
+type DeepCopyCodec struct{}
+
+var _ Codec = DeepCopyCodec{}
+
+// Transfer assigns a deep copy of the item to the value pointed to by v.
+func (DeepCopyCodec) Transfer(item interface{}, v interface{}) error {
+	if item == nil {
+		return assign(item, v)
+	}
+
+	return assign(deepCopy(reflect.ValueOf(item)).Interface(), v)
+}
+
+// JSONCodec transfers the item marshaling it into json and than unmarshal back into v. Be wary
+// of your json tags and private fields.
+type JSONCodec struct{}
+
+var _ Codec = JSONCodec{}
+
+// Transfer json encodes the item and decodes it into v.
+func (JSONCodec) Transfer(item interface{}, v interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(item); err != nil {
+		return err
+	}
+
+	return json.NewDecoder(&buf).Decode(v)
+}
+
+// GobCodec transfers the item encoding it with encoding/gob and decoding it back into v.
+//
+// Concrete types conveyed inside interface values (other than the generic map[string]interface{}
+// and []interface{}) must be registered with gob.Register.
+type GobCodec struct{}
+
+var _ Codec = GobCodec{}
+
+// gobEnvelope wraps an interface value, gob can only decode interface values sent as a field of a struct.
+type gobEnvelope struct {
+	V interface{}
+}
+
+// nolint:gochecknoinits
+func init() {
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// Transfer gob encodes the item and decodes it into v.
+func (GobCodec) Transfer(item interface{}, v interface{}) error {
+	var buf bytes.Buffer
+
+	if p, ok := v.(*interface{}); ok {
+		if err := gob.NewEncoder(&buf).Encode(gobEnvelope{V: item}); err != nil {
+			return err
+		}
+
+		var e gobEnvelope
+		if err := gob.NewDecoder(&buf).Decode(&e); err != nil {
+			return err
+		}
+
+		*p = e.V
+
+		return nil
+	}
+
+	if err := gob.NewEncoder(&buf).Encode(item); err != nil {
+		return err
+	}
+
+	return gob.NewDecoder(&buf).Decode(v)
+}
+
+// MsgpackCodec transfers the item encoding it with MessagePack and decoding it back into v.
+// Be wary of your msgpack tags and private fields.
+type MsgpackCodec struct{}
+
+var _ Codec = MsgpackCodec{}
+
+// Transfer msgpack encodes the item and decodes it into v.
+func (MsgpackCodec) Transfer(item interface{}, v interface{}) error {
+	b, err := msgpack.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	return msgpack.Unmarshal(b, v)
+}
+
+// assign sets the item into the value pointed to by v. When the item is a pointer which can not be assigned,
+// the value it points to is tried.
+func assign(item interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return ErrTypeMismatch
+	}
+
+	dst := rv.Elem()
+
+	if item == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+
+		return nil
+	}
+
+	src := reflect.ValueOf(item)
+	for {
+		if src.Type().AssignableTo(dst.Type()) {
+			dst.Set(src)
+
+			return nil
+		}
+
+		if src.Kind() != reflect.Ptr || src.IsNil() {
+			return ErrTypeMismatch
+		}
+
+		src = src.Elem()
+	}
+}
+
+// deepCopy returns a copy of v that does not share maps, slices or pointers with it.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, k := range v.MapKeys() {
+			c.SetMapIndex(k, deepCopy(v.MapIndex(k)))
+		}
+
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+
+		return c
+	default:
+		return v
+	}
+}
@@ -0,0 +1,75 @@
+package swissarmyknife_test
+
+import (
+	"testing"
+
+	swiss_army_knife "github.com/dohernandez/swiss-army-knife"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecTransfer(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		codec    swiss_army_knife.Codec
+		shared   bool
+	}{
+		{
+			scenario: "Transfer with pass-through codec shares the item",
+			codec:    swiss_army_knife.PassThroughCodec{},
+			shared:   true,
+		},
+		{
+			scenario: "Transfer with deep-copy codec copies the item",
+			codec:    swiss_army_knife.DeepCopyCodec{},
+		},
+		{
+			scenario: "Transfer with json codec copies the item",
+			codec:    swiss_army_knife.JSONCodec{},
+		},
+		{
+			scenario: "Transfer with gob codec copies the item",
+			codec:    swiss_army_knife.GobCodec{},
+		},
+		{
+			scenario: "Transfer with msgpack codec copies the item",
+			codec:    swiss_army_knife.MsgpackCodec{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			item := map[string]interface{}{
+				"id":    "1629",
+				"stops": []interface{}{"a", "b"},
+			}
+
+			var v interface{}
+
+			err := tc.codec.Transfer(item, &v)
+			assert.NoError(t, err)
+
+			m, ok := v.(map[string]interface{})
+			assert.True(t, ok, "value must be a map[string]interface{}")
+			assert.Equal(t, item, m)
+
+			m["id"] = "1874"
+
+			if tc.shared {
+				assert.Equal(t, "1874", item["id"])
+			} else {
+				assert.Equal(t, "1629", item["id"])
+			}
+		})
+	}
+}
+
+func TestPassThroughCodecTransferTypeMismatch(t *testing.T) {
+	var v int
+
+	err := swiss_army_knife.PassThroughCodec{}.Transfer("1629", &v)
+	assert.EqualError(t, err, swiss_army_knife.ErrTypeMismatch.Error())
+
+	err = swiss_army_knife.PassThroughCodec{}.Transfer(1629, v)
+	assert.EqualError(t, err, swiss_army_knife.ErrTypeMismatch.Error())
+}
@@ -0,0 +1,32 @@
+package swissarmyknife
+
+import (
+	"context"
+	"time"
+)
+
+// valueOnlyContext is a context carrying the values of its parent which is never done.
+type valueOnlyContext struct {
+	context.Context
+}
+
+// withoutCancel returns a context that keeps the values of ctx but it is not done when ctx is done.
+// Used to finish work (i.e. flushing the output) after the processing was cancelled.
+func withoutCancel(ctx context.Context) context.Context {
+	return valueOnlyContext{Context: ctx}
+}
+
+// Deadline returns no deadline.
+func (valueOnlyContext) Deadline() (deadline time.Time, ok bool) {
+	return time.Time{}, false
+}
+
+// Done returns nil, the context is never done.
+func (valueOnlyContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err returns nil, the context is never done.
+func (valueOnlyContext) Err() error {
+	return nil
+}
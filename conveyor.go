@@ -1,8 +1,6 @@
 package swissarmyknife
 
 import (
-	"bytes"
-	"encoding/json"
 	"io"
 )
 
@@ -24,22 +22,30 @@ type ChannelConveyor interface {
 type channelConveyor struct {
 	inputCh  chan interface{}
 	outputCh chan interface{}
+
+	codec Codec
 }
 
 // NewChannelConveyor creates new conveyor that conveys values with channels.
+// The Codec is used to transfer the values accepted, JSONCodec is used when codec is nil.
 //
 // Common initialization example:
 //
 //      inputs := make(chan interface{})
 //
 //		// create a ChannelConveyor.
-//		cc := NewChannelConveyor(inputs)
+//		cc := NewChannelConveyor(inputs, PassThroughCodec{})
 //
-func NewChannelConveyor(input chan interface{}) ChannelConveyor {
+func NewChannelConveyor(input chan interface{}, codec Codec) ChannelConveyor {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
 	return channelConveyor{
 		inputCh: input,
 		// to limit the amount of work that is queued up.
 		outputCh: make(chan interface{}, 1024),
+		codec:    codec,
 	}
 }
 
@@ -49,29 +55,15 @@ func (c channelConveyor) Close() {
 }
 
 // Accept accepts a pointer to an object you want the receive the data into.
-// Currently you *have* to pass a pointer to a object. Accept will marshal the received
-// object into json and than unmarshal back into the object you have provided. Be wary
-// of your json tags and private fields.
-//
-// NOTE: marshal/unmarshal mechanism will change in future version.
+// Currently you *have* to pass a pointer to a object. Accept will transfer the received
+// object into the object you have provided using the conveyor Codec.
 func (c channelConveyor) Accept(v interface{}) error {
 	item, ok := <-c.inputCh
 	if !ok {
 		return io.EOF
 	}
 
-	// TODO: Make this pluggable.
-	// JSON encode -> decode is just a temporary implementation.
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(item); err != nil {
-		return err
-	}
-
-	if err := json.NewDecoder(&buf).Decode(v); err != nil {
-		return err
-	}
-
-	return nil
+	return c.codec.Transfer(item, v)
 }
 
 // Emit emits the data on the channel to be accepted by next operation.
@@ -84,6 +76,7 @@ func (c channelConveyor) Emit(v interface{}) error {
 // ChainNext initiates a new conveyor (B) with the output of this consumer (A) being
 // the input of the new consumer. In effect chaining them A->B with the arrow showing direction
 // of the items data passed.
+// The new conveyor uses the same Codec.
 func (c channelConveyor) ChainNext() ChannelConveyor {
-	return NewChannelConveyor(c.outputCh)
+	return NewChannelConveyor(c.outputCh, c.codec)
 }
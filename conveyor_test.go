@@ -108,14 +108,26 @@ func TestChannelConveyor(t *testing.T) {
 		},
 	}
 
-	for _, tc := range testCases {
-		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
-		t.Run(tc.scenario, func(t *testing.T) {
-			input := newChanWithItems(items)
+	codecs := map[string]swiss_army_knife.Codec{
+		"default":      nil,
+		"pass-through": swiss_army_knife.PassThroughCodec{},
+		"deep-copy":    swiss_army_knife.DeepCopyCodec{},
+		"json":         swiss_army_knife.JSONCodec{},
+		"gob":          swiss_army_knife.GobCodec{},
+		"msgpack":      swiss_army_knife.MsgpackCodec{},
+	}
+
+	for name, codec := range codecs {
+		for _, tc := range testCases {
+			tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+			codec := codec
+			t.Run(tc.scenario+" using "+name+" codec", func(t *testing.T) {
+				input := newChanWithItems(items)
 
-			cc := swiss_army_knife.NewChannelConveyor(input)
+				cc := swiss_army_knife.NewChannelConveyor(input, codec)
 
-			tc.assert(t, cc, items)
-		})
+				tc.assert(t, cc, items)
+			})
+		}
 	}
 }
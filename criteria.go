@@ -0,0 +1,354 @@
+package swissarmyknife
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCriteria is returned when the criteria are not valid.
+var ErrInvalidCriteria = errors.New("invalid criteria")
+
+// Operator is the comparison a criterion applies to the value found at its Key.
+type Operator string
+
+// Operators available.
+const (
+	// Equal matches when the value is equal to the criterion value. Numbers are compared numerically, strings
+	// holding a number are compared as strings.
+	Equal Operator = "=="
+	// NotEqual matches when the value is not equal to the criterion value, or the key is missing.
+	NotEqual Operator = "!="
+	// LessThan matches when the value is less than the criterion value. Numbers and timestamps are compared
+	// as such, otherwise values are compared as strings.
+	LessThan Operator = "<"
+	// LessThanOrEqual matches when the value is less than or equal to the criterion value.
+	LessThanOrEqual Operator = "<="
+	// GreaterThan matches when the value is greater than the criterion value.
+	GreaterThan Operator = ">"
+	// GreaterThanOrEqual matches when the value is greater than or equal to the criterion value.
+	GreaterThanOrEqual Operator = ">="
+	// In matches when the value is equal to any of the criterion values.
+	In Operator = "in"
+	// NotIn matches when the value is not equal to any of the criterion values, or the key is missing.
+	NotIn Operator = "not in"
+	// Exists matches when the key exists.
+	Exists Operator = "exists"
+	// Missing matches when the key does not exist.
+	Missing Operator = "missing"
+	// HasPrefix matches when the value starts with the criterion value.
+	HasPrefix Operator = "^="
+	// HasSuffix matches when the value ends with the criterion value.
+	HasSuffix Operator = "$="
+	// Contains matches when the value contains the criterion value.
+	Contains Operator = "*="
+	// Matches matches when the value matches the criterion value as a regular expression.
+	Matches Operator = "=~"
+	// NotMatches matches when the value does not match the criterion value as a regular expression,
+	// or the key is missing.
+	NotMatches Operator = "!~"
+)
+
+// timeLayouts are the layouts tried to compare values as timestamps.
+// nolint:gochecknoglobals
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// Criteria defines a contract for the conditions a record is matched against.
+type Criteria interface {
+	// Match returns whether the record matches the criteria.
+	Match(record map[string]interface{}) bool
+}
+
+// criterion is a condition on the value found at a key.
+type criterion struct {
+	path     []pathSegment
+	operator Operator
+	values   []Value
+
+	// parsed values, to avoid parsing them for each record.
+	numbers []float64
+	isNum   []bool
+	times   []time.Time
+	isTime  []bool
+	regexp  *regexp.Regexp
+}
+
+// NewCriterion creates the Criteria matching the value found at key with the operator and the values.
+// Equality and comparison operators take one value, In and NotIn at least one, Exists and Missing none.
+//
+// ErrInvalidKeyPath is returned if the key is not a valid path.
+// ErrInvalidCriteria is returned if the operator is unknown, the amount of values does not fit the operator or
+// the value is not a valid regular expression.
+func NewCriterion(key Key, operator Operator, values ...Value) (Criteria, error) {
+	path, err := key.path()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &criterion{
+		path:     path,
+		operator: operator,
+		values:   values,
+	}
+
+	switch operator {
+	case Exists, Missing:
+		if len(values) != 0 {
+			return nil, ErrInvalidCriteria
+		}
+	case In, NotIn:
+		if len(values) == 0 {
+			return nil, ErrInvalidCriteria
+		}
+	case Equal, NotEqual, LessThan, LessThanOrEqual, GreaterThan, GreaterThanOrEqual,
+		HasPrefix, HasSuffix, Contains:
+		if len(values) != 1 {
+			return nil, ErrInvalidCriteria
+		}
+	case Matches, NotMatches:
+		if len(values) != 1 {
+			return nil, ErrInvalidCriteria
+		}
+
+		c.regexp, err = regexp.Compile(values[0].String())
+		if err != nil {
+			return nil, ErrInvalidCriteria
+		}
+	default:
+		return nil, ErrInvalidCriteria
+	}
+
+	for _, v := range values {
+		n, isNum := parseNumber(v.String())
+		t, isTime := parseTime(v.String())
+
+		c.numbers = append(c.numbers, n)
+		c.isNum = append(c.isNum, isNum)
+		c.times = append(c.times, t)
+		c.isTime = append(c.isTime, isTime)
+	}
+
+	return c, nil
+}
+
+// Match returns whether the value found at the key matches the criterion.
+func (c *criterion) Match(record map[string]interface{}) bool {
+	v, ok := getPath(record, c.path)
+
+	switch c.operator {
+	case Exists:
+		return ok
+	case Missing:
+		return !ok
+	case NotEqual, NotIn, NotMatches:
+		if !ok {
+			return true
+		}
+	default:
+		if !ok {
+			return false
+		}
+	}
+
+	s := fmt.Sprint(v)
+
+	switch c.operator {
+	case Equal:
+		r, ok := c.compare(0, v, s)
+		return ok && r == 0
+	case NotEqual:
+		r, ok := c.compare(0, v, s)
+		return !ok || r != 0
+	case LessThan:
+		r, ok := c.compare(0, v, s)
+		return ok && r < 0
+	case LessThanOrEqual:
+		r, ok := c.compare(0, v, s)
+		return ok && r <= 0
+	case GreaterThan:
+		r, ok := c.compare(0, v, s)
+		return ok && r > 0
+	case GreaterThanOrEqual:
+		r, ok := c.compare(0, v, s)
+		return ok && r >= 0
+	case In, NotIn:
+		in := false
+
+		for i := range c.values {
+			if r, ok := c.compare(i, v, s); ok && r == 0 {
+				in = true
+
+				break
+			}
+		}
+
+		return in == (c.operator == In)
+	case HasPrefix:
+		return strings.HasPrefix(s, c.values[0].String())
+	case HasSuffix:
+		return strings.HasSuffix(s, c.values[0].String())
+	case Contains:
+		return strings.Contains(s, c.values[0].String())
+	case Matches:
+		return c.regexp.MatchString(s)
+	case NotMatches:
+		return !c.regexp.MatchString(s)
+	}
+
+	return false
+}
+
+// compare compares the value (s being its string representation) with the criterion value at index i.
+// Values are compared numerically when both are numbers, chronologically when both are timestamps,
+// otherwise as strings. Returns false when the values can not be compared, a NaN being neither equal, less nor
+// greater than any number.
+func (c *criterion) compare(i int, v interface{}, s string) (int, bool) {
+	if c.isNum[i] {
+		if n, ok := toNumber(v); ok {
+			switch {
+			case math.IsNaN(n):
+				return 0, false
+			case n < c.numbers[i]:
+				return -1, true
+			case n > c.numbers[i]:
+				return 1, true
+			default:
+				return 0, true
+			}
+		}
+	}
+
+	if c.isTime[i] {
+		if t, ok := parseTime(s); ok {
+			switch {
+			case t.Before(c.times[i]):
+				return -1, true
+			case t.After(c.times[i]):
+				return 1, true
+			default:
+				return 0, true
+			}
+		}
+	}
+
+	return strings.Compare(s, c.values[i].String()), true
+}
+
+// toNumber returns the value as a float64, false if the value is not a number. Strings are not numbers, even
+// holding one, so "007" is not equal to 7.
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		return parseNumber(n.String())
+	default:
+		return 0, false
+	}
+}
+
+// parseNumber parses s as a float64, false if s is not a number. NaN and infinities are not numbers.
+func parseNumber(s string) (float64, bool) {
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// parseTime parses s as a timestamp using the known layouts, false if s is not a timestamp.
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// allCriteria matches when all the criteria match.
+type allCriteria []Criteria
+
+// Match returns whether all the criteria match.
+func (cc allCriteria) Match(record map[string]interface{}) bool {
+	for _, c := range cc {
+		if !c.Match(record) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// anyCriteria matches when any of the criteria match.
+type anyCriteria []Criteria
+
+// Match returns whether any of the criteria match.
+func (cc anyCriteria) Match(record map[string]interface{}) bool {
+	for _, c := range cc {
+		if c.Match(record) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notCriteria matches when the criteria do not match.
+type notCriteria struct {
+	criteria Criteria
+}
+
+// Match returns whether the criteria do not match.
+func (c notCriteria) Match(record map[string]interface{}) bool {
+	return !c.criteria.Match(record)
+}
+
+// And creates the Criteria matching when all the criteria match.
+func And(criteria ...Criteria) Criteria {
+	return allCriteria(criteria)
+}
+
+// Or creates the Criteria matching when any of the criteria match.
+func Or(criteria ...Criteria) Criteria {
+	return anyCriteria(criteria)
+}
+
+// Not creates the Criteria matching when the criteria do not match.
+func Not(criteria Criteria) Criteria {
+	return notCriteria{criteria: criteria}
+}
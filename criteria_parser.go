@@ -0,0 +1,358 @@
+package swissarmyknife
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CriteriaSyntaxError is returned when parsing criteria fails, reporting the offset where it failed.
+type CriteriaSyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+// Error returns the error message.
+func (e *CriteriaSyntaxError) Error() string {
+	return fmt.Sprintf("%s: %s at offset %d", ErrInvalidCriteria, e.Msg, e.Offset)
+}
+
+// Cause returns ErrInvalidCriteria, the error behind the syntax error.
+func (e *CriteriaSyntaxError) Cause() error {
+	return ErrInvalidCriteria
+}
+
+// Unwrap returns ErrInvalidCriteria, the error behind the syntax error.
+func (e *CriteriaSyntaxError) Unwrap() error {
+	return ErrInvalidCriteria
+}
+
+// operators in the order they are looked up, longest first so `<=` is not read as `<`.
+// nolint:gochecknoglobals
+var parserOperators = []struct {
+	token    string
+	operator Operator
+	word     bool
+}{
+	{token: "==", operator: Equal},
+	{token: "!=", operator: NotEqual},
+	{token: "<=", operator: LessThanOrEqual},
+	{token: ">=", operator: GreaterThanOrEqual},
+	{token: "=~", operator: Matches},
+	{token: "!~", operator: NotMatches},
+	{token: "^=", operator: HasPrefix},
+	{token: "$=", operator: HasSuffix},
+	{token: "*=", operator: Contains},
+	{token: "<", operator: LessThan},
+	{token: ">", operator: GreaterThan},
+	{token: ":", operator: Equal},
+	{token: "not in", operator: NotIn, word: true},
+	{token: "in", operator: In, word: true},
+	{token: "exists", operator: Exists, word: true},
+	{token: "missing", operator: Missing, word: true},
+}
+
+// ParseCriteria parses criteria from its text representation.
+//
+// A condition is a key followed by an operator and its value, i.e. `id == 347`. Operators are
+// `==` (or `:`), `!=`, `<`, `<=`, `>`, `>=`, `^=` (prefix), `$=` (suffix), `*=` (contains), `=~` (regex),
+// `!~` (not regex), `in [v1,v2]`, `not in [v1,v2]`, `exists` and `missing`.
+// Conditions are combined with `;` (AND) and `|` (OR), AND taking precedence, negated with `!` and grouped
+// with parentheses. Values containing `;`, `|`, `)` or leading/trailing spaces must be double quoted,
+// `\"` and `\\` being the escapes supported within quotes.
+//
+// The legacy syntax `key:value;keyn:valuen` is valid and matches when all the pairs are equal.
+//
+// Examples:
+//
+//      id:347;driver.location.city:paris
+//      speed > 100 | (status in [stopped,parked] ; !driver.id exists)
+//      created_at >= 2016-12-14T07:00:00Z ; name =~ "^(ana|bob)$"
+//
+// CriteriaSyntaxError is returned if the text is not valid criteria.
+func ParseCriteria(s string) (Criteria, error) {
+	p := &criteriaParser{s: s}
+
+	c, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+
+	if !p.eof() {
+		return nil, p.errorf("unexpected %q", p.s[p.pos])
+	}
+
+	return c, nil
+}
+
+// criteriaParser is a recursive descent parser of criteria text.
+type criteriaParser struct {
+	s   string
+	pos int
+}
+
+// parseOr parses conditions separated by `|`.
+func (p *criteriaParser) parseOr() (Criteria, error) {
+	var cc []Criteria
+
+	for {
+		c, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		cc = append(cc, c)
+
+		if !p.consume("|") {
+			break
+		}
+	}
+
+	if len(cc) == 1 {
+		return cc[0], nil
+	}
+
+	return Or(cc...), nil
+}
+
+// parseAnd parses conditions separated by `;`.
+func (p *criteriaParser) parseAnd() (Criteria, error) {
+	var cc []Criteria
+
+	for {
+		c, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		cc = append(cc, c)
+
+		if !p.consume(";") {
+			break
+		}
+	}
+
+	if len(cc) == 1 {
+		return cc[0], nil
+	}
+
+	return And(cc...), nil
+}
+
+// parseUnary parses a negated criteria, a group or a condition.
+func (p *criteriaParser) parseUnary() (Criteria, error) {
+	if p.consume("!") {
+		c, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return Not(c), nil
+	}
+
+	if p.consume("(") {
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.consume(")") {
+			return nil, p.errorf("missing %q", ')')
+		}
+
+		return c, nil
+	}
+
+	return p.parseCondition()
+}
+
+// parseCondition parses a key followed by an operator and its values.
+func (p *criteriaParser) parseCondition() (Criteria, error) {
+	p.skipSpaces()
+
+	start := p.pos
+	key := p.parseKey()
+
+	if key == "" {
+		return nil, p.errorf("missing key")
+	}
+
+	p.skipSpaces()
+
+	operator, ok := p.parseOperator()
+	if !ok {
+		return nil, p.errorf("missing operator")
+	}
+
+	var values []Value
+
+	switch operator {
+	case Exists, Missing:
+	case In, NotIn:
+		vv, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+
+		values = vv
+	default:
+		v, err := p.parseValue(";|)")
+		if err != nil {
+			return nil, err
+		}
+
+		values = []Value{v}
+	}
+
+	c, err := NewCriterion(key, operator, values...)
+	if err != nil {
+		return nil, &CriteriaSyntaxError{Offset: start, Msg: fmt.Sprintf("%s %q", err, p.s[start:p.pos])}
+	}
+
+	return c, nil
+}
+
+// parseKey reads the key until an operator or a space, escaped characters are kept escaped for the Key path.
+func (p *criteriaParser) parseKey() Key {
+	start := p.pos
+
+	for ; !p.eof(); p.pos++ {
+		ch := p.s[p.pos]
+
+		if ch == '\\' && p.pos+1 < len(p.s) {
+			p.pos++
+
+			continue
+		}
+
+		if strings.IndexByte(" \t=!<>:^$*~;|()", ch) >= 0 {
+			break
+		}
+	}
+
+	return Key(p.s[start:p.pos])
+}
+
+// parseOperator reads the operator.
+func (p *criteriaParser) parseOperator() (Operator, bool) {
+	for _, op := range parserOperators {
+		if !strings.HasPrefix(p.s[p.pos:], op.token) {
+			continue
+		}
+
+		end := p.pos + len(op.token)
+
+		// word operators must not be followed by a letter, i.e. `index` is not `in`.
+		if op.word && end < len(p.s) && isLetter(p.s[end]) {
+			continue
+		}
+
+		p.pos = end
+
+		return op.operator, true
+	}
+
+	return "", false
+}
+
+// parseList reads a list of values within brackets, separated by `,`.
+func (p *criteriaParser) parseList() ([]Value, error) {
+	if !p.consume("[") {
+		return nil, p.errorf("missing %q", '[')
+	}
+
+	var values []Value
+
+	for {
+		v, err := p.parseValue(",]")
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, v)
+
+		if !p.consume(",") {
+			break
+		}
+	}
+
+	if !p.consume("]") {
+		return nil, p.errorf("missing %q", ']')
+	}
+
+	return values, nil
+}
+
+// parseValue reads a double quoted value or a bare value until any of the terminators, trimming spaces.
+func (p *criteriaParser) parseValue(terminators string) (Value, error) {
+	p.skipSpaces()
+
+	if p.eof() || p.s[p.pos] != '"' {
+		start := p.pos
+
+		for !p.eof() && strings.IndexByte(terminators, p.s[p.pos]) < 0 {
+			p.pos++
+		}
+
+		return Value(strings.TrimSpace(p.s[start:p.pos])), nil
+	}
+
+	start := p.pos
+	p.pos++
+
+	var v strings.Builder
+
+	for ; !p.eof(); p.pos++ {
+		ch := p.s[p.pos]
+
+		switch {
+		case ch == '"':
+			p.pos++
+
+			return Value(v.String()), nil
+		case ch == '\\' && p.pos+1 < len(p.s) && (p.s[p.pos+1] == '"' || p.s[p.pos+1] == '\\'):
+			p.pos++
+			ch = p.s[p.pos]
+		}
+
+		v.WriteByte(ch)
+	}
+
+	return "", &CriteriaSyntaxError{Offset: start, Msg: "unterminated quoted value"}
+}
+
+// consume skips spaces and reads the token if it is next, returning whether it was read.
+func (p *criteriaParser) consume(token string) bool {
+	p.skipSpaces()
+
+	if strings.HasPrefix(p.s[p.pos:], token) {
+		p.pos += len(token)
+
+		return true
+	}
+
+	return false
+}
+
+// skipSpaces skips spaces and tabs.
+func (p *criteriaParser) skipSpaces() {
+	for !p.eof() && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// eof returns whether the whole text was read.
+func (p *criteriaParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+// errorf returns a CriteriaSyntaxError at the current offset.
+func (p *criteriaParser) errorf(format string, args ...interface{}) error {
+	return &CriteriaSyntaxError{Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// isLetter returns whether ch is an ASCII letter, digit or underscore.
+func isLetter(ch byte) bool {
+	return ch == '_' || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9')
+}
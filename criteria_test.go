@@ -0,0 +1,192 @@
+package swissarmyknife_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	swiss_army_knife "github.com/dohernandez/swiss-army-knife"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCriterion(t *testing.T) {
+	// An artificial value source.
+	var value map[string]interface{}
+
+	// The value is already Unmarshal to make easy the test
+	err := json.Unmarshal([]byte(`{"id":1629,"name":"driver-paris","created_at":"2016-12-14 18:48:11",`+
+		`"driver":{"location":{"city":"paris"}},"stops":[{"id":"a"}]}`), &value)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		scenario string
+		key      swiss_army_knife.Key
+		operator swiss_army_knife.Operator
+		values   []swiss_army_knife.Value
+		match    bool
+	}{
+		{scenario: "Equal numeric", key: "id", operator: swiss_army_knife.Equal, values: []swiss_army_knife.Value{"1629.0"}, match: true},
+		{scenario: "Equal string", key: "driver.location.city", operator: swiss_army_knife.Equal, values: []swiss_army_knife.Value{"paris"}, match: true},
+		{scenario: "Equal missing key", key: "unknown", operator: swiss_army_knife.Equal, values: []swiss_army_knife.Value{"paris"}},
+		{scenario: "Not equal", key: "id", operator: swiss_army_knife.NotEqual, values: []swiss_army_knife.Value{"1874"}, match: true},
+		{scenario: "Not equal missing key", key: "unknown", operator: swiss_army_knife.NotEqual, values: []swiss_army_knife.Value{"1874"}, match: true},
+		{scenario: "Less than numeric", key: "id", operator: swiss_army_knife.LessThan, values: []swiss_army_knife.Value{"10000"}, match: true},
+		{scenario: "Less than or equal numeric", key: "id", operator: swiss_army_knife.LessThanOrEqual, values: []swiss_army_knife.Value{"1629"}, match: true},
+		{scenario: "Greater than numeric", key: "id", operator: swiss_army_knife.GreaterThan, values: []swiss_army_knife.Value{"200"}, match: true},
+		{scenario: "Greater than or equal numeric", key: "id", operator: swiss_army_knife.GreaterThanOrEqual, values: []swiss_army_knife.Value{"1630"}},
+		{scenario: "Greater than timestamp", key: "created_at", operator: swiss_army_knife.GreaterThan, values: []swiss_army_knife.Value{"2016-12-14T07:00:00Z"}, match: true},
+		{scenario: "Less than timestamp", key: "created_at", operator: swiss_army_knife.LessThan, values: []swiss_army_knife.Value{"2016-12-14"}},
+		{scenario: "Less than string", key: "name", operator: swiss_army_knife.LessThan, values: []swiss_army_knife.Value{"driver-rome"}, match: true},
+		{scenario: "In", key: "id", operator: swiss_army_knife.In, values: []swiss_army_knife.Value{"347", "1629"}, match: true},
+		{scenario: "Not in", key: "id", operator: swiss_army_knife.NotIn, values: []swiss_army_knife.Value{"347", "1629"}},
+		{scenario: "Exists", key: "stops[0].id", operator: swiss_army_knife.Exists, match: true},
+		{scenario: "Missing", key: "stops[1].id", operator: swiss_army_knife.Missing, match: true},
+		{scenario: "Prefix", key: "name", operator: swiss_army_knife.HasPrefix, values: []swiss_army_knife.Value{"driver-"}, match: true},
+		{scenario: "Suffix", key: "name", operator: swiss_army_knife.HasSuffix, values: []swiss_army_knife.Value{"-rome"}},
+		{scenario: "Contains", key: "name", operator: swiss_army_knife.Contains, values: []swiss_army_knife.Value{"par"}, match: true},
+		{scenario: "Matches", key: "name", operator: swiss_army_knife.Matches, values: []swiss_army_knife.Value{`^driver-(paris|rome)$`}, match: true},
+		{scenario: "Not matches", key: "name", operator: swiss_army_knife.NotMatches, values: []swiss_army_knife.Value{`^\d+$`}, match: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			c, err := swiss_army_knife.NewCriterion(tc.key, tc.operator, tc.values...)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.match, c.Match(value))
+		})
+	}
+}
+
+func TestNewCriterionInvalid(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		key      swiss_army_knife.Key
+		operator swiss_army_knife.Operator
+		values   []swiss_army_knife.Value
+		err      error
+	}{
+		{scenario: "Invalid key", key: "driver.", operator: swiss_army_knife.Exists, err: swiss_army_knife.ErrInvalidKeyPath},
+		{scenario: "Unknown operator", key: "id", operator: "<>", values: []swiss_army_knife.Value{"1"}, err: swiss_army_knife.ErrInvalidCriteria},
+		{scenario: "Missing value", key: "id", operator: swiss_army_knife.Equal, err: swiss_army_knife.ErrInvalidCriteria},
+		{scenario: "Unexpected value", key: "id", operator: swiss_army_knife.Exists, values: []swiss_army_knife.Value{"1"}, err: swiss_army_knife.ErrInvalidCriteria},
+		{scenario: "Invalid regex", key: "id", operator: swiss_army_knife.Matches, values: []swiss_army_knife.Value{"("}, err: swiss_army_knife.ErrInvalidCriteria},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			_, err := swiss_army_knife.NewCriterion(tc.key, tc.operator, tc.values...)
+			assert.Equal(t, tc.err, err)
+		})
+	}
+}
+
+func TestParseCriteria(t *testing.T) {
+	records := []string{
+		`{"id":347,"speed":120,"status":"moving","driver":{"id":"d1"},"code":"007"}`,
+		`{"id":1629,"speed":0,"status":"parked"}`,
+		`{"id":1874,"speed":40,"status":"stopped","name":"a;b"}`,
+	}
+
+	values := make([]map[string]interface{}, len(records))
+	for i, r := range records {
+		assert.NoError(t, json.Unmarshal([]byte(r), &values[i]))
+	}
+
+	testCases := []struct {
+		scenario string
+		criteria string
+		matches  []bool
+	}{
+		{scenario: "Legacy key/value pair", criteria: "id:347", matches: []bool{true, false, false}},
+		{scenario: "Legacy key/value pair matches strings exactly", criteria: "code:7", matches: []bool{false, false, false}},
+		{scenario: "Legacy multiple key/value pair", criteria: "id:347;status:parked", matches: []bool{false, false, false}},
+		{scenario: "Comparison", criteria: "speed > 30", matches: []bool{true, false, true}},
+		{scenario: "And", criteria: "speed >= 40 ; speed<=100", matches: []bool{false, false, true}},
+		{scenario: "Or", criteria: "id == 347 | status:parked", matches: []bool{true, true, false}},
+		{scenario: "And takes precedence over or", criteria: "id:347 | speed>10;status!=moving", matches: []bool{true, false, true}},
+		{scenario: "Group", criteria: "(id:347 | speed>10);status!=moving", matches: []bool{false, false, true}},
+		{scenario: "Negation", criteria: "!(status in [parked, stopped])", matches: []bool{true, false, false}},
+		{scenario: "Not in", criteria: "status not in [parked,stopped]", matches: []bool{true, false, false}},
+		{scenario: "Exists", criteria: "driver.id exists", matches: []bool{true, false, false}},
+		{scenario: "Negated exists", criteria: "!driver.id exists", matches: []bool{false, true, true}},
+		{scenario: "Missing", criteria: "name missing", matches: []bool{true, true, false}},
+		{scenario: "Prefix", criteria: "status ^= mov", matches: []bool{true, false, false}},
+		{scenario: "Suffix", criteria: "status $= ed", matches: []bool{false, true, true}},
+		{scenario: "Contains", criteria: "status *= o", matches: []bool{true, false, true}},
+		{scenario: "Quoted value", criteria: `name == "a;b"`, matches: []bool{false, false, true}},
+		{scenario: "Regex", criteria: `status =~ "^(parked|stopped)$"`, matches: []bool{false, true, true}},
+		{scenario: "Not regex", criteria: `status !~ ^p`, matches: []bool{true, false, true}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			c, err := swiss_army_knife.ParseCriteria(tc.criteria)
+			assert.NoError(t, err)
+
+			for i, v := range values {
+				assert.Equal(t, tc.matches[i], c.Match(v), records[i])
+			}
+		})
+	}
+}
+
+func TestParseCriteriaNotANumber(t *testing.T) {
+	// NaN does not come from JSON, but from the inputs decoding floats as they are (i.e. msgpack).
+	value := map[string]interface{}{"id": "NaN", "speed": "Inf", "score": math.NaN()}
+
+	testCases := []struct {
+		scenario string
+		criteria string
+		match    bool
+	}{
+		{scenario: "NaN string is not equal", criteria: "id:347"},
+		{scenario: "NaN string is not less than or equal", criteria: "id<=1"},
+		{scenario: "Inf string is not equal", criteria: "speed == 1e400"},
+		{scenario: "NaN is not equal", criteria: "score == 1"},
+		{scenario: "NaN is not in", criteria: "score in [1, 2]"},
+		{scenario: "NaN is not less than", criteria: "score < 1"},
+		{scenario: "NaN is not less than or equal", criteria: "score <= 1"},
+		{scenario: "NaN is not greater than", criteria: "score > 1"},
+		{scenario: "NaN is not greater than or equal", criteria: "score >= 1"},
+		{scenario: "NaN is different", criteria: "score != 1", match: true},
+		{scenario: "NaN is not in negated", criteria: "score not in [1, 2]", match: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			c, err := swiss_army_knife.ParseCriteria(tc.criteria)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.match, c.Match(value))
+		})
+	}
+}
+
+func TestParseCriteriaInvalid(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		criteria string
+		err      string
+	}{
+		{scenario: "Empty", criteria: "", err: "invalid criteria: missing key at offset 0"},
+		{scenario: "Missing operator", criteria: "id 347", err: "invalid criteria: missing operator at offset 3"},
+		{scenario: "Missing parenthesis", criteria: "(id:347", err: "invalid criteria: missing ')' at offset 7"},
+		{scenario: "Unexpected parenthesis", criteria: "id:347)", err: "invalid criteria: unexpected ')' at offset 6"},
+		{scenario: "Missing list", criteria: "id in 347", err: "invalid criteria: missing '[' at offset 6"},
+		{scenario: "Unterminated quote", criteria: `name == "a`, err: "invalid criteria: unterminated quoted value at offset 8"},
+		{scenario: "Invalid key", criteria: "driver..id:1", err: `invalid criteria: invalid key path "driver..id:1" at offset 0`},
+		{scenario: "Invalid regex", criteria: "name =~ (", err: `invalid criteria: invalid criteria "name =~ (" at offset 0`},
+		{scenario: "Invalid quoted regex", criteria: `name =~ "("`, err: `invalid criteria: invalid criteria "name =~ \"(\"" at offset 0`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			_, err := swiss_army_knife.ParseCriteria(tc.criteria)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
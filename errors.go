@@ -1,6 +1,9 @@
 package swissarmyknife
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrTypeMismatch is returned when the casting is not ok.
@@ -8,4 +11,48 @@ var (
 
 	// ErrDoNotEmit is returned when the operation don't want to emit the current value to the next operation.
 	ErrDoNotEmit = errors.New("do not emit")
+
+	// ErrAborted is returned when the process was aborted because the errors exceeded the error policy.
+	ErrAborted = errors.New("process aborted, errors exceeded the error policy")
+
+	// ErrNotResumable is returned when checkpointing the process but the input is not able to resume
+	// (see sakio.ResumableInput).
+	ErrNotResumable = errors.New("input not resumable, checkpointing requires a resumable input")
+)
+
+const (
+	// InputStage is the operation index of the errors that occurred reading the input.
+	InputStage = -1
 )
+
+// RecordError is the error that occurred processing a record.
+type RecordError struct {
+	// OperationIndex is the position of the operation in the list of operations that failed processing the record.
+	// InputStage when reading the input failed and the amount of operations when outputting the record failed.
+	OperationIndex int
+	// OperationName is the name of the operation that failed processing the record, "input" or "output" when
+	// reading the input or outputting the record failed.
+	OperationName string
+	// Input is the record as it was read from the input, empty when the input does not provide it
+	// (see sakio.RawInput).
+	Input string
+	// Record is the record the operation failed processing, nil when reading the input failed.
+	Record interface{}
+	// Err is the error that occurred.
+	Err error
+}
+
+// Error returns the error message including the operation that failed.
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("%s (%d): %s", e.OperationName, e.OperationIndex, e.Err)
+}
+
+// Unwrap returns the error that occurred.
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the error that occurred, compatible with github.com/pkg/errors.
+func (e *RecordError) Cause() error {
+	return e.Err
+}
@@ -0,0 +1,1066 @@
+package swissarmyknife
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidExpression is returned when the expression is not valid.
+	ErrInvalidExpression = errors.New("invalid expression")
+
+	// ErrExpressionEvaluation is returned when the expression can not be evaluated against a record.
+	ErrExpressionEvaluation = errors.New("expression evaluation failed")
+)
+
+// ExpressionError is returned when parsing or evaluating an expression fails, reporting the offset in the
+// expression where it failed.
+type ExpressionError struct {
+	Offset int
+	Msg    string
+	// Err is ErrInvalidExpression when parsing failed or ErrExpressionEvaluation when evaluating failed.
+	Err error
+}
+
+// Error returns the error message.
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("%s: %s at offset %d", e.Err, e.Msg, e.Offset)
+}
+
+// Cause returns the error behind the expression error.
+func (e *ExpressionError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the error behind the expression error.
+func (e *ExpressionError) Unwrap() error {
+	return e.Err
+}
+
+// Expression is a parsed expression, ready to be evaluated against records.
+//
+// An expression is a list of statements separated by `;`. A statement is either an assignment `key = expr`
+// setting the value of the key, or a predicate `expr` skipping the record when it is not truthy.
+//
+// Expressions support:
+//
+//      literals:    1, 2.5, "text", 'text', true, false, null, [1, 2, 3]
+//      fields:      speed, driver.location.city, stops[0].id, driver["first name"]
+//      arithmetic:  + (numbers or strings concatenation), -, *, /, %
+//      comparison:  ==, !=, <, <=, >, >=, in
+//      logic:       &&, ||, !
+//      conditional: cond ? a : b
+//      functions:   len, lower, upper, trim, contains, startsWith, endsWith, replace, substr, split, join,
+//                   matches, string, number, abs, round, floor, ceil, min, max, coalesce, field
+//
+// Missing fields evaluate to null. Values are truthy unless they are null, false, zero, an empty string, or
+// an empty array or map.
+//
+// Common initialization example:
+//
+//      expression, err := ParseExpression(`speed > 80 && city == "paris"; distance_km = dist_m / 1000`)
+//      if err != nil {
+//			return err
+//		}
+//
+type Expression struct {
+	statements []statement
+}
+
+// statement is an expression, assigned to the path when the path is not nil.
+type statement struct {
+	path []pathSegment
+	expr exprNode
+}
+
+// ParseExpression parses the expression.
+//
+// ExpressionError is returned if the expression is not valid.
+func ParseExpression(s string) (*Expression, error) {
+	tokens, err := lexExpression(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &expressionParser{tokens: tokens}
+
+	var e Expression
+
+	for {
+		st, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+
+		e.statements = append(e.statements, st)
+
+		if !p.accept(";") {
+			break
+		}
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+
+	return &e, nil
+}
+
+// IsPredicate returns whether all the statements of the expression are predicates.
+func (e *Expression) IsPredicate() bool {
+	for _, st := range e.statements {
+		if st.path != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsAssignment returns whether all the statements of the expression are assignments.
+func (e *Expression) IsAssignment() bool {
+	for _, st := range e.statements {
+		if st.path == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewExpressionOperation creates an expression Operation based on expression.
+// The Expression statements are evaluated in order against the value, assignments set the value of their key
+// (the maps missing in the Key path are created) and predicates skip the value when they are not truthy.
+//
+// Accepts only value as a map[string]interface{} type.
+//
+// ErrTypeMismatch is returned if casting value interface{} to a map[string]interface{} fails.
+// ExpressionError is returned if the expression can not be evaluated against the value.
+// ErrDoNotEmit is returned when a predicate is not truthy, allowing the value to be skipped.
+// value is returned with all the assignments applied, none of them is applied if any fails.
+//
+// Common initialization example:
+//
+//      expression, err := ParseExpression("distance_km = dist_m / 1000")
+//      if err != nil {
+//			return err
+//		}
+//
+//      operation := NewExpressionOperation(context.TODO(), expression)
+//
+func NewExpressionOperation(_ context.Context, expression *Expression) Operation {
+	assignments := 0
+
+	for _, st := range expression.statements {
+		if st.path != nil {
+			assignments++
+		}
+	}
+
+	return func(ctx context.Context, value interface{}) (interface{}, error) {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, ErrTypeMismatch
+		}
+
+		// the assignments are applied to a copy of the value when there are more than one, the value being left
+		// untouched if any of them fails.
+		if assignments > 1 {
+			m = deepCopy(reflect.ValueOf(m)).Interface().(map[string]interface{})
+		}
+
+		for _, st := range expression.statements {
+			v, err := st.expr.eval(m)
+			if err != nil {
+				return nil, err
+			}
+
+			if st.path == nil {
+				if !truthy(v) {
+					// predicate not truthy, value must be skipped
+					return nil, ErrDoNotEmit
+				}
+
+				continue
+			}
+
+			if _, err := setPath(m, st.path, v); err != nil {
+				return nil, err
+			}
+		}
+
+		return m, nil
+	}
+}
+
+// token kinds.
+const (
+	tokenEOF = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenPunct
+)
+
+// token is a lexical token of an expression.
+type token struct {
+	kind   int
+	text   string
+	offset int
+	number float64
+}
+
+// expressionPuncts are the punctuation tokens, longest first.
+// nolint:gochecknoglobals
+var expressionPuncts = []string{
+	"==", "!=", "<=", ">=", "&&", "||",
+	"+", "-", "*", "/", "%", "<", ">", "!", "=", "(", ")", "[", "]", ",", ".", "?", ":", ";",
+}
+
+// lexExpression splits the expression into tokens.
+func lexExpression(s string) ([]token, error) {
+	var tokens []token
+
+	i := 0
+
+	for {
+		for i < len(s) && strings.IndexByte(" \t\r\n", s[i]) >= 0 {
+			i++
+		}
+
+		if i == len(s) {
+			return append(tokens, token{kind: tokenEOF, offset: i}), nil
+		}
+
+		start := i
+		ch := s[i]
+
+		switch {
+		case ch >= '0' && ch <= '9':
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' || s[i] == 'e' || s[i] == 'E' ||
+				(s[i] == '-' || s[i] == '+') && (s[i-1] == 'e' || s[i-1] == 'E')) {
+				i++
+			}
+
+			n, err := strconv.ParseFloat(s[start:i], 64)
+			if err != nil {
+				return nil, &ExpressionError{Offset: start, Msg: fmt.Sprintf("invalid number %q", s[start:i]), Err: ErrInvalidExpression}
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: s[start:i], offset: start, number: n})
+		case ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z':
+			for i < len(s) && isLetter(s[i]) {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, text: s[start:i], offset: start})
+		case ch == '"' || ch == '\'':
+			str, end, err := lexString(s, i)
+			if err != nil {
+				return nil, err
+			}
+
+			i = end
+			tokens = append(tokens, token{kind: tokenString, text: str, offset: start})
+		default:
+			punct := ""
+
+			for _, p := range expressionPuncts {
+				if strings.HasPrefix(s[i:], p) {
+					punct = p
+
+					break
+				}
+			}
+
+			if punct == "" {
+				return nil, &ExpressionError{Offset: start, Msg: fmt.Sprintf("unexpected %q", ch), Err: ErrInvalidExpression}
+			}
+
+			i += len(punct)
+			tokens = append(tokens, token{kind: tokenPunct, text: punct, offset: start})
+		}
+	}
+}
+
+// lexString reads the string quoted at offset i, returning its value and the offset following it.
+func lexString(s string, i int) (string, int, error) {
+	quote := s[i]
+	start := i
+
+	var str strings.Builder
+
+	for i++; i < len(s); i++ {
+		ch := s[i]
+
+		if ch == quote {
+			return str.String(), i + 1, nil
+		}
+
+		if ch == '\\' && i+1 < len(s) {
+			i++
+
+			switch s[i] {
+			case 'n':
+				ch = '\n'
+			case 't':
+				ch = '\t'
+			default:
+				ch = s[i]
+			}
+		}
+
+		str.WriteByte(ch)
+	}
+
+	return "", 0, &ExpressionError{Offset: start, Msg: "unterminated string", Err: ErrInvalidExpression}
+}
+
+// expressionParser is a recursive descent parser of expression tokens.
+type expressionParser struct {
+	tokens []token
+	pos    int
+}
+
+// peek returns the next token without reading it.
+func (p *expressionParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// next reads the next token.
+func (p *expressionParser) next() token {
+	t := p.tokens[p.pos]
+
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+// accept reads the next token if it is the punctuation given, returning whether it was read.
+func (p *expressionParser) accept(punct string) bool {
+	if t := p.peek(); t.kind == tokenPunct && t.text == punct {
+		p.pos++
+
+		return true
+	}
+
+	return false
+}
+
+// expect reads the next token, failing if it is not the punctuation given.
+func (p *expressionParser) expect(punct string) error {
+	if !p.accept(punct) {
+		return p.errorf(p.peek(), "missing %q", punct)
+	}
+
+	return nil
+}
+
+// errorf returns an ExpressionError at the token offset.
+func (p *expressionParser) errorf(t token, format string, args ...interface{}) error {
+	return &ExpressionError{Offset: t.offset, Msg: fmt.Sprintf(format, args...), Err: ErrInvalidExpression}
+}
+
+// parseStatement parses an assignment or a predicate.
+func (p *expressionParser) parseStatement() (statement, error) {
+	start := p.peek()
+
+	expr, err := p.parseTernary()
+	if err != nil {
+		return statement{}, err
+	}
+
+	if !p.accept("=") {
+		return statement{expr: expr}, nil
+	}
+
+	path, ok := assignmentPath(expr)
+	if !ok {
+		return statement{}, p.errorf(start, "invalid assignment target")
+	}
+
+	value, err := p.parseTernary()
+	if err != nil {
+		return statement{}, err
+	}
+
+	return statement{path: path, expr: value}, nil
+}
+
+// parseTernary parses `cond ? a : b`.
+func (p *expressionParser) parseTernary() (exprNode, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.accept("?") {
+		return cond, nil
+	}
+
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+
+	otherwise, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ternaryNode{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+// binaryPrecedence are the binary operators by precedence, lowest first.
+// nolint:gochecknoglobals
+var binaryPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// parseBinary parses the binary operators from the precedence level given.
+func (p *expressionParser) parseBinary(level int) (exprNode, error) {
+	if level == len(binaryPrecedence) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+
+		if !(t.kind == tokenPunct || t.kind == tokenIdent && t.text == "in") || !contains(binaryPrecedence[level], t.text) {
+			return left, nil
+		}
+
+		p.next()
+
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{op: t.text, left: left, right: right, offset: t.offset}
+	}
+}
+
+// parseUnary parses `!x` and `-x`.
+func (p *expressionParser) parseUnary() (exprNode, error) {
+	t := p.peek()
+
+	if t.kind == tokenPunct && (t.text == "!" || t.text == "-") {
+		p.next()
+
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &unaryNode{op: t.text, operand: operand, offset: t.offset}, nil
+	}
+
+	return p.parsePostfix()
+}
+
+// parsePostfix parses the member and index accesses following a primary expression.
+func (p *expressionParser) parsePostfix() (exprNode, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokenIdent {
+				return nil, p.errorf(t, "missing field name")
+			}
+
+			expr = &indexNode{container: expr, index: &literalNode{value: t.text}, offset: t.offset}
+		case p.accept("["):
+			t := p.peek()
+
+			index, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+
+			expr = &indexNode{container: expr, index: index, offset: t.offset}
+		default:
+			return expr, nil
+		}
+	}
+}
+
+// parsePrimary parses literals, fields, function calls, lists and parenthesis.
+func (p *expressionParser) parsePrimary() (exprNode, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenNumber:
+		return &literalNode{value: t.number}, nil
+	case tokenString:
+		return &literalNode{value: t.text}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+
+		if p.accept("(") {
+			return p.parseCall(t)
+		}
+
+		return &fieldNode{name: t.text}, nil
+	case tokenPunct:
+		switch t.text {
+		case "(":
+			expr, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+
+			return expr, nil
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+
+			return &listNode{items: items}, nil
+		}
+	}
+
+	if t.kind == tokenEOF {
+		return nil, p.errorf(t, "unexpected end of expression")
+	}
+
+	return nil, p.errorf(t, "unexpected %q", t.text)
+}
+
+// parseCall parses the arguments of the function call named by t.
+func (p *expressionParser) parseCall(t token) (exprNode, error) {
+	f, ok := expressionFunctions[t.text]
+	if !ok {
+		return nil, p.errorf(t, "unknown function %q", t.text)
+	}
+
+	args, err := p.parseList(")")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) < f.minArgs || f.maxArgs >= 0 && len(args) > f.maxArgs {
+		return nil, p.errorf(t, "wrong number of arguments for %s", t.text)
+	}
+
+	fn, err := compileCall(t.text, f.fn, args)
+	if err != nil {
+		return nil, p.errorf(t, "%s: %s", t.text, err)
+	}
+
+	return &callNode{name: t.text, fn: fn, args: args, offset: t.offset}, nil
+}
+
+// parseList parses expressions separated by `,` until the closing punctuation.
+func (p *expressionParser) parseList(closing string) ([]exprNode, error) {
+	var items []exprNode
+
+	if p.accept(closing) {
+		return items, nil
+	}
+
+	for {
+		item, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if err := p.expect(closing); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// assignmentPath returns the path assigned by the expression, false if the expression is not a field access
+// with literal indices.
+func assignmentPath(expr exprNode) ([]pathSegment, bool) {
+	switch n := expr.(type) {
+	case *fieldNode:
+		return []pathSegment{{name: n.name}}, true
+	case *indexNode:
+		path, ok := assignmentPath(n.container)
+		if !ok {
+			return nil, false
+		}
+
+		lit, ok := n.index.(*literalNode)
+		if !ok {
+			return nil, false
+		}
+
+		switch index := lit.value.(type) {
+		case string:
+			return append(path, pathSegment{name: index}), true
+		case float64:
+			if index < 0 || index != math.Trunc(index) {
+				return nil, false
+			}
+
+			return append(path, pathSegment{index: int(index), isIndex: true}), true
+		}
+	}
+
+	return nil, false
+}
+
+// exprNode is a node of the expression syntax tree.
+type exprNode interface {
+	eval(record map[string]interface{}) (interface{}, error)
+}
+
+// literalNode is a constant value.
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+// fieldNode is a top level field of the record.
+type fieldNode struct {
+	name string
+}
+
+func (n *fieldNode) eval(record map[string]interface{}) (interface{}, error) {
+	return record[n.name], nil
+}
+
+// indexNode is a map key or array index access.
+type indexNode struct {
+	container exprNode
+	index     exprNode
+	offset    int
+}
+
+func (n *indexNode) eval(record map[string]interface{}) (interface{}, error) {
+	c, err := n.container.eval(record)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := n.index.eval(record)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c := c.(type) {
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return nil, evalErrorf(n.offset, "map key must be a string, got %T", index)
+		}
+
+		return c[key], nil
+	case []interface{}:
+		i, ok := numberValue(index)
+		if !ok || i != math.Trunc(i) {
+			return nil, evalErrorf(n.offset, "array index must be an integer, got %v", index)
+		}
+
+		if i < 0 || int(i) >= len(c) {
+			return nil, nil
+		}
+
+		return c[int(i)], nil
+	}
+
+	// accessing a missing or scalar value evaluates to null.
+	return nil, nil
+}
+
+// listNode is an array literal.
+type listNode struct {
+	items []exprNode
+}
+
+func (n *listNode) eval(record map[string]interface{}) (interface{}, error) {
+	items := make([]interface{}, len(n.items))
+
+	for i, item := range n.items {
+		v, err := item.eval(record)
+		if err != nil {
+			return nil, err
+		}
+
+		items[i] = v
+	}
+
+	return items, nil
+}
+
+// ternaryNode is `cond ? then : otherwise`.
+type ternaryNode struct {
+	cond      exprNode
+	then      exprNode
+	otherwise exprNode
+}
+
+func (n *ternaryNode) eval(record map[string]interface{}) (interface{}, error) {
+	cond, err := n.cond.eval(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if truthy(cond) {
+		return n.then.eval(record)
+	}
+
+	return n.otherwise.eval(record)
+}
+
+// unaryNode is `!operand` or `-operand`.
+type unaryNode struct {
+	op      string
+	operand exprNode
+	offset  int
+}
+
+func (n *unaryNode) eval(record map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "!" {
+		return !truthy(v), nil
+	}
+
+	f, ok := numberValue(v)
+	if !ok {
+		return nil, evalErrorf(n.offset, "cannot negate %T", v)
+	}
+
+	return -f, nil
+}
+
+// binaryNode is `left op right`.
+type binaryNode struct {
+	op     string
+	left   exprNode
+	right  exprNode
+	offset int
+}
+
+func (n *binaryNode) eval(record map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(record)
+	if err != nil {
+		return nil, err
+	}
+
+	// logical operators short-circuit.
+	switch n.op {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+
+		right, err := n.right.eval(record)
+
+		return truthy(right), err
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+
+		right, err := n.right.eval(record)
+
+		return truthy(right), err
+	}
+
+	right, err := n.right.eval(record)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equalValues(left, right), nil
+	case "!=":
+		return !equalValues(left, right), nil
+	case "<", "<=", ">", ">=":
+		return n.compare(left, right)
+	case "in":
+		return n.in(left, right)
+	}
+
+	return n.arithmetic(left, right)
+}
+
+// compare applies the comparison operator, numbers and strings are comparable, comparing with null is false.
+func (n *binaryNode) compare(left, right interface{}) (interface{}, error) {
+	if left == nil || right == nil {
+		return false, nil
+	}
+
+	var c int
+
+	lf, lok := numberValue(left)
+	rf, rok := numberValue(right)
+	ls, lsok := left.(string)
+	rs, rsok := right.(string)
+
+	switch {
+	case lok && rok:
+		switch {
+		case lf < rf:
+			c = -1
+		case lf > rf:
+			c = 1
+		}
+	case lsok && rsok:
+		c = strings.Compare(ls, rs)
+	default:
+		return nil, evalErrorf(n.offset, "cannot compare %T and %T", left, right)
+	}
+
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+// in returns whether left is an element of the array, a key of the map or a substring of the string right.
+func (n *binaryNode) in(left, right interface{}) (interface{}, error) {
+	switch r := right.(type) {
+	case nil:
+		return false, nil
+	case []interface{}:
+		for _, item := range r {
+			if equalValues(left, item) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case map[string]interface{}:
+		key, ok := left.(string)
+		if !ok {
+			return false, nil
+		}
+
+		_, ok = r[key]
+
+		return ok, nil
+	case string:
+		s, ok := left.(string)
+
+		return ok && strings.Contains(r, s), nil
+	}
+
+	return nil, evalErrorf(n.offset, "cannot look up in %T", right)
+}
+
+// arithmetic applies the arithmetic operator, `+` concatenates when any of the operands is a string.
+func (n *binaryNode) arithmetic(left, right interface{}) (interface{}, error) {
+	if n.op == "+" {
+		_, lok := left.(string)
+		_, rok := right.(string)
+
+		if lok || rok {
+			return stringValue(left) + stringValue(right), nil
+		}
+	}
+
+	lf, lok := numberValue(left)
+	rf, rok := numberValue(right)
+
+	if !lok || !rok {
+		return nil, evalErrorf(n.offset, "cannot apply %s to %T and %T", n.op, left, right)
+	}
+
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, evalErrorf(n.offset, "division by zero")
+		}
+
+		return lf / rf, nil
+	default:
+		if rf == 0 {
+			return nil, evalErrorf(n.offset, "division by zero")
+		}
+
+		return math.Mod(lf, rf), nil
+	}
+}
+
+// callNode is a function call.
+type callNode struct {
+	name   string
+	fn     func(args []interface{}) (interface{}, error)
+	args   []exprNode
+	offset int
+}
+
+func (n *callNode) eval(record map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+
+	for i, arg := range n.args {
+		v, err := arg.eval(record)
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = v
+	}
+
+	// field is evaluated against the record, using the Key path syntax.
+	if n.name == "field" {
+		key, ok := args[0].(string)
+		if !ok {
+			return nil, evalErrorf(n.offset, "field: key must be a string, got %T", args[0])
+		}
+
+		path, err := Key(key).path()
+		if err != nil {
+			return nil, evalErrorf(n.offset, "field: %s %q", err, key)
+		}
+
+		v, _ := getPath(record, path)
+
+		return v, nil
+	}
+
+	v, err := n.fn(args)
+	if err != nil {
+		return nil, evalErrorf(n.offset, "%s: %s", n.name, err)
+	}
+
+	return v, nil
+}
+
+// evalErrorf returns an ExpressionError caused by ErrExpressionEvaluation.
+func evalErrorf(offset int, format string, args ...interface{}) error {
+	return &ExpressionError{Offset: offset, Msg: fmt.Sprintf(format, args...), Err: ErrExpressionEvaluation}
+}
+
+// truthy returns whether the value is not null, false, zero, an empty string or an empty array or map.
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case []interface{}:
+		return len(t) > 0
+	case map[string]interface{}:
+		return len(t) > 0
+	}
+
+	if f, ok := numberValue(v); ok {
+		return f != 0
+	}
+
+	return true
+}
+
+// numberValue returns the value as a float64, false if the value is not a number. Strings are not numbers.
+func numberValue(v interface{}) (float64, bool) {
+	if _, ok := v.(string); ok {
+		return 0, false
+	}
+
+	return toNumber(v)
+}
+
+// stringValue returns the value as a string, null being the empty string.
+func stringValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(v)
+}
+
+// equalValues returns whether the values are equal, numbers being compared numerically.
+func equalValues(a, b interface{}) bool {
+	af, aok := numberValue(a)
+	bf, bok := numberValue(b)
+
+	if aok && bok {
+		return af == bf
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// contains returns whether s is in the list.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,250 @@
+package swissarmyknife
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// expressionFunction is a function available in expressions, maxArgs is -1 when it is variadic.
+type expressionFunction struct {
+	minArgs int
+	maxArgs int
+	fn      func(args []interface{}) (interface{}, error)
+}
+
+// expressionFunctions are the functions available in expressions, by name.
+// nolint:gochecknoglobals
+var expressionFunctions = map[string]expressionFunction{
+	"len":        {minArgs: 1, maxArgs: 1, fn: lenFunction},
+	"lower":      {minArgs: 1, maxArgs: 1, fn: stringFunction(strings.ToLower)},
+	"upper":      {minArgs: 1, maxArgs: 1, fn: stringFunction(strings.ToUpper)},
+	"trim":       {minArgs: 1, maxArgs: 1, fn: stringFunction(strings.TrimSpace)},
+	"contains":   {minArgs: 2, maxArgs: 2, fn: stringPredicateFunction(strings.Contains)},
+	"startsWith": {minArgs: 2, maxArgs: 2, fn: stringPredicateFunction(strings.HasPrefix)},
+	"endsWith":   {minArgs: 2, maxArgs: 2, fn: stringPredicateFunction(strings.HasSuffix)},
+	"replace":    {minArgs: 3, maxArgs: 3, fn: replaceFunction},
+	"substr":     {minArgs: 2, maxArgs: 3, fn: substrFunction},
+	"split":      {minArgs: 2, maxArgs: 2, fn: splitFunction},
+	"join":       {minArgs: 2, maxArgs: 2, fn: joinFunction},
+	"matches":    {minArgs: 2, maxArgs: 2, fn: matchesFunction},
+	"string":     {minArgs: 1, maxArgs: 1, fn: stringConversionFunction},
+	"number":     {minArgs: 1, maxArgs: 1, fn: numberConversionFunction},
+	"abs":        {minArgs: 1, maxArgs: 1, fn: mathFunction(math.Abs)},
+	"round":      {minArgs: 1, maxArgs: 1, fn: mathFunction(math.Round)},
+	"floor":      {minArgs: 1, maxArgs: 1, fn: mathFunction(math.Floor)},
+	"ceil":       {minArgs: 1, maxArgs: 1, fn: mathFunction(math.Ceil)},
+	"min":        {minArgs: 1, maxArgs: -1, fn: extremumFunction(math.Min)},
+	"max":        {minArgs: 1, maxArgs: -1, fn: extremumFunction(math.Max)},
+	"coalesce":   {minArgs: 1, maxArgs: -1, fn: coalesceFunction},
+	// field is evaluated by the call node, it needs the record.
+	"field": {minArgs: 1, maxArgs: 1},
+}
+
+// lenFunction returns the length of a string, array or map, zero for null.
+func lenFunction(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case nil:
+		return float64(0), nil
+	case string:
+		return float64(len([]rune(v))), nil
+	case []interface{}:
+		return float64(len(v)), nil
+	case map[string]interface{}:
+		return float64(len(v)), nil
+	}
+
+	return nil, fmt.Errorf("unexpected %T", args[0])
+}
+
+// stringFunction creates a function transforming a string, null being the empty string.
+func stringFunction(f func(string) string) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		return f(stringValue(args[0])), nil
+	}
+}
+
+// stringPredicateFunction creates a function testing two strings.
+func stringPredicateFunction(f func(s, substr string) bool) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		return f(stringValue(args[0]), stringValue(args[1])), nil
+	}
+}
+
+// replaceFunction replaces all the occurrences of old by new in the string.
+func replaceFunction(args []interface{}) (interface{}, error) {
+	return strings.Replace(stringValue(args[0]), stringValue(args[1]), stringValue(args[2]), -1), nil
+}
+
+// substrFunction returns the substring from start, of length characters when given.
+func substrFunction(args []interface{}) (interface{}, error) {
+	s := []rune(stringValue(args[0]))
+
+	start, ok := numberValue(args[1])
+	if !ok {
+		return nil, fmt.Errorf("start must be a number, got %T", args[1])
+	}
+
+	from := clamp(int(start), 0, len(s))
+	to := len(s)
+
+	if len(args) == 3 {
+		length, ok := numberValue(args[2])
+		if !ok {
+			return nil, fmt.Errorf("length must be a number, got %T", args[2])
+		}
+
+		to = clamp(from+int(length), from, len(s))
+	}
+
+	return string(s[from:to]), nil
+}
+
+// splitFunction splits the string by the separator.
+func splitFunction(args []interface{}) (interface{}, error) {
+	parts := strings.Split(stringValue(args[0]), stringValue(args[1]))
+
+	items := make([]interface{}, len(parts))
+	for i, part := range parts {
+		items[i] = part
+	}
+
+	return items, nil
+}
+
+// joinFunction joins the array items with the separator.
+func joinFunction(args []interface{}) (interface{}, error) {
+	items, ok := args[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected %T", args[0])
+	}
+
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = stringValue(item)
+	}
+
+	return strings.Join(parts, stringValue(args[1])), nil
+}
+
+// matchesFunction returns whether the string matches the regular expression.
+func matchesFunction(args []interface{}) (interface{}, error) {
+	re, err := regexp.Compile(stringValue(args[1]))
+	if err != nil {
+		return nil, err
+	}
+
+	return re.MatchString(stringValue(args[0])), nil
+}
+
+// compileCall returns the function applied by the call with the arguments, matches compiling a constant regular
+// expression once instead of for every record.
+func compileCall(name string, fn func(args []interface{}) (interface{}, error), args []exprNode) (func(args []interface{}) (interface{}, error), error) {
+	if name != "matches" {
+		return fn, nil
+	}
+
+	pattern, ok := args[1].(*literalNode)
+	if !ok {
+		return fn, nil
+	}
+
+	re, err := regexp.Compile(stringValue(pattern.value))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(args []interface{}) (interface{}, error) {
+		return re.MatchString(stringValue(args[0])), nil
+	}, nil
+}
+
+// stringConversionFunction returns the value as a string.
+func stringConversionFunction(args []interface{}) (interface{}, error) {
+	return stringValue(args[0]), nil
+}
+
+// numberConversionFunction returns the value as a number, parsing strings.
+func numberConversionFunction(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", v)
+		}
+
+		return f, nil
+	case bool:
+		if v {
+			return float64(1), nil
+		}
+
+		return float64(0), nil
+	}
+
+	f, ok := numberValue(args[0])
+	if !ok {
+		return nil, fmt.Errorf("unexpected %T", args[0])
+	}
+
+	return f, nil
+}
+
+// mathFunction creates a function applying f to a number.
+func mathFunction(f func(float64) float64) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		n, ok := numberValue(args[0])
+		if !ok {
+			return nil, fmt.Errorf("unexpected %T", args[0])
+		}
+
+		return f(n), nil
+	}
+}
+
+// extremumFunction creates a function reducing numbers with f.
+func extremumFunction(f func(x, y float64) float64) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		var r float64
+
+		for i, arg := range args {
+			n, ok := numberValue(arg)
+			if !ok {
+				return nil, fmt.Errorf("unexpected %T", arg)
+			}
+
+			if i == 0 {
+				r = n
+			} else {
+				r = f(r, n)
+			}
+		}
+
+		return r, nil
+	}
+}
+
+// coalesceFunction returns the first argument that is not null.
+func coalesceFunction(args []interface{}) (interface{}, error) {
+	for _, arg := range args {
+		if arg != nil {
+			return arg, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// clamp returns n limited to the range [min, max].
+func clamp(n, min, max int) int {
+	switch {
+	case n < min:
+		return min
+	case n > max:
+		return max
+	default:
+		return n
+	}
+}
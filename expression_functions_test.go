@@ -0,0 +1,56 @@
+package swissarmyknife_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpressionFunctions(t *testing.T) {
+	record := `{"name":" Ana Paris ","tags":["a","b"],"speed":92.5,"created.at":"2016-12-14","driver":{"id":"d1"}}`
+
+	testCases := []struct {
+		scenario   string
+		expression string
+		result     interface{}
+	}{
+		{scenario: "len string", expression: `len("añb")`, result: float64(3)},
+		{scenario: "len array", expression: "len(tags)", result: float64(2)},
+		{scenario: "len null", expression: "len(missing)", result: float64(0)},
+		{scenario: "lower", expression: "lower(name)", result: " ana paris "},
+		{scenario: "upper", expression: "upper(name)", result: " ANA PARIS "},
+		{scenario: "trim", expression: "trim(name)", result: "Ana Paris"},
+		{scenario: "contains", expression: `contains(name, "Par")`, result: true},
+		{scenario: "startsWith", expression: `startsWith(trim(name), "Ana")`, result: true},
+		{scenario: "endsWith", expression: `endsWith(name, "x")`, result: false},
+		{scenario: "replace", expression: `replace(trim(name), " ", "_")`, result: "Ana_Paris"},
+		{scenario: "substr", expression: `substr("driver", 1, 3)`, result: "riv"},
+		{scenario: "substr to end", expression: `substr("driver", 3)`, result: "ver"},
+		{scenario: "substr out of range", expression: `substr("driver", 4, 10)`, result: "er"},
+		{scenario: "split", expression: `split("a,b", ",")`, result: []interface{}{"a", "b"}},
+		{scenario: "join", expression: `join(tags, "|")`, result: "a|b"},
+		{scenario: "matches", expression: `matches(trim(name), "^Ana\\s")`, result: true},
+		{scenario: "matches evaluated pattern", expression: `matches(trim(name), lower("^ANA\\s"))`, result: false},
+		{scenario: "matches concatenated pattern", expression: `matches(trim(name), "^" + substr(name, 1, 3))`, result: true},
+		{scenario: "string", expression: "string(speed)", result: "92.5"},
+		{scenario: "number", expression: `number("12.5") + 1`, result: 13.5},
+		{scenario: "abs", expression: "abs(-speed)", result: 92.5},
+		{scenario: "round", expression: "round(speed)", result: float64(93)},
+		{scenario: "floor", expression: "floor(speed)", result: float64(92)},
+		{scenario: "ceil", expression: "ceil(speed)", result: float64(93)},
+		{scenario: "min", expression: "min(speed, 10, 50)", result: float64(10)},
+		{scenario: "max", expression: "max(speed, 10, 50)", result: 92.5},
+		{scenario: "coalesce", expression: `coalesce(missing, driver.id, "none")`, result: "d1"},
+		{scenario: "field", expression: `field("created\\.at")`, result: "2016-12-14"},
+		{scenario: "field nested", expression: `field("tags[1]")`, result: "b"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			r, err := evaluate(t, tc.expression, record)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.result, r)
+		})
+	}
+}
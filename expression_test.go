@@ -0,0 +1,245 @@
+package swissarmyknife_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	swiss_army_knife "github.com/dohernandez/swiss-army-knife"
+	"github.com/stretchr/testify/assert"
+)
+
+// evaluate evaluates the expression against the record, assigning it to the `result` key.
+func evaluate(t *testing.T, expression string, record string) (interface{}, error) {
+	t.Helper()
+
+	var value map[string]interface{}
+
+	err := json.Unmarshal([]byte(record), &value)
+	assert.NoError(t, err)
+
+	e, err := swiss_army_knife.ParseExpression("result = " + expression)
+	if !assert.NoError(t, err) {
+		return nil, err
+	}
+
+	ctx := context.TODO()
+
+	r, err := swiss_army_knife.NewExpressionOperation(ctx, e)(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.(map[string]interface{})["result"], nil
+}
+
+func TestExpressionEvaluation(t *testing.T) {
+	record := `{"id":347,"speed":92.5,"city":"paris","dist_m":2500,"driver":{"name":"Ana","tags":["a","b"]},` +
+		`"stops":[{"id":"s1"},{"id":"s2"}],"active":true,"empty":""}`
+
+	testCases := []struct {
+		scenario   string
+		expression string
+		result     interface{}
+	}{
+		{scenario: "Number literal", expression: "1.5e2", result: float64(150)},
+		{scenario: "String literal", expression: `'it\'s'`, result: "it's"},
+		{scenario: "Null literal", expression: "null", result: nil},
+		{scenario: "List literal", expression: `[1, "a", true]`, result: []interface{}{float64(1), "a", true}},
+		{scenario: "Field", expression: "city", result: "paris"},
+		{scenario: "Nested field", expression: "driver.name", result: "Ana"},
+		{scenario: "Array index", expression: "stops[1].id", result: "s2"},
+		{scenario: "Map key", expression: `driver["name"]`, result: "Ana"},
+		{scenario: "Missing field", expression: "driver.location.lat", result: nil},
+		{scenario: "Out of range index", expression: "stops[5]", result: nil},
+		{scenario: "Arithmetic precedence", expression: "1 + 2 * 3 - 4 / 2", result: float64(5)},
+		{scenario: "Parenthesis", expression: "(1 + 2) * 3", result: float64(9)},
+		{scenario: "Modulo", expression: "id % 10", result: float64(7)},
+		{scenario: "Unary minus", expression: "-speed", result: -92.5},
+		{scenario: "Field arithmetic", expression: "dist_m / 1000", result: 2.5},
+		{scenario: "String concatenation", expression: `city + "-" + id`, result: "paris-347"},
+		{scenario: "Comparison", expression: "speed > 80", result: true},
+		{scenario: "String comparison", expression: `city < "rome"`, result: true},
+		{scenario: "Comparison with null", expression: "missing > 1", result: false},
+		{scenario: "Equality", expression: `id == 347 && city == "paris"`, result: true},
+		{scenario: "Inequality", expression: `city != "paris"`, result: false},
+		{scenario: "Null equality", expression: "missing == null", result: true},
+		{scenario: "Or", expression: `speed > 100 || active`, result: true},
+		{scenario: "Not", expression: "!empty", result: true},
+		{scenario: "Short-circuit", expression: "false && 1 / 0", result: false},
+		{scenario: "In list", expression: `city in ["paris", "rome"]`, result: true},
+		{scenario: "In array field", expression: `"c" in driver.tags`, result: false},
+		{scenario: "In map", expression: `"name" in driver`, result: true},
+		{scenario: "Ternary", expression: `speed > 80 ? "fast" : "slow"`, result: "fast"},
+		{scenario: "Nested ternary", expression: `speed > 100 ? "fast" : speed > 50 ? "medium" : "slow"`, result: "medium"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			r, err := evaluate(t, tc.expression, record)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.result, r)
+		})
+	}
+}
+
+func TestExpressionEvaluationError(t *testing.T) {
+	testCases := []struct {
+		scenario   string
+		expression string
+		err        string
+	}{
+		{scenario: "Division by zero", expression: "1 / 0", err: "expression evaluation failed: division by zero at offset 11"},
+		{scenario: "Arithmetic type mismatch", expression: "true * 2", err: "expression evaluation failed: cannot apply * to bool and float64 at offset 14"},
+		{scenario: "Comparison type mismatch", expression: `"a" < 1`, err: "expression evaluation failed: cannot compare string and float64 at offset 13"},
+		{scenario: "Function error", expression: `number("a")`, err: `expression evaluation failed: number: invalid number "a" at offset 9`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			_, err := evaluate(t, tc.expression, `{}`)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestParseExpressionInvalid(t *testing.T) {
+	testCases := []struct {
+		scenario   string
+		expression string
+		err        string
+	}{
+		{scenario: "Empty", expression: "", err: "invalid expression: unexpected end of expression at offset 0"},
+		{scenario: "Unexpected token", expression: "speed >", err: "invalid expression: unexpected end of expression at offset 7"},
+		{scenario: "Unexpected character", expression: "speed # 1", err: `invalid expression: unexpected '#' at offset 6`},
+		{scenario: "Trailing token", expression: "speed 1", err: `invalid expression: unexpected "1" at offset 6`},
+		{scenario: "Missing parenthesis", expression: "(speed > 1", err: `invalid expression: missing ")" at offset 10`},
+		{scenario: "Unterminated string", expression: `city == "paris`, err: "invalid expression: unterminated string at offset 8"},
+		{scenario: "Unknown function", expression: "foo(1)", err: `invalid expression: unknown function "foo" at offset 0`},
+		{scenario: "Wrong number of arguments", expression: "lower(1, 2)", err: "invalid expression: wrong number of arguments for lower at offset 0"},
+		{scenario: "Invalid regular expression", expression: `matches(name, "(")`, err: "invalid expression: matches: error parsing regexp: missing closing ): `(` at offset 0"},
+		{scenario: "Invalid assignment target", expression: "a + 1 = 2", err: "invalid expression: invalid assignment target at offset 0"},
+		{scenario: "Invalid assignment index", expression: "stops[i] = 2", err: "invalid expression: invalid assignment target at offset 0"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			_, err := swiss_army_knife.ParseExpression(tc.expression)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestExpressionOperation(t *testing.T) {
+	record := `{"id":347,"speed":92.5,"city":"paris","dist_m":2500,"stops":[{"id":"s1"}]}`
+
+	testCases := []struct {
+		scenario   string
+		expression string
+		predicate  bool
+		assignment bool
+		result     string
+	}{
+		{
+			scenario:   "Operation keeps value matching predicate",
+			expression: `speed > 80 && city == "paris"`,
+			predicate:  true,
+			result:     record,
+		},
+		{
+			scenario:   "Operation skips value not matching predicate",
+			expression: `speed > 80 && city == "rome"`,
+			predicate:  true,
+		},
+		{
+			scenario:   "Operation computes field",
+			expression: "distance_km = dist_m / 1000",
+			assignment: true,
+			result:     `{"id":347,"speed":92.5,"city":"paris","dist_m":2500,"distance_km":2.5,"stops":[{"id":"s1"}]}`,
+		},
+		{
+			scenario:   "Operation computes nested fields in order",
+			expression: `trip.km = dist_m / 1000; trip.label = upper(city) + " " + trip.km; stops[0].id = "s0"`,
+			assignment: true,
+			result: `{"id":347,"speed":92.5,"city":"paris","dist_m":2500,"stops":[{"id":"s0"}],` +
+				`"trip":{"km":2.5,"label":"PARIS 2.5"}}`,
+		},
+		{
+			scenario:   "Operation computes field and skips value",
+			expression: "fast = speed > 100; fast",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			var value map[string]interface{}
+
+			err := json.Unmarshal([]byte(record), &value)
+			assert.NoError(t, err)
+
+			e, err := swiss_army_knife.ParseExpression(tc.expression)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.predicate, e.IsPredicate())
+			assert.Equal(t, tc.assignment, e.IsAssignment())
+
+			ctx := context.TODO()
+
+			r, err := swiss_army_knife.NewExpressionOperation(ctx, e)(ctx, value)
+			if tc.result == "" {
+				assert.EqualError(t, err, swiss_army_knife.ErrDoNotEmit.Error())
+				assert.Empty(t, r)
+			} else {
+				var result map[string]interface{}
+
+				assert.NoError(t, json.Unmarshal([]byte(tc.result), &result))
+				assert.NoError(t, err)
+				assert.Equal(t, result, r)
+			}
+		})
+	}
+}
+
+func TestExpressionOperationError(t *testing.T) {
+	record := `{"id":347,"speed":92.5,"dist_m":2500,"stops":[{"id":"s1"}]}`
+
+	testCases := []struct {
+		scenario   string
+		expression string
+		err        string
+	}{
+		{
+			scenario:   "Operation fails evaluating a later assignment",
+			expression: `distance_km = dist_m / 1000; stops[0].id = "s0"; speed = speed / 0`,
+			err:        "expression evaluation failed: division by zero at offset 63",
+		},
+		{
+			scenario:   "Operation fails setting a later assignment",
+			expression: `trip.km = dist_m / 1000; id.value = 1`,
+			err:        swiss_army_knife.ErrTypeMismatch.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			var value, original map[string]interface{}
+
+			assert.NoError(t, json.Unmarshal([]byte(record), &value))
+			assert.NoError(t, json.Unmarshal([]byte(record), &original))
+
+			e, err := swiss_army_knife.ParseExpression(tc.expression)
+			assert.NoError(t, err)
+
+			ctx := context.TODO()
+
+			r, err := swiss_army_knife.NewExpressionOperation(ctx, e)(ctx, value)
+			assert.EqualError(t, err, tc.err)
+			assert.Empty(t, r)
+			assert.Equal(t, original, value, "value must be left untouched")
+		})
+	}
+}
@@ -0,0 +1,878 @@
+package io
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// AvroRecordReader reads the records of an Avro object container file, decoded with the schema embedded in it.
+//
+// Records are converted as unmarshaling JSON decodes them: records and maps become map[string]interface{}, arrays
+// []interface{}, numbers float64, bytes and fixed base64 strings, enums their symbol and unions the value of the
+// type they hold. Timestamps are RFC 3339 strings, dates YYYY-MM-DD strings, times of the day durations, i.e. 1h30m,
+// and decimals float64.
+type AvroRecordReader struct {
+	r      io.Reader
+	ocf    *goavro.OCFReader
+	schema *avroSchema
+}
+
+// NewAvroRecordReader creates an instance of AvroRecordReader reading the object container file from r.
+func NewAvroRecordReader(r io.Reader) *AvroRecordReader {
+	return &AvroRecordReader{
+		r: r,
+	}
+}
+
+// ReadRecord returns the next record. The header of the file is read the first time it is called.
+//
+// Returns any error that occurred, including io.EOF when no more record is available, an empty stream having no
+// record, and an error when the header or a block of the file is not valid. The stream is not readable after an
+// error.
+func (r *AvroRecordReader) ReadRecord() (interface{}, error) {
+	if r.ocf == nil {
+		br := bufio.NewReader(r.r)
+
+		if _, err := br.Peek(1); err != nil {
+			return nil, err
+		}
+
+		ocf, err := goavro.NewOCFReader(br)
+		if err != nil {
+			return nil, err
+		}
+
+		schema, err := parseAvroSchema(string(ocf.MetaData()["avro.schema"]))
+		if err != nil {
+			return nil, err
+		}
+
+		r.ocf = ocf
+		r.schema = schema
+	}
+
+	if !r.ocf.Scan() {
+		if err := r.ocf.Err(); err != nil {
+			return nil, err
+		}
+
+		return nil, io.EOF
+	}
+
+	v, err := r.ocf.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.schema.toGeneric(r.schema.root, v), nil
+}
+
+// Read returns the next record as compact JSON.
+//
+// Returns any error that occurred, see ReadRecord.
+func (r *AvroRecordReader) Read() (string, error) {
+	v, err := r.ReadRecord()
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// Schema returns the schema embedded in the file, empty until the first record is read.
+func (r *AvroRecordReader) Schema() string {
+	if r.ocf == nil {
+		return ""
+	}
+
+	return string(r.ocf.MetaData()["avro.schema"])
+}
+
+// AvroInput reads the input data from an Avro object container file, yielding the records as maps decoded with the
+// schema embedded in the file, see AvroRecordReader.
+//
+// Common initialization example:
+//
+//      input := NewAvroInput(os.Stdin)
+//
+type AvroInput struct {
+	reader *AvroRecordReader
+
+	record interface{}
+}
+
+var _ RawInput = new(AvroInput)
+
+// NewAvroInput creates an instance of AvroInput reading the object container file from r.
+func NewAvroInput(r io.Reader) *AvroInput {
+	return &AvroInput{
+		reader: NewAvroRecordReader(r),
+	}
+}
+
+// Next returns the next record of the file. Starting from the first record when it is call the first time.
+//
+// Returns any error that occurred, including io.EOF when no more record is available.
+func (i *AvroInput) Next(_ context.Context) (interface{}, error) {
+	r, err := i.reader.ReadRecord()
+	if err != nil {
+		return nil, err
+	}
+
+	i.record = r
+
+	return r, nil
+}
+
+// Raw returns the last record returned by Next as compact JSON.
+func (i *AvroInput) Raw() string {
+	b, err := json.Marshal(i.record)
+	if err != nil {
+		return ""
+	}
+
+	return string(b)
+}
+
+// Schema returns the schema embedded in the file, empty until the first record is read.
+func (i *AvroInput) Schema() string {
+	return i.reader.Schema()
+}
+
+// defaultAvroBlockSize is the max amount of records of the blocks written by AvroOutput when none is set.
+const defaultAvroBlockSize = 1000
+
+// AvroOutput writes the output data as an Avro object container file, the records being encoded in blocks.
+//
+// Records are the values as unmarshaling JSON decodes them, converted into the types of the schema (see
+// AvroRecordReader), the keys not matching any field of a record being discarded. The schema is the one set with
+// WithSchema, otherwise it is inferred from the first record: a record whose fields are its keys, sorted, all of
+// them nullable, numbers being double, nested maps records and arrays typed after their first element.
+//
+// Records are buffered and written as a block when the block is full, periodically when a flush interval is set
+// and when Write, Flush or Close are called.
+//
+// Common initialization example:
+//
+//      output := NewAvroOutput(os.Stdout).
+//			WithSchema(schema).
+//			WithCompression("snappy").
+//			WithFlushInterval(time.Second)
+//
+type AvroOutput struct {
+	w             io.Writer
+	schema        string
+	compression   string
+	blockSize     int
+	flushInterval time.Duration
+
+	mu     sync.Mutex
+	ocf    *goavro.OCFWriter
+	codec  *avroSchema
+	block  []interface{}
+	err    error
+	ticker *time.Ticker
+	done   chan struct{}
+}
+
+var _ StreamOutput = new(AvroOutput)
+
+// NewAvroOutput creates an instance of AvroOutput writing the object container file to w, the header first.
+func NewAvroOutput(w io.Writer) *AvroOutput {
+	return &AvroOutput{
+		w:         w,
+		blockSize: defaultAvroBlockSize,
+	}
+}
+
+// Append adds the output data to the block being buffered, the header being written before the first one.
+//
+// Errors are kept and returned by the next call to Write, Flush or Close, once an error occurred
+// the output data is discarded.
+func (o *AvroOutput) Append(_ context.Context, output interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.err != nil {
+		return
+	}
+
+	if o.ocf == nil {
+		if o.err = o.start(output); o.err != nil {
+			return
+		}
+	}
+
+	v, err := o.codec.toNative(o.codec.root, output, "")
+	if err != nil {
+		o.err = err
+
+		return
+	}
+
+	o.block = append(o.block, v)
+
+	if len(o.block) >= o.blockSize || o.flushInterval == 0 {
+		o.flush()
+	}
+}
+
+// start writes the header of the file, with the schema inferred from the record when none is set, and initializes
+// the periodic flush, if any.
+func (o *AvroOutput) start(record interface{}) error {
+	schema := o.schema
+	if schema == "" {
+		m, ok := record.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("avro output expects map[string]interface{} records to infer the schema, got %T", record)
+		}
+
+		b, err := json.Marshal(inferAvroType("Record", m))
+		if err != nil {
+			return err
+		}
+
+		schema = string(b)
+	}
+
+	codec, err := parseAvroSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	// wrapped so an *os.File, i.e. os.Stdout redirected to a file, is written as a new file instead of appended to.
+	ocf, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               struct{ io.Writer }{o.w},
+		Schema:          schema,
+		CompressionName: o.compression,
+	})
+	if err != nil {
+		return err
+	}
+
+	o.ocf = ocf
+	o.codec = codec
+
+	if o.flushInterval == 0 {
+		return nil
+	}
+
+	o.ticker = time.NewTicker(o.flushInterval)
+	o.done = make(chan struct{})
+
+	go func(ticker *time.Ticker, done chan struct{}) {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				o.mu.Lock()
+				o.flush()
+				o.mu.Unlock()
+			}
+		}
+	}(o.ticker, o.done)
+
+	return nil
+}
+
+// flush writes the buffered records as a block keeping the first error that occurred.
+func (o *AvroOutput) flush() {
+	if o.err != nil || len(o.block) == 0 {
+		return
+	}
+
+	o.err = o.ocf.Append(o.block)
+	o.block = o.block[:0]
+}
+
+// Write writes the buffered records as a block.
+//
+// Returns any error that occurred.
+func (o *AvroOutput) Write(ctx context.Context) error {
+	return o.Flush(ctx)
+}
+
+// Flush writes the buffered records as a block.
+//
+// Returns any error that occurred.
+func (o *AvroOutput) Flush(_ context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.flush()
+
+	return o.err
+}
+
+// Close writes the buffered records as a block and stops the periodic flush. When no record was appended and the
+// schema is set, the header is written, so the file is valid. The io.Writer is not closed.
+//
+// Returns any error that occurred.
+func (o *AvroOutput) Close(_ context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.ocf == nil && o.schema != "" && o.err == nil {
+		o.err = o.start(nil)
+	}
+
+	if o.ticker != nil {
+		o.ticker.Stop()
+		close(o.done)
+
+		o.ticker = nil
+	}
+
+	o.flush()
+
+	return o.err
+}
+
+// WithSchema set the Avro schema, as JSON, of the records into AvroOutput. Inferred from the first record when
+// not set.
+func (o *AvroOutput) WithSchema(schema string) *AvroOutput {
+	o.schema = schema
+
+	return o
+}
+
+// WithCompression set the codec compressing the blocks into AvroOutput: null, the default, deflate or snappy.
+func (o *AvroOutput) WithCompression(compression string) *AvroOutput {
+	o.compression = compression
+
+	return o
+}
+
+// WithBlockSize set the max amount of records of the blocks into AvroOutput.
+func (o *AvroOutput) WithBlockSize(blockSize int) *AvroOutput {
+	if blockSize > 0 {
+		o.blockSize = blockSize
+	}
+
+	return o
+}
+
+// WithFlushInterval set the interval to write the buffered records into AvroOutput.
+// Zero means writing a block after each record appended.
+func (o *AvroOutput) WithFlushInterval(flushInterval time.Duration) *AvroOutput {
+	o.flushInterval = flushInterval
+
+	return o
+}
+
+// ValidAvroCompression returns an error when the name is not a codec compressing the blocks of Avro files.
+func ValidAvroCompression(compression string) error {
+	switch compression {
+	case "", goavro.CompressionNullLabel, goavro.CompressionDeflateLabel, goavro.CompressionSnappyLabel:
+		return nil
+	}
+
+	return fmt.Errorf("unknown avro compression %q, valid are null, deflate and snappy", compression)
+}
+
+// inferAvroType returns the Avro type of the value, the records being named after the path of their key.
+func inferAvroType(name string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		fields := make([]interface{}, len(keys))
+		for n, k := range keys {
+			fields[n] = map[string]interface{}{
+				"name":    k,
+				"type":    []interface{}{"null", inferAvroType(name+"_"+k, v[k])},
+				"default": nil,
+			}
+		}
+
+		return map[string]interface{}{"type": "record", "name": name, "fields": fields}
+	case []interface{}:
+		var items interface{} = "string"
+
+		for _, e := range v {
+			if e != nil {
+				items = inferAvroType(name, e)
+
+				break
+			}
+		}
+
+		return map[string]interface{}{"type": "array", "items": []interface{}{"null", items}}
+	case bool:
+		return "boolean"
+	case float64, float32, int, int64, int32, json.Number:
+		return "double"
+	}
+
+	return "string"
+}
+
+// avroSchema is an Avro schema parsed from JSON along with the named types it defines, to convert the values
+// between the goavro native types and the types unmarshaling JSON decodes into.
+type avroSchema struct {
+	root  interface{}
+	names map[string]map[string]interface{}
+}
+
+// parseAvroSchema parses the schema, the names of the named types being replaced by their full name.
+func parseAvroSchema(schema string) (*avroSchema, error) {
+	var root interface{}
+
+	if err := json.Unmarshal([]byte(schema), &root); err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %v", err)
+	}
+
+	s := &avroSchema{
+		root:  root,
+		names: make(map[string]map[string]interface{}),
+	}
+	s.collect(root, "")
+
+	return s, nil
+}
+
+// collect registers the named types defined in the type by their full and short name.
+func (s *avroSchema) collect(t interface{}, namespace string) {
+	switch t := t.(type) {
+	case []interface{}:
+		for _, m := range t {
+			s.collect(m, namespace)
+		}
+	case map[string]interface{}:
+		switch t["type"] {
+		case "record", "error", "enum", "fixed":
+			name, _ := t["name"].(string)
+			if ns, ok := t["namespace"].(string); ok {
+				namespace = ns
+			}
+
+			if n := strings.LastIndex(name, "."); n >= 0 {
+				namespace = name[:n]
+			} else if namespace != "" {
+				name = namespace + "." + name
+			}
+
+			t["name"] = name
+			s.names[name] = t
+
+			if short := name[strings.LastIndex(name, ".")+1:]; s.names[short] == nil {
+				s.names[short] = t
+			}
+
+			fields, _ := t["fields"].([]interface{})
+			for _, f := range fields {
+				if f, ok := f.(map[string]interface{}); ok {
+					s.collect(f["type"], namespace)
+				}
+			}
+		case "array":
+			s.collect(t["items"], namespace)
+		case "map":
+			s.collect(t["values"], namespace)
+		default:
+			s.collect(t["type"], namespace)
+		}
+	}
+}
+
+// resolve returns the type name, i.e. record or long, and the definition, if any, of the type.
+func (s *avroSchema) resolve(t interface{}) (string, map[string]interface{}) {
+	switch t := t.(type) {
+	case string:
+		if def, ok := s.names[t]; ok {
+			typ, _ := def["type"].(string)
+
+			return typ, def
+		}
+
+		return t, nil
+	case map[string]interface{}:
+		if typ, ok := t["type"].(string); ok {
+			if def, ok := s.names[typ]; ok {
+				return s.resolve(def["name"])
+			}
+
+			return typ, t
+		}
+
+		return s.resolve(t["type"])
+	case []interface{}:
+		return "union", nil
+	}
+
+	return "", nil
+}
+
+// logicalType returns the logical type of the type definition, empty if none.
+func logicalType(def map[string]interface{}) string {
+	lt, _ := def["logicalType"].(string)
+
+	return lt
+}
+
+// unionName returns the name goavro wraps the values of the type with when it is a member of a union.
+func (s *avroSchema) unionName(t interface{}) string {
+	typ, def := s.resolve(t)
+
+	switch typ {
+	case "record", "error", "enum", "fixed":
+		name, _ := def["name"].(string)
+
+		return name
+	}
+
+	switch lt := typ + "." + logicalType(def); lt {
+	case "long.timestamp-millis", "long.timestamp-micros", "int.time-millis", "long.time-micros", "int.date",
+		"bytes.decimal":
+		return lt
+	}
+
+	return typ
+}
+
+// toGeneric converts a goavro native value of the type into the types unmarshaling JSON decodes into.
+func (s *avroSchema) toGeneric(t interface{}, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	if union, ok := t.([]interface{}); ok {
+		if m, ok := v.(map[string]interface{}); ok && len(m) == 1 {
+			for name, uv := range m {
+				for _, member := range union {
+					if s.unionName(member) == name {
+						return s.toGeneric(member, uv)
+					}
+				}
+			}
+		}
+
+		return avroScalar(v)
+	}
+
+	typ, def := s.resolve(t)
+
+	switch typ {
+	case "record", "error":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		record := make(map[string]interface{}, len(m))
+		fields, _ := def["fields"].([]interface{})
+
+		for _, f := range fields {
+			f, _ := f.(map[string]interface{})
+			name, _ := f["name"].(string)
+
+			if fv, ok := m[name]; ok {
+				record[name] = s.toGeneric(f["type"], fv)
+			}
+		}
+
+		return record
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			break
+		}
+
+		items := make([]interface{}, len(a))
+		for n, e := range a {
+			items[n] = s.toGeneric(def["items"], e)
+		}
+
+		return items
+	case "map":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		values := make(map[string]interface{}, len(m))
+		for k, e := range m {
+			values[k] = s.toGeneric(def["values"], e)
+		}
+
+		return values
+	case "int":
+		if d, ok := v.(time.Time); ok && logicalType(def) == "date" {
+			return d.UTC().Format("2006-01-02")
+		}
+	}
+
+	return avroScalar(v)
+}
+
+// avroScalar converts a goavro native scalar value into the types unmarshaling JSON decodes into.
+func avroScalar(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	case *big.Rat:
+		f, _ := v.Float64()
+
+		return f
+	}
+
+	return v
+}
+
+// toNative converts a value, as unmarshaling JSON decodes it, into the goavro native value of the type. The field
+// is the name of the record field holding the value, for the errors.
+func (s *avroSchema) toNative(t interface{}, v interface{}, field string) (interface{}, error) {
+	if union, ok := t.([]interface{}); ok {
+		names := make([]string, len(union))
+
+		for n, member := range union {
+			names[n] = s.unionName(member)
+
+			if names[n] == "null" {
+				if v == nil {
+					return nil, nil
+				}
+
+				continue
+			}
+
+			if nv, err := s.toNative(member, v, field); err == nil {
+				return map[string]interface{}{names[n]: nv}, nil
+			}
+		}
+
+		return nil, avroValueError(v, strings.Join(names, "|"), field)
+	}
+
+	typ, def := s.resolve(t)
+
+	switch typ {
+	case "null":
+		if v == nil {
+			return nil, nil
+		}
+	case "boolean":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case "int":
+		switch logicalType(def) {
+		case "date":
+			return avroTime(v, "2006-01-02", typ, field)
+		case "time-millis":
+			return avroDuration(v, typ, field)
+		}
+
+		if n, ok := avroWhole(v, math.MinInt32, math.MaxInt32); ok {
+			return int32(n), nil
+		}
+	case "long":
+		switch logicalType(def) {
+		case "timestamp-millis", "timestamp-micros":
+			return avroTime(v, time.RFC3339Nano, typ, field)
+		case "time-micros":
+			return avroDuration(v, typ, field)
+		}
+
+		if n, ok := avroWhole(v, math.MinInt64, math.MaxInt64); ok {
+			return int64(n), nil
+		}
+	case "float":
+		if n, ok := avroNumber(v); ok {
+			return float32(n), nil
+		}
+	case "double":
+		if n, ok := avroNumber(v); ok {
+			return n, nil
+		}
+	case "string":
+		if str, ok := v.(string); ok {
+			return str, nil
+		}
+	case "bytes", "fixed":
+		if logicalType(def) == "decimal" {
+			if n, ok := avroNumber(v); ok {
+				return new(big.Rat).SetFloat64(n), nil
+			}
+
+			break
+		}
+
+		switch b := v.(type) {
+		case []byte:
+			return b, nil
+		case string:
+			if d, err := base64.StdEncoding.DecodeString(b); err == nil {
+				return d, nil
+			}
+		}
+	case "enum":
+		if str, ok := v.(string); ok {
+			symbols, _ := def["symbols"].([]interface{})
+			for _, symbol := range symbols {
+				if symbol == str {
+					return str, nil
+				}
+			}
+		}
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			break
+		}
+
+		items := make([]interface{}, len(a))
+
+		for n, e := range a {
+			item, err := s.toNative(def["items"], e, field)
+			if err != nil {
+				return nil, err
+			}
+
+			items[n] = item
+		}
+
+		return items, nil
+	case "map":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		values := make(map[string]interface{}, len(m))
+
+		for k, e := range m {
+			value, err := s.toNative(def["values"], e, field)
+			if err != nil {
+				return nil, err
+			}
+
+			values[k] = value
+		}
+
+		return values, nil
+	case "record", "error":
+		return s.recordToNative(def, v, field)
+	}
+
+	return nil, avroValueError(v, typ, field)
+}
+
+// recordToNative converts a map into the goavro native value of the record type, the keys not matching any field
+// being discarded and the fields missing, or nil, taking their default value, if any.
+func (s *avroSchema) recordToNative(def map[string]interface{}, v interface{}, field string) (interface{}, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		if field == "" {
+			return nil, fmt.Errorf("avro output expects map[string]interface{} records, got %T", v)
+		}
+
+		return nil, avroValueError(v, "record", field)
+	}
+
+	record := make(map[string]interface{}, len(m))
+	fields, _ := def["fields"].([]interface{})
+
+	for _, f := range fields {
+		f, _ := f.(map[string]interface{})
+		name, _ := f["name"].(string)
+
+		fv := m[name]
+		if _, ok := f["default"]; ok && fv == nil {
+			continue
+		}
+
+		nv, err := s.toNative(f["type"], fv, name)
+		if err != nil {
+			return nil, err
+		}
+
+		record[name] = nv
+	}
+
+	return record, nil
+}
+
+// avroNumber returns the number as float64, false if it is not one. Unlike protobuf, strings holding a number are
+// not, so the values of the unions with a string member are kept as strings.
+func avroNumber(v interface{}) (float64, bool) {
+	if _, ok := v.(string); ok {
+		return 0, false
+	}
+
+	return toFloat64(v)
+}
+
+// avroWhole returns the number as a whole number within min and max, false if it is not.
+func avroWhole(v interface{}, min, max float64) (float64, bool) {
+	n, ok := avroNumber(v)
+	if !ok || n != math.Trunc(n) || n < min || n > max {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// avroTime returns the time of the string in the layout, for the date and timestamp logical types.
+func avroTime(v interface{}, layout, typ, field string) (interface{}, error) {
+	if str, ok := v.(string); ok {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t, nil
+		}
+	}
+
+	return nil, avroValueError(v, typ, field)
+}
+
+// avroDuration returns the duration of the string, i.e. 1h30m, for the time of the day logical types.
+func avroDuration(v interface{}, typ, field string) (interface{}, error) {
+	if str, ok := v.(string); ok {
+		if d, err := time.ParseDuration(str); err == nil {
+			return d, nil
+		}
+	}
+
+	return nil, avroValueError(v, typ, field)
+}
+
+// avroValueError returns the error of a value not matching its type.
+func avroValueError(v interface{}, typ, field string) error {
+	if field == "" {
+		return fmt.Errorf("invalid value %v (%T) for %s", v, v, typ)
+	}
+
+	return fmt.Errorf("invalid value %v (%T) for %s field %s", v, v, typ, field)
+}
@@ -0,0 +1,285 @@
+package io_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/stretchr/testify/assert"
+)
+
+// ridesAvroSchema is the schema of the rides, covering the types converted from and to the records.
+const ridesAvroSchema = `{
+	"type": "record",
+	"name": "Ride",
+	"namespace": "rides.v1",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "lat", "type": ["null", "double"], "default": null},
+		{"name": "city", "type": ["null", "string", "long"], "default": null},
+		{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["MOVING", "STOPPED"]}, "default": "MOVING"},
+		{"name": "stops", "type": {"type": "array", "items": {"type": "record", "name": "Stop", "fields": [{"name": "id", "type": "int"}]}}, "default": []},
+		{"name": "last_stop", "type": ["null", "Stop"], "default": null},
+		{"name": "tags", "type": {"type": "map", "values": "string"}, "default": {}},
+		{"name": "checksum", "type": ["null", "bytes"], "default": null},
+		{"name": "started_at", "type": ["null", {"type": "long", "logicalType": "timestamp-millis"}], "default": null},
+		{"name": "day", "type": ["null", {"type": "int", "logicalType": "date"}], "default": null}
+	]
+}`
+
+func TestAvroOutput(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		schema   string
+		record   map[string]interface{}
+		expected map[string]interface{}
+		err      string
+	}{
+		{
+			scenario: "Round trip all the types",
+			schema:   ridesAvroSchema,
+			record: map[string]interface{}{
+				"id":         float64(347),
+				"lat":        48.8566,
+				"city":       "paris",
+				"status":     "STOPPED",
+				"stops":      []interface{}{map[string]interface{}{"id": float64(1)}},
+				"last_stop":  map[string]interface{}{"id": float64(1)},
+				"tags":       map[string]interface{}{"source": "gps"},
+				"checksum":   "AQI=",
+				"started_at": "2019-06-01T10:30:00.5Z",
+				"day":        "2019-06-01",
+			},
+		},
+		{
+			scenario: "Defaults, unions and unknown keys discarded",
+			schema:   ridesAvroSchema,
+			record:   map[string]interface{}{"id": float64(12), "city": float64(75), "c_lat": 48.8566, "lat": nil},
+			expected: map[string]interface{}{
+				"id":         float64(12),
+				"lat":        nil,
+				"city":       float64(75),
+				"status":     "MOVING",
+				"stops":      []interface{}{},
+				"last_stop":  nil,
+				"tags":       map[string]interface{}{},
+				"checksum":   nil,
+				"started_at": nil,
+				"day":        nil,
+			},
+		},
+		{
+			scenario: "Inferred schema",
+			record: map[string]interface{}{
+				"id":     float64(347),
+				"active": true,
+				"driver": map[string]interface{}{"city": "paris"},
+				"stops":  []interface{}{float64(1), nil},
+				"none":   nil,
+			},
+		},
+		{
+			scenario: "Invalid value",
+			schema:   ridesAvroSchema,
+			record:   map[string]interface{}{"id": 1.5},
+			err:      "invalid value 1.5 (float64) for long field id",
+		},
+		{
+			scenario: "Invalid enum value",
+			schema:   ridesAvroSchema,
+			record:   map[string]interface{}{"id": float64(1), "status": "PARKED"},
+			err:      "invalid value PARKED (string) for enum field status",
+		},
+		{
+			scenario: "Invalid nested value",
+			schema:   ridesAvroSchema,
+			record:   map[string]interface{}{"id": float64(1), "stops": []interface{}{map[string]interface{}{"id": "1"}}},
+			err:      "invalid value 1 (string) for int field id",
+		},
+		{
+			scenario: "Invalid union value",
+			schema:   ridesAvroSchema,
+			record:   map[string]interface{}{"id": float64(1), "city": true},
+			err:      "invalid value true (bool) for null|string|long field city",
+		},
+		{
+			scenario: "Missing value",
+			schema:   ridesAvroSchema,
+			record:   map[string]interface{}{"lat": 1.5},
+			err:      "invalid value <nil> (<nil>) for long field id",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			ctx := context.TODO()
+
+			var buf bytes.Buffer
+
+			output := sakio.NewAvroOutput(&buf).
+				WithSchema(tc.schema)
+
+			output.Append(ctx, tc.record)
+
+			err := output.Close(ctx)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			input := sakio.NewAvroInput(&buf)
+
+			r, err := input.Next(ctx)
+			assert.NoError(t, err)
+
+			expected := tc.expected
+			if expected == nil {
+				expected = tc.record
+			}
+
+			assert.Equal(t, expected, r)
+
+			_, err = input.Next(ctx)
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestAvroOutputBlocks(t *testing.T) {
+	for _, compression := range []string{"null", "deflate", "snappy"} {
+		compression := compression // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(compression, func(t *testing.T) {
+			ctx := context.TODO()
+
+			var buf bytes.Buffer
+
+			output := sakio.NewAvroOutput(&buf).
+				WithCompression(compression).
+				WithBlockSize(2).
+				WithFlushInterval(time.Hour)
+
+			for n := 1; n <= 3; n++ {
+				output.Append(ctx, map[string]interface{}{"id": float64(n), "city": strings.Repeat("paris", 10)})
+			}
+
+			// the first block is written once full, the header first.
+			assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("Obj\x01")))
+			written := buf.Len()
+
+			assert.NoError(t, output.Flush(ctx))
+			assert.True(t, buf.Len() > written)
+			assert.NoError(t, output.Close(ctx))
+
+			input := sakio.NewAvroInput(&buf)
+
+			for n := 1; n <= 3; n++ {
+				r, err := input.Next(ctx)
+				assert.NoError(t, err)
+				assert.Equal(t, map[string]interface{}{"id": float64(n), "city": strings.Repeat("paris", 10)}, r)
+			}
+
+			assert.Contains(t, input.Schema(), `"name":"Record"`)
+			assert.Equal(t, `{"city":"parisparisparisparisparisparisparisparisparisparis","id":3}`, input.Raw())
+
+			_, err := input.Next(ctx)
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestAvroOutputError(t *testing.T) {
+	ctx := context.TODO()
+
+	output := sakio.NewAvroOutput(ioutil.Discard)
+	output.Append(ctx, "347")
+	assert.EqualError(t, output.Close(ctx), "avro output expects map[string]interface{} records to infer the schema, got string")
+
+	output = sakio.NewAvroOutput(ioutil.Discard).WithCompression("bzip2")
+	output.Append(ctx, map[string]interface{}{"id": float64(1)})
+	assert.Error(t, output.Flush(ctx))
+
+	output = sakio.NewAvroOutput(ioutil.Discard).WithSchema(ridesAvroSchema)
+	output.Append(ctx, "347")
+	assert.EqualError(t, output.Close(ctx), "avro output expects map[string]interface{} records, got string")
+
+	assert.NoError(t, sakio.ValidAvroCompression("snappy"))
+	assert.EqualError(t, sakio.ValidAvroCompression("gzip"), `unknown avro compression "gzip", valid are null, deflate and snappy`)
+}
+
+func TestAvroOutputEmpty(t *testing.T) {
+	ctx := context.TODO()
+
+	var buf bytes.Buffer
+
+	// the header is written with the schema set, so the file is valid.
+	assert.NoError(t, sakio.NewAvroOutput(&buf).WithSchema(ridesAvroSchema).Close(ctx))
+
+	_, err := sakio.NewAvroInput(&buf).Next(ctx)
+	assert.Equal(t, io.EOF, err)
+
+	// nothing is written without records to infer the schema from.
+	buf.Reset()
+	assert.NoError(t, sakio.NewAvroOutput(&buf).Close(ctx))
+	assert.Equal(t, 0, buf.Len())
+
+	_, err = sakio.NewAvroInput(&buf).Next(ctx)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestAvroInputInvalid(t *testing.T) {
+	_, err := sakio.NewAvroInput(strings.NewReader(`{"id":1}`)).Next(context.TODO())
+	assert.Error(t, err)
+}
+
+func TestFileInputNextAvro(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	ctx := context.TODO()
+
+	var buf bytes.Buffer
+
+	output := sakio.NewAvroOutput(&buf).WithBlockSize(1)
+	output.Append(ctx, map[string]interface{}{"id": float64(1), "driver": map[string]interface{}{"city": "paris"}})
+	output.Append(ctx, map[string]interface{}{"id": float64(2)})
+	assert.NoError(t, output.Close(ctx))
+
+	a := writeFile(t, dir, "a.avro", buf.Bytes())
+	b := writeFile(t, dir, "b.avro.gz", gzipped(t, buf.String()))
+
+	input := sakio.NewFileInput(filepath.Join(dir, "*")).
+		WithAvro()
+
+	expected := []struct {
+		raw    string
+		source sakio.Source
+	}{
+		{raw: `{"driver":{"city":"paris"},"id":1}`, source: sakio.Source{File: a, Line: 1}},
+		{raw: `{"driver":null,"id":2}`, source: sakio.Source{File: a, Line: 2}},
+		{raw: `{"driver":{"city":"paris"},"id":1}`, source: sakio.Source{File: b, Line: 1}},
+		{raw: `{"driver":null,"id":2}`, source: sakio.Source{File: b, Line: 2}},
+	}
+
+	for _, e := range expected {
+		r, err := input.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, e.raw, r)
+		assert.Equal(t, e.source, input.Source())
+	}
+
+	_, err = input.Next(ctx)
+	assert.Equal(t, io.EOF, err)
+}
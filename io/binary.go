@@ -0,0 +1,163 @@
+package io
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack"
+)
+
+// UnmarshalMsgpack is an UnmarshalInput func decoding a MessagePack value, i.e. the record of a frame read by
+// FrameInput.
+//
+// Values are decoded as unmarshaling JSON does: maps as map[string]interface{} and numbers as float64, so the
+// operations work the same way whatever the input format is.
+func UnmarshalMsgpack(_ context.Context, i string) (interface{}, error) {
+	var v interface{}
+
+	if err := msgpack.Unmarshal([]byte(i), &v); err != nil {
+		return nil, err
+	}
+
+	return normalizeBinary(v), nil
+}
+
+// MarshalMsgpack is a MarshalOutput func encoding a record as a MessagePack value, i.e. to be written framed (see
+// WriterOutput.WithFraming). Numbers without fractional part are encoded as integers, in as few bytes as possible,
+// and the map keys are sorted.
+func MarshalMsgpack(_ context.Context, i interface{}) (string, error) {
+	var buf bytes.Buffer
+
+	enc := msgpack.NewEncoder(&buf).
+		UseCompactEncoding(true).
+		SortMapKeys(true)
+
+	if err := enc.Encode(denormalizeBinary(i)); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// UnmarshalCBOR is an UnmarshalInput func decoding a CBOR value, i.e. the record of a frame read by FrameInput.
+//
+// Values are decoded as unmarshaling JSON does: maps as map[string]interface{} and numbers as float64, so the
+// operations work the same way whatever the input format is.
+func UnmarshalCBOR(_ context.Context, i string) (interface{}, error) {
+	var v interface{}
+
+	if err := cbor.Unmarshal([]byte(i), &v); err != nil {
+		return nil, err
+	}
+
+	return normalizeBinary(v), nil
+}
+
+// MarshalCBOR is a MarshalOutput func encoding a record as a CBOR value, i.e. to be written framed (see
+// WriterOutput.WithFraming). Numbers without fractional part are encoded as integers and the map keys are sorted.
+func MarshalCBOR(_ context.Context, i interface{}) (string, error) {
+	em, err := cbor.EncOptions{Sort: cbor.SortCanonical}.EncMode()
+	if err != nil {
+		return "", err
+	}
+
+	r, err := em.Marshal(denormalizeBinary(i))
+	if err != nil {
+		return "", err
+	}
+
+	return string(r), nil
+}
+
+// normalizeBinary returns the decoded value with the maps keyed by string and the numbers as float64.
+func normalizeBinary(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalizeBinary(e)
+		}
+
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = normalizeBinary(e)
+		}
+
+		return m
+	case []interface{}:
+		for n, e := range v {
+			v[n] = normalizeBinary(e)
+		}
+
+		return v
+	case int8:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case int:
+		return float64(v)
+	case uint8:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case float32:
+		return float64(v)
+	}
+
+	return v
+}
+
+// maxSafeInteger is the greatest integer a float64 holds exactly.
+const maxSafeInteger = 1 << 53
+
+// denormalizeBinary returns the value with the numbers without fractional part as int64, so they are encoded as
+// integers instead of floats.
+func denormalizeBinary(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = denormalizeBinary(e)
+		}
+
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for n, e := range v {
+			s[n] = denormalizeBinary(e)
+		}
+
+		return s
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= maxSafeInteger {
+			return int64(v)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+
+		return v.String()
+	}
+
+	return v
+}
@@ -0,0 +1,91 @@
+package io_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinaryCodecs(t *testing.T) {
+	codecs := []struct {
+		scenario  string
+		marshal   sakio.MarshalOutput
+		unmarshal sakio.UnmarshalInput
+	}{
+		{scenario: "MessagePack", marshal: sakio.MarshalMsgpack, unmarshal: sakio.UnmarshalMsgpack},
+		{scenario: "CBOR", marshal: sakio.MarshalCBOR, unmarshal: sakio.UnmarshalCBOR},
+	}
+
+	testCases := []struct {
+		scenario string
+		record   interface{}
+		expected interface{}
+	}{
+		{
+			scenario: "Round trip nested values",
+			record: map[string]interface{}{
+				"id":     float64(347),
+				"speed":  12.5,
+				"big":    float64(-1 << 40),
+				"active": true,
+				"name":   "driver",
+				"none":   nil,
+				"stops":  []interface{}{float64(1), "two", map[string]interface{}{"lat": 48.8566}},
+				"driver": map[string]interface{}{"city": "paris"},
+			},
+		},
+		{
+			scenario: "Round trip json numbers",
+			record:   map[string]interface{}{"id": json.Number("347"), "lat": json.Number("48.8566")},
+			expected: map[string]interface{}{"id": float64(347), "lat": 48.8566},
+		},
+		{
+			scenario: "Round trip scalar",
+			record:   "value",
+		},
+	}
+
+	for _, c := range codecs {
+		c := c // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		for _, tc := range testCases {
+			tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+			t.Run(c.scenario+" "+tc.scenario, func(t *testing.T) {
+				ctx := context.TODO()
+
+				raw, err := c.marshal(ctx, tc.record)
+				assert.NoError(t, err)
+
+				r, err := c.unmarshal(ctx, raw)
+				assert.NoError(t, err)
+
+				expected := tc.expected
+				if expected == nil {
+					expected = tc.record
+				}
+
+				assert.Equal(t, expected, r)
+			})
+		}
+
+		t.Run(c.scenario+" invalid", func(t *testing.T) {
+			_, err := c.unmarshal(context.TODO(), "\xc1")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMarshalBinaryIntegers(t *testing.T) {
+	ctx := context.TODO()
+
+	// numbers without fractional part are encoded as integers, in as few bytes as possible.
+	raw, err := sakio.MarshalMsgpack(ctx, map[string]interface{}{"id": float64(1), "lat": 1.5})
+	assert.NoError(t, err)
+	assert.Equal(t, "\x82\xa2id\x01\xa3lat\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00", raw)
+
+	raw, err = sakio.MarshalCBOR(ctx, map[string]interface{}{"id": float64(1), "lat": 1.5})
+	assert.NoError(t, err)
+	assert.Equal(t, "\xa2\x62id\x01\x63lat\xfb\x3f\xf8\x00\x00\x00\x00\x00\x00", raw)
+}
@@ -0,0 +1,312 @@
+package io
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CSVInput reads the input data from CSV, one record per row, the records being map[string]interface{} keyed by
+// column name. The column names are taken from the header row, unless they are set with WithColumns.
+//
+// Common initialization example:
+//
+//      input := NewCSVInput(os.Stdin).
+//			WithDelimiter('\t').
+//			WithTypeInference()
+//
+type CSVInput struct {
+	r             *csv.Reader
+	columns       []string
+	typeInference bool
+
+	raw string
+}
+
+var _ RawInput = new(CSVInput)
+
+// NewCSVInput creates an instance of CSVInput reading the rows from r.
+func NewCSVInput(r io.Reader) *CSVInput {
+	cr := csv.NewReader(r)
+	// the amount of fields is checked against the columns, so the rows not matching them are skipped.
+	cr.FieldsPerRecord = -1
+
+	return &CSVInput{
+		r: cr,
+	}
+}
+
+// Next returns the next row as a record. Starting from the first row after the header, if any, when it is call
+// the first time.
+//
+// Returns any error that occurred, including io.EOF when no more record is available and *InvalidRecordError
+// when the row is not valid CSV or its amount of fields does not match the amount of columns.
+func (i *CSVInput) Next(_ context.Context) (interface{}, error) {
+	if i.columns == nil {
+		header, err := i.r.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		i.columns = header
+	}
+
+	row, err := i.r.Read()
+	if err != nil {
+		if _, ok := err.(*csv.ParseError); ok {
+			i.raw = ""
+
+			return nil, &InvalidRecordError{Err: err}
+		}
+
+		return nil, err
+	}
+
+	i.raw = formatCSVRow(row, i.r.Comma)
+
+	if len(row) != len(i.columns) {
+		return nil, &InvalidRecordError{
+			Err: fmt.Errorf("row has %d fields, expected %d columns", len(row), len(i.columns)),
+		}
+	}
+
+	record := make(map[string]interface{}, len(row))
+
+	for n, column := range i.columns {
+		if i.typeInference {
+			record[column] = inferType(row[n])
+
+			continue
+		}
+
+		record[column] = row[n]
+	}
+
+	return record, nil
+}
+
+// Raw returns the last row returned by Next as CSV, empty when the row is not valid CSV.
+func (i *CSVInput) Raw() string {
+	return i.raw
+}
+
+// WithDelimiter set the field delimiter, i.e. '\t' for TSV, into CSVInput. Comma by default.
+func (i *CSVInput) WithDelimiter(delimiter rune) *CSVInput {
+	i.r.Comma = delimiter
+
+	return i
+}
+
+// WithColumns set the column names into CSVInput, the first row being then a record instead of the header.
+func (i *CSVInput) WithColumns(columns ...string) *CSVInput {
+	if len(columns) > 0 {
+		i.columns = columns
+	}
+
+	return i
+}
+
+// WithTypeInference set CSVInput to infer the type of the fields: numbers become float64, true and false bool,
+// and empty fields nil. Otherwise all the fields are strings.
+func (i *CSVInput) WithTypeInference() *CSVInput {
+	i.typeInference = true
+
+	return i
+}
+
+// inferType returns the field as float64, bool or nil when it looks like one, the field itself otherwise.
+func inferType(field string) interface{} {
+	switch field {
+	case "":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if n, err := strconv.ParseFloat(field, 64); err == nil {
+		return n
+	}
+
+	return field
+}
+
+// CSVOutput writes the output data as CSV, one row per record, as soon as it is appended.
+//
+// Records are map[string]interface{}, nested maps being flattened into columns named by the path of their keys,
+// i.e. driver.city, and the other nested values, like arrays, written as JSON. The columns are the ones set with
+// WithColumns, otherwise the keys of the first record sorted. Keys not in the columns are not written, and the
+// columns missing in a record are left empty.
+//
+// Common initialization example:
+//
+//      output := NewCSVOutput(os.Stdout).
+//			WithColumns("id", "driver.city").
+//			WithFlushInterval(time.Second)
+//
+type CSVOutput struct {
+	w         *WriterOutput
+	delimiter rune
+	columns   []string
+	header    bool
+
+	mu      sync.Mutex
+	started bool
+}
+
+var _ StreamOutput = new(CSVOutput)
+
+// NewCSVOutput creates an instance of CSVOutput writing the rows to w, the header first.
+func NewCSVOutput(w io.Writer) *CSVOutput {
+	return &CSVOutput{
+		w:         NewWriterOutput(w),
+		delimiter: ',',
+		header:    true,
+	}
+}
+
+// Append writes the output data as a row, preceded by the header when it is the first one.
+//
+// Errors are kept and returned by the next call to Write, Flush or Close, once an error occurred
+// the output data is discarded.
+func (o *CSVOutput) Append(ctx context.Context, output interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	record, ok := output.(map[string]interface{})
+	if !ok {
+		o.w.fail(fmt.Errorf("csv output expects map[string]interface{} records, got %T", output))
+
+		return
+	}
+
+	fields := make(map[string]string)
+	flattenCSV("", record, fields)
+
+	if !o.started {
+		o.started = true
+
+		if o.columns == nil {
+			for column := range fields {
+				o.columns = append(o.columns, column)
+			}
+
+			sort.Strings(o.columns)
+		}
+
+		if o.header {
+			o.w.Append(ctx, formatCSVRow(o.columns, o.delimiter))
+		}
+	}
+
+	row := make([]string, len(o.columns))
+	for n, column := range o.columns {
+		row[n] = fields[column]
+	}
+
+	o.w.Append(ctx, formatCSVRow(row, o.delimiter))
+}
+
+// Write flushes the buffered rows.
+//
+// Returns any error that occurred.
+func (o *CSVOutput) Write(ctx context.Context) error {
+	return o.w.Write(ctx)
+}
+
+// Flush flushes the buffered rows.
+//
+// Returns any error that occurred.
+func (o *CSVOutput) Flush(ctx context.Context) error {
+	return o.w.Flush(ctx)
+}
+
+// Close flushes the buffered rows and stops the periodic flush. The io.Writer is not closed.
+//
+// Returns any error that occurred.
+func (o *CSVOutput) Close(ctx context.Context) error {
+	return o.w.Close(ctx)
+}
+
+// WithDelimiter set the field delimiter, i.e. '\t' for TSV, into CSVOutput. Comma by default.
+func (o *CSVOutput) WithDelimiter(delimiter rune) *CSVOutput {
+	o.delimiter = delimiter
+
+	return o
+}
+
+// WithColumns set the columns, in order, into CSVOutput.
+func (o *CSVOutput) WithColumns(columns ...string) *CSVOutput {
+	if len(columns) > 0 {
+		o.columns = columns
+	}
+
+	return o
+}
+
+// WithoutHeader set CSVOutput to not write the header row.
+func (o *CSVOutput) WithoutHeader() *CSVOutput {
+	o.header = false
+
+	return o
+}
+
+// WithFlushInterval set the interval to flush the buffered rows into CSVOutput.
+// Zero means flushing after each record appended.
+func (o *CSVOutput) WithFlushInterval(flushInterval time.Duration) *CSVOutput {
+	o.w.WithFlushInterval(flushInterval)
+
+	return o
+}
+
+// flattenCSV sets into fields the values of the record, keyed by their path.
+func flattenCSV(prefix string, record map[string]interface{}, fields map[string]string) {
+	for k, v := range record {
+		key := prefix + k
+
+		switch v := v.(type) {
+		case map[string]interface{}:
+			flattenCSV(key+".", v, fields)
+		case nil:
+			fields[key] = ""
+		case string:
+			fields[key] = v
+		case float64:
+			fields[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool, int, int64, json.Number:
+			fields[key] = fmt.Sprint(v)
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				fields[key] = fmt.Sprint(v)
+
+				continue
+			}
+
+			fields[key] = string(b)
+		}
+	}
+}
+
+// formatCSVRow returns the fields as a CSV row, quoted as needed, without the trailing newline.
+func formatCSVRow(fields []string, delimiter rune) string {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	w.Comma = delimiter
+
+	// writing into a bytes.Buffer does not fail.
+	_ = w.Write(fields) // nolint:errcheck
+	w.Flush()
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,183 @@
+package io_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVInputNext(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		data     string
+		init     func(input *sakio.CSVInput)
+		records  []interface{}
+		raws     []string
+	}{
+		{
+			scenario: "Read rows using the header",
+			data:     "id,city,note\n1,paris,\"a, \"\"quoted\"\"\nnote\"\n2,lyon,\n",
+			records: []interface{}{
+				map[string]interface{}{"id": "1", "city": "paris", "note": "a, \"quoted\"\nnote"},
+				map[string]interface{}{"id": "2", "city": "lyon", "note": ""},
+			},
+			raws: []string{"1,paris,\"a, \"\"quoted\"\"\nnote\"", "2,lyon,"},
+		},
+		{
+			scenario: "Read TSV rows using the columns given and inferring types",
+			data:     "1\t48.86\ttrue\tparis\n2\t-2e3\tfalse\t\n",
+			init: func(input *sakio.CSVInput) {
+				input.WithDelimiter('\t').
+					WithColumns("id", "lat", "active", "city").
+					WithTypeInference()
+			},
+			records: []interface{}{
+				map[string]interface{}{"id": float64(1), "lat": 48.86, "active": true, "city": "paris"},
+				map[string]interface{}{"id": float64(2), "lat": float64(-2000), "active": false, "city": nil},
+			},
+			raws: []string{"1\t48.86\ttrue\tparis", "2\t-2e3\tfalse\t"},
+		},
+		{
+			scenario: "Read header only",
+			data:     "id,city\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			ctx := context.TODO()
+
+			input := sakio.NewCSVInput(strings.NewReader(tc.data))
+			if tc.init != nil {
+				tc.init(input)
+			}
+
+			var (
+				records []interface{}
+				raws    []string
+			)
+
+			for {
+				r, err := input.Next(ctx)
+				if err == io.EOF {
+					break
+				}
+
+				assert.NoError(t, err)
+
+				records = append(records, r)
+				raws = append(raws, input.Raw())
+			}
+
+			assert.Equal(t, tc.records, records)
+			assert.Equal(t, tc.raws, raws)
+		})
+	}
+}
+
+func TestCSVInputNextInvalidRecord(t *testing.T) {
+	ctx := context.TODO()
+
+	input := sakio.NewCSVInput(strings.NewReader("id,city\n1\n2,\"lyon\"x\n3,paris\n"))
+
+	_, err := input.Next(ctx)
+	assert.EqualError(t, err, "row has 1 fields, expected 2 columns")
+	assert.IsType(t, &sakio.InvalidRecordError{}, err)
+	assert.Equal(t, "1", input.Raw())
+
+	_, err = input.Next(ctx)
+	assert.IsType(t, &sakio.InvalidRecordError{}, err)
+	assert.IsType(t, &csv.ParseError{}, err.(*sakio.InvalidRecordError).Err)
+	assert.Equal(t, "", input.Raw())
+
+	// the rows following the invalid ones are still readable.
+	r, err := input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": "3", "city": "paris"}, r)
+
+	_, err = input.Next(ctx)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestCSVOutputAppend(t *testing.T) {
+	records := []interface{}{
+		map[string]interface{}{
+			"id":     float64(347),
+			"lat":    48.8566,
+			"big":    float64(1e21),
+			"active": true,
+			"note":   "a, \"quoted\"",
+			"driver": map[string]interface{}{"name": "john", "location": map[string]interface{}{"city": "paris"}},
+			"stops":  []interface{}{float64(1), "two"},
+			"none":   nil,
+		},
+		map[string]interface{}{
+			"id":    float64(482),
+			"extra": "not in the columns",
+		},
+	}
+
+	testCases := []struct {
+		scenario string
+		init     func(output *sakio.CSVOutput)
+		expected string
+	}{
+		{
+			scenario: "Write rows with the columns of the first record",
+			expected: `active,big,driver.location.city,driver.name,id,lat,none,note,stops
+true,1000000000000000000000,paris,john,347,48.8566,,"a, ""quoted""","[1,""two""]"
+,,,,482,,,,
+`,
+		},
+		{
+			scenario: "Write TSV rows with the columns given without header",
+			init: func(output *sakio.CSVOutput) {
+				output.WithDelimiter('\t').
+					WithColumns("id", "driver.name", "missing").
+					WithoutHeader()
+			},
+			expected: "347\tjohn\t\n482\t\t\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			ctx := context.TODO()
+
+			var buf bytes.Buffer
+
+			output := sakio.NewCSVOutput(&buf)
+			if tc.init != nil {
+				tc.init(output)
+			}
+
+			for _, r := range records {
+				output.Append(ctx, r)
+			}
+
+			assert.NoError(t, output.Close(ctx))
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestCSVOutputAppendError(t *testing.T) {
+	ctx := context.TODO()
+
+	var buf bytes.Buffer
+
+	output := sakio.NewCSVOutput(&buf)
+	output.Append(ctx, []interface{}{"not", "a", "map"})
+	output.Append(ctx, map[string]interface{}{"id": float64(1)})
+
+	assert.EqualError(t, output.Close(ctx), "csv output expects map[string]interface{} records, got []interface {}")
+	assert.Empty(t, buf.String())
+}
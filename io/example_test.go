@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 
 	sakio "github.com/dohernandez/swiss-army-knife/io"
@@ -66,7 +67,7 @@ func ExampleStdoutOutput_Write() {
 
 	output.Append(ctx, `{"id":4649,"lat":49.01249051526539,"lng":2.0403327446430257,"created_at":"2016-12-14 07:00:00"}`)
 
-	if err := output.Write(ctx); err == nil {
+	if err := output.Write(ctx); err != nil {
 		panic(err)
 	}
 
@@ -101,7 +102,22 @@ func ExampleStdoutOutput_WithMarshaling() {
 
 	output.Append(ctx, stdItem)
 
-	if err := output.Write(ctx); err == nil {
+	if err := output.Write(ctx); err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// {"id":4649,"lat":49.01249051526539,"lng":2.0403327446430257,"created_at":"2016-12-14 07:00:00"}
+}
+
+func ExampleWriterOutput_Append() {
+	ctx := context.TODO()
+	output := sakio.NewWriterOutput(os.Stdout)
+
+	// written as soon as it is appended.
+	output.Append(ctx, `{"id":4649,"lat":49.01249051526539,"lng":2.0403327446430257,"created_at":"2016-12-14 07:00:00"}`)
+
+	if err := output.Close(ctx); err != nil {
 		panic(err)
 	}
 
@@ -0,0 +1,419 @@
+package io
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultFollowInterval is the interval FileInput checks whether the followed file grew when none is set.
+const DefaultFollowInterval = 250 * time.Millisecond
+
+// FileOffset is the position reached in the file followed by FileInput, stored into the offset file to resume from
+// it (see WithOffsetFile).
+type FileOffset struct {
+	// Path of the file followed.
+	Path string `json:"path"`
+	// Device and Inode identify the file, so that the offset is not applied to another file after a rotation.
+	// Zero on the systems not providing them.
+	Device uint64 `json:"device"`
+	Inode  uint64 `json:"inode"`
+	// Offset is the amount of bytes of the records read.
+	Offset int64 `json:"offset"`
+	// Line is the number of the last record read.
+	Line int `json:"line"`
+}
+
+// followedFile is the file followed by FileInput.
+type followedFile struct {
+	f      *os.File
+	info   os.FileInfo
+	reader *RecordReader
+	line   int
+
+	// rotated is true once the path is another file, the remainder of this one being read before switching to it.
+	rotated bool
+}
+
+// offset returns the position reached in the file.
+func (f *followedFile) offset(path string) FileOffset {
+	device, inode, _ := fileID(f.info)
+
+	return FileOffset{Path: path, Device: device, Inode: inode, Offset: f.reader.offset, Line: f.line}
+}
+
+// followAcks tracks the records read from the followed file until they are acknowledged, to store the offset of the
+// records acknowledged in order (see FileInput.WithAcknowledgement). The receipt of each record is its number in the
+// order it was read. A nil followAcks tracks nothing.
+type followAcks struct {
+	mu   sync.Mutex
+	read int64
+	// pending are the records read not acknowledged yet, or acknowledged before a previous one.
+	pending map[int64]*pendingAck
+	// acked is the amount of records acknowledged in order, offset the offset after the last of them.
+	acked  int64
+	offset *FileOffset
+}
+
+// pendingAck is a record read from the followed file pending to be acknowledged.
+type pendingAck struct {
+	offset FileOffset
+	acked  bool
+}
+
+// track tracks the record read along with the offset after it, returning its receipt.
+func (a *followAcks) track(offset FileOffset) int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.read++
+	a.pending[a.read] = &pendingAck{offset: offset}
+
+	return a.read
+}
+
+// ack acknowledges the record of the receipt, moving the offset forward along the records acknowledged in order.
+func (a *followAcks) ack(receipt interface{}) {
+	if a == nil {
+		return
+	}
+
+	seq, ok := receipt.(int64)
+	if !ok {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if r, ok := a.pending[seq]; ok {
+		r.acked = true
+	}
+
+	for {
+		r, ok := a.pending[a.acked+1]
+		if !ok || !r.acked {
+			return
+		}
+
+		a.acked++
+		delete(a.pending, a.acked)
+
+		offset := r.offset
+		a.offset = &offset
+	}
+}
+
+// acknowledged returns the offset after the last record acknowledged in order, nil until one is.
+func (a *followAcks) acknowledged() *FileOffset {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.offset
+}
+
+// readFollow reads the next record of the followed file, waiting for it to be written.
+//
+// The file is rotated when the path is renamed or removed and a new file created in its place, the remainder of
+// the former file being read before reading the new one from the beginning. The file is truncated when its size is
+// less than the offset reached, being then read again from the beginning.
+func (i *FileInput) readFollow(ctx context.Context) error {
+	if !i.started {
+		if err := i.startFollow(); err != nil {
+			return err
+		}
+
+		i.started = true
+	}
+
+	path := i.patterns[0]
+
+	for {
+		if i.followed == nil {
+			f, err := i.openFollowed(path)
+			if err != nil {
+				return err
+			}
+
+			if f == nil {
+				// the file is not created yet, or it is being rotated.
+				if err := i.waitFollow(ctx); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			i.followed = f
+		}
+
+		raw, err := i.followed.reader.Read()
+		if _, ok := err.(*RecordTooLargeError); err == nil || ok {
+			i.followed.line++
+			i.raw = raw
+			i.source = Source{File: path, Line: i.followed.line}
+
+			if i.acks != nil {
+				i.receipt = i.acks.track(i.followed.offset(i.abs))
+			}
+
+			if ok {
+				return &InvalidRecordError{Err: err}
+			}
+
+			return nil
+		}
+
+		if err != io.EOF {
+			return &os.PathError{Op: "read", Path: path, Err: err}
+		}
+
+		if i.followed.rotated {
+			// the remainder of the rotated file is read, switching to the new one.
+			err := i.followed.f.Close()
+			i.followed = nil
+
+			if err != nil {
+				return &os.PathError{Op: "close", Path: path, Err: err}
+			}
+
+			continue
+		}
+
+		if err := i.checkFollowed(path); err != nil {
+			return err
+		}
+
+		if i.followed.rotated {
+			continue
+		}
+
+		// caught up with the writer, the offset is stored while waiting.
+		if err := i.storeOffset(); err != nil {
+			return err
+		}
+
+		if err := i.waitFollow(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// startFollow checks the follow mode settings and loads the stored offset, if any.
+func (i *FileInput) startFollow() error {
+	if len(i.patterns) != 1 {
+		return fmt.Errorf("follow mode reads a single file, got %d", len(i.patterns))
+	}
+
+	if i.jsonStream || i.framing != NoFraming || i.avro {
+		return fmt.Errorf("follow mode reads newline delimited records only")
+	}
+
+	abs, err := filepath.Abs(i.patterns[0])
+	if err != nil {
+		return err
+	}
+
+	i.abs = abs
+
+	if i.acknowledge {
+		i.acks = &followAcks{pending: make(map[int64]*pendingAck)}
+	}
+
+	if i.offsetFile == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(i.offsetFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var offset FileOffset
+	if err := json.Unmarshal(data, &offset); err != nil {
+		return &os.PathError{Op: "decode", Path: i.offsetFile, Err: err}
+	}
+
+	i.resume = &offset
+	i.stored = offset
+
+	return nil
+}
+
+// openFollowed opens the file, seeking to the offset to resume from when it is the same file. Nil when the file does
+// not exist.
+func (i *FileInput) openFollowed(path string) (*followedFile, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close() // nolint:errcheck
+
+		return nil, err
+	}
+
+	followed := &followedFile{
+		f:      f,
+		info:   info,
+		reader: NewRecordReader(f).WithMaxRecordSize(i.maxRecordSize),
+	}
+	followed.reader.follow = true
+
+	resume := i.resume
+	i.resume = nil
+
+	if resume == nil || !sameFile(*resume, path, info) || info.Size() < resume.Offset {
+		return followed, nil
+	}
+
+	if _, err := f.Seek(resume.Offset, io.SeekStart); err != nil {
+		_ = f.Close() // nolint:errcheck
+
+		return nil, err
+	}
+
+	followed.reader.r.Reset(f)
+	followed.reader.offset = resume.Offset
+	followed.line = resume.Line
+
+	return followed, nil
+}
+
+// sameFile reports whether the offset was stored for the file, by its device and inode when the system provides them.
+func sameFile(offset FileOffset, path string, info os.FileInfo) bool {
+	if abs, err := filepath.Abs(path); err != nil || abs != offset.Path {
+		return false
+	}
+
+	device, inode, ok := fileID(info)
+
+	return !ok || (device == offset.Device && inode == offset.Inode)
+}
+
+// checkFollowed checks whether the followed file was rotated or truncated.
+func (i *FileInput) checkFollowed(path string) error {
+	info, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err != nil || !os.SameFile(info, i.followed.info) {
+		i.followed.rotated = true
+		// the last record is read even without newline, nothing is written to the file anymore.
+		i.followed.reader.follow = false
+
+		return nil
+	}
+
+	if info.Size() >= i.followed.reader.offset {
+		return nil
+	}
+
+	if _, err := i.followed.f.Seek(0, io.SeekStart); err != nil {
+		return &os.PathError{Op: "seek", Path: path, Err: err}
+	}
+
+	i.followed.reader = NewRecordReader(i.followed.f).WithMaxRecordSize(i.maxRecordSize)
+	i.followed.reader.follow = true
+	i.followed.line = 0
+
+	return nil
+}
+
+// waitFollow waits for the follow interval to elapse.
+//
+// Returns the context error when the context is done meanwhile.
+func (i *FileInput) waitFollow(ctx context.Context) error {
+	interval := i.followInterval
+	if interval <= 0 {
+		interval = DefaultFollowInterval
+	}
+
+	t := time.NewTimer(interval)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// storeOffset writes the position reached in the followed file, or the one of the records acknowledged when
+// acknowledging them, into the offset file, if any and if it changed. The offset file is replaced atomically.
+func (i *FileInput) storeOffset() error {
+	if i.offsetFile == "" || i.followed == nil {
+		return nil
+	}
+
+	offset := i.followed.offset(i.abs)
+
+	if i.acks != nil {
+		acked := i.acks.acknowledged()
+		if acked == nil {
+			return nil
+		}
+
+		offset = *acked
+	}
+
+	if offset == i.stored {
+		return nil
+	}
+
+	data, err := json.Marshal(offset)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(filepath.Dir(i.offsetFile), "."+filepath.Base(i.offsetFile)+".tmp")
+
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, i.offsetFile); err != nil {
+		return err
+	}
+
+	i.stored = offset
+
+	return nil
+}
+
+// closeFollowed stores the offset and closes the followed file, Next resuming from the offset.
+func (i *FileInput) closeFollowed() error {
+	if i.followed == nil {
+		return nil
+	}
+
+	err := i.storeOffset()
+
+	if cerr := i.followed.f.Close(); err == nil {
+		err = cerr
+	}
+
+	offset := i.followed.offset(i.abs)
+	i.resume = &offset
+	i.followed = nil
+
+	return err
+}
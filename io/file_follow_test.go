@@ -0,0 +1,228 @@
+package io_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/stretchr/testify/assert"
+)
+
+// appendFile appends the data to the file, creating it if needed.
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	assert.NoError(t, err)
+
+	_, err = f.WriteString(data)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+}
+
+// nextWithin returns the next record of the input, failing when it takes longer than a second.
+func nextWithin(t *testing.T, input sakio.Input) interface{} {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	r, err := input.Next(ctx)
+	assert.NoError(t, err)
+
+	return r
+}
+
+// assertWaiting asserts that the input waits for the next record.
+func assertWaiting(t *testing.T, input sakio.Input) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := input.Next(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestFileInputFollow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	path := filepath.Join(dir, "rides.ndjson")
+
+	input := sakio.NewFileInput(path).
+		WithFollow(5 * time.Millisecond)
+
+	defer input.Close() // nolint:errcheck
+
+	// the file is waited for.
+	assertWaiting(t, input)
+
+	appendFile(t, path, "a\nb")
+	assert.Equal(t, "a", nextWithin(t, input))
+
+	// the last record is returned once its newline is written.
+	assertWaiting(t, input)
+
+	appendFile(t, path, "\nc\n")
+	assert.Equal(t, "b", nextWithin(t, input))
+	assert.Equal(t, "c", nextWithin(t, input))
+	assert.Equal(t, sakio.Source{File: path, Line: 3}, input.Source())
+
+	// truncated, the file is read from the beginning.
+	assert.NoError(t, ioutil.WriteFile(path, []byte("d\n"), 0644))
+	assert.Equal(t, "d", nextWithin(t, input))
+	assert.Equal(t, sakio.Source{File: path, Line: 1}, input.Source())
+
+	// rotated, the remainder of the former file is read before the new one.
+	appendFile(t, path, "e\nf")
+	assert.Equal(t, "e", nextWithin(t, input))
+	assert.NoError(t, os.Rename(path, path+".1"))
+	appendFile(t, path, "g\n")
+	assert.Equal(t, "f", nextWithin(t, input))
+	assert.Equal(t, "g", nextWithin(t, input))
+	assert.Equal(t, sakio.Source{File: path, Line: 1}, input.Source())
+
+	assertWaiting(t, input)
+}
+
+func TestFileInputFollowOffsetFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	path := filepath.Join(dir, "rides.ndjson")
+	offsetFile := filepath.Join(dir, "rides.offset")
+
+	appendFile(t, path, "a\nb\n")
+
+	input := sakio.NewFileInput(path).
+		WithFollow(5 * time.Millisecond).
+		WithOffsetFile(offsetFile)
+
+	assert.Equal(t, "a", nextWithin(t, input))
+	assert.NoError(t, input.Close())
+
+	var offset sakio.FileOffset
+
+	data, err := ioutil.ReadFile(offsetFile)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &offset))
+	assert.Equal(t, int64(2), offset.Offset)
+	assert.Equal(t, 1, offset.Line)
+
+	// resumed from the offset after a restart.
+	appendFile(t, path, "c\n")
+
+	input = sakio.NewFileInput(path).
+		WithFollow(5 * time.Millisecond).
+		WithOffsetFile(offsetFile)
+
+	assert.Equal(t, "b", nextWithin(t, input))
+	assert.Equal(t, "c", nextWithin(t, input))
+	assert.Equal(t, sakio.Source{File: path, Line: 3}, input.Source())
+
+	// the offset is stored while waiting.
+	assertWaiting(t, input)
+
+	data, err = ioutil.ReadFile(offsetFile)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &offset))
+	assert.Equal(t, int64(6), offset.Offset)
+
+	assert.NoError(t, input.Close())
+
+	// the offset is not applied to the file rotated in meanwhile.
+	assert.NoError(t, os.Rename(path, path+".1"))
+	appendFile(t, path, "d\n")
+
+	input = sakio.NewFileInput(path).
+		WithFollow(5 * time.Millisecond).
+		WithOffsetFile(offsetFile)
+
+	defer input.Close() // nolint:errcheck
+
+	assert.Equal(t, "d", nextWithin(t, input))
+	assert.Equal(t, sakio.Source{File: path, Line: 1}, input.Source())
+}
+
+func TestFileInputFollowInvalid(t *testing.T) {
+	_, err := sakio.NewFileInput("a.ndjson", "b.ndjson").
+		WithFollow(0).
+		Next(context.TODO())
+	assert.EqualError(t, err, "follow mode reads a single file, got 2")
+
+	_, err = sakio.NewFileInput("a.json").
+		WithJSONStream("").
+		WithFollow(0).
+		Next(context.TODO())
+	assert.EqualError(t, err, "follow mode reads newline delimited records only")
+}
+
+func TestFileInputFollowAcknowledgement(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	path := filepath.Join(dir, "rides.ndjson")
+	offsetFile := filepath.Join(dir, "rides.offset")
+
+	appendFile(t, path, "a\nb\nc\n")
+
+	input := sakio.NewFileInput(path).
+		WithFollow(5 * time.Millisecond).
+		WithOffsetFile(offsetFile).
+		WithAcknowledgement()
+
+	var receipts []interface{}
+
+	for _, expected := range []string{"a", "b", "c"} {
+		assert.Equal(t, expected, nextWithin(t, input))
+
+		receipts = append(receipts, input.Receipt())
+	}
+
+	ctx := context.TODO()
+
+	// the offset is not stored until the first record is acknowledged.
+	input.Ack(ctx, receipts[1])
+	assertWaiting(t, input)
+
+	_, err = os.Stat(offsetFile)
+	assert.True(t, os.IsNotExist(err))
+
+	// the offset is the one of the records acknowledged in order, failed ones included.
+	input.Nack(ctx, receipts[0], errors.New("operation fails"))
+	assertWaiting(t, input)
+
+	var offset sakio.FileOffset
+
+	data, err := ioutil.ReadFile(offsetFile)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &offset))
+	assert.Equal(t, int64(4), offset.Offset)
+	assert.Equal(t, 2, offset.Line)
+
+	assert.NoError(t, input.Close())
+
+	// the record not acknowledged is read again after a restart.
+	input = sakio.NewFileInput(path).
+		WithFollow(5 * time.Millisecond).
+		WithOffsetFile(offsetFile).
+		WithAcknowledgement()
+
+	defer input.Close() // nolint:errcheck
+
+	assert.Equal(t, "c", nextWithin(t, input))
+	assert.Equal(t, sakio.Source{File: path, Line: 3}, input.Source())
+}
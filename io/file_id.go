@@ -0,0 +1,18 @@
+// +build !windows,!plan9
+
+package io
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID returns the device and the inode of the file, false when they are not available.
+func fileID(info os.FileInfo) (device, inode uint64, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return uint64(st.Dev), uint64(st.Ino), true // nolint:unconvert
+}
@@ -0,0 +1,12 @@
+// +build windows plan9
+
+package io
+
+import (
+	"os"
+)
+
+// fileID returns false, the device and the inode of the files are not available on this system.
+func fileID(_ os.FileInfo) (device, inode uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,616 @@
+package io
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Source is the location a record was read from.
+type Source struct {
+	File string
+	Line int
+}
+
+// String returns the source as file:line.
+func (s Source) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// SourceInput defines a contract for input data source able to provide the location the records were read from.
+type SourceInput interface {
+	Input
+
+	// Source returns the location of the last record returned by Next.
+	Source() Source
+}
+
+// FileInput reads the input data from files, one record per line. Files are read one after another in the order
+// of the patterns, the files matching a pattern sorted by name.
+//
+// Patterns are paths, globs (see filepath.Match) or directories, read recursively. Files are decompressed when
+// their extension is .gz, .zst or .bz2 or their content starts with the gzip, zstd or bzip2 magic bytes.
+//
+// In follow mode (see WithFollow) a single file is read as it grows, like tail -F does. Otherwise the files can be
+// resumed from the position reached (see Position and Resume).
+//
+// Common initialization example:
+//
+//      input := NewFileInput("resources/dump/*.json_dump", "archive/2019/").
+//			WithUnmarshaling(unmarshal).
+//			WithSourceAnnotation("_source_file", "_source_line")
+//		// nolint:errcheck
+//		defer input.Close()
+//
+type FileInput struct {
+	patterns       []string
+	unmarshalInput UnmarshalInput
+	maxRecordSize  int
+	jsonStream     bool
+	jsonPointer    string
+	framing        Framing
+	avro           bool
+	fileKey        string
+	lineKey        string
+	follow         bool
+	followInterval time.Duration
+	offsetFile     string
+	acknowledge    bool
+
+	files   []string
+	file    *inputFile
+	started bool
+
+	followed *followedFile
+	// abs is the absolute path of the followed file.
+	abs string
+	// resume is the offset to resume from when opening the followed file, stored is the last one stored.
+	resume *FileOffset
+	stored FileOffset
+	// acks tracks the records read from the followed file until acknowledged, receipt is the one of the last record.
+	acks    *followAcks
+	receipt int64
+	// position is the position to resume reading the files from, see Resume, last the one reached in the last file
+	// closed.
+	position *Position
+	last     Position
+
+	raw    string
+	source Source
+}
+
+var (
+	_ RawInput       = new(FileInput)
+	_ SourceInput    = new(FileInput)
+	_ ResumableInput = new(FileInput)
+	_ AckableInput   = new(FileInput)
+)
+
+// NewFileInput creates an instance of FileInput reading the files matching the patterns.
+func NewFileInput(patterns ...string) *FileInput {
+	return &FileInput{
+		patterns: patterns,
+	}
+}
+
+// WithUnmarshaling set UnmarshalInput func into FileInput.
+func (i *FileInput) WithUnmarshaling(unmarshalInput UnmarshalInput) *FileInput {
+	i.unmarshalInput = unmarshalInput
+
+	return i
+}
+
+// WithMaxRecordSize set the max size in bytes of the records read into FileInput, the records exceeding it are
+// skipped. DefaultMaxRecordSize when not set.
+func (i *FileInput) WithMaxRecordSize(maxRecordSize int) *FileInput {
+	i.maxRecordSize = maxRecordSize
+
+	return i
+}
+
+// WithJSONStream set FileInput to read the files as JSON streams instead of one record per line, being the records
+// the elements of the top level arrays, the concatenated values or the elements of the array the JSON pointer points
+// to, if not empty (see JSONRecordReader). The line of the source is then the position of the record in the file.
+func (i *FileInput) WithJSONStream(pointer string) *FileInput {
+	i.jsonStream = true
+	i.jsonPointer = pointer
+
+	return i
+}
+
+// WithFraming set the Framing of the records into FileInput, Uint32Framing or VarintFraming to read the files as
+// binary streams of length prefixed records instead of one record per line (see FrameReader), the max record size
+// being the max frame size. The line of the source is then the position of the record in the file.
+func (i *FileInput) WithFraming(framing Framing) *FileInput {
+	i.framing = framing
+
+	return i
+}
+
+// WithAvro set FileInput to read the files as Avro object container files instead of one record per line, being the
+// records decoded with the schema embedded in each file as compact JSON (see AvroRecordReader), to be unmarshaled as
+// JSON. The line of the source is then the position of the record in the file.
+func (i *FileInput) WithAvro() *FileInput {
+	i.avro = true
+
+	return i
+}
+
+// WithFollow set FileInput to follow a single file as it grows instead of reading it up to its end, checking every
+// interval whether it grew, DefaultFollowInterval when zero. The file is read from the beginning, or from the
+// stored offset (see WithOffsetFile), the last record being returned once its newline is written. The file is read
+// from the beginning again when truncated, and the file created in place of the path when it is renamed or removed
+// (rotated), once the remainder of the former one is read. Next waits for the file to be created, if needed.
+//
+// Only newline delimited records are read in follow mode, the file is not decompressed.
+func (i *FileInput) WithFollow(interval time.Duration) *FileInput {
+	i.follow = true
+	i.followInterval = interval
+
+	return i
+}
+
+// WithOffsetFile set the file the offset reached in the followed file is stored into, to resume from it after a
+// restart, into FileInput. The offset is stored while waiting for the file to grow and on Close, the records read
+// after it being read again when resuming. The offset is not applied to a different file, i.e. the file was
+// rotated meanwhile.
+func (i *FileInput) WithOffsetFile(path string) *FileInput {
+	i.offsetFile = path
+
+	return i
+}
+
+// WithAcknowledgement set FileInput to store into the offset file (see WithOffsetFile) the offset of the records
+// acknowledged in order (see Ack and Nack) instead of the records read, so the records read but not processed are
+// read again after a restart, at-least-once. Only applies in follow mode.
+func (i *FileInput) WithAcknowledgement() *FileInput {
+	i.acknowledge = true
+
+	return i
+}
+
+// WithSourceAnnotation sets the keys the source file and line number are added under to the records unmarshaled
+// as map[string]interface{}. An empty key is not added.
+func (i *FileInput) WithSourceAnnotation(fileKey, lineKey string) *FileInput {
+	i.fileKey = fileKey
+	i.lineKey = lineKey
+
+	return i
+}
+
+// Next returns the next record of the files. If unmarshalInput is set, the record will be unmarshaled.
+// Starting from the first record of the first file when it is call the first time.
+//
+// Returns any error that occurred, including io.EOF when no more record is available in any of the files,
+// *os.PathError when a file can not be read and *InvalidRecordError when unmarshal the record fails or the record
+// exceeds the max record size (see RecordTooLargeError). In follow mode, io.EOF is never returned, the context
+// error is returned when the context is done while waiting for the file to grow.
+func (i *FileInput) Next(ctx context.Context) (interface{}, error) {
+	read := i.read
+	if i.follow {
+		read = func() error {
+			return i.readFollow(ctx)
+		}
+	}
+
+	if err := read(); err != nil {
+		return nil, err
+	}
+
+	if i.unmarshalInput == nil {
+		return i.raw, nil
+	}
+
+	r, err := i.unmarshalInput(ctx, i.raw)
+	if err != nil {
+		return nil, &InvalidRecordError{Err: err}
+	}
+
+	if m, ok := r.(map[string]interface{}); ok {
+		if i.fileKey != "" {
+			m[i.fileKey] = i.source.File
+		}
+
+		if i.lineKey != "" {
+			m[i.lineKey] = i.source.Line
+		}
+	}
+
+	return r, nil
+}
+
+// read reads the next record of the files, moving to the following file at the end of each one.
+func (i *FileInput) read() error {
+	if !i.started {
+		files, err := expandPatterns(i.patterns)
+		if err != nil {
+			return err
+		}
+
+		i.files = files
+		i.started = true
+
+		if err := i.skipFiles(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		if i.file == nil {
+			if len(i.files) == 0 {
+				return io.EOF
+			}
+
+			f, err := openInputFile(i.files[0], i.newRecordReader)
+			if err != nil {
+				return err
+			}
+
+			i.file = f
+			i.files = i.files[1:]
+
+			if err := i.resumeFile(); err != nil {
+				return err
+			}
+		}
+
+		raw, err := i.file.reader.Read()
+		if _, ok := err.(*RecordTooLargeError); err == nil || ok {
+			i.file.line++
+			i.raw = raw
+			i.source = Source{File: i.file.name, Line: i.file.line}
+
+			if ok {
+				return &InvalidRecordError{Err: err}
+			}
+
+			return nil
+		}
+
+		if err == io.EOF {
+			err = nil
+		}
+
+		if cerr := i.file.Close(); err == nil {
+			err = cerr
+		}
+
+		name := i.file.name
+		i.last = i.Position()
+		i.file = nil
+
+		if err != nil {
+			return &os.PathError{Op: "read", Path: name, Err: err}
+		}
+	}
+}
+
+// Raw returns the last record returned by Next as it was read from the file.
+func (i *FileInput) Raw() string {
+	return i.raw
+}
+
+// Source returns the file and line number of the last record returned by Next.
+func (i *FileInput) Source() Source {
+	return i.source
+}
+
+// Position returns the file, the byte offset and the amount of records read of the file of the last record returned
+// by Next. The offset is zero when the file is decompressed or its records are not newline delimited, being then
+// resumed by reading the records again up to the position.
+func (i *FileInput) Position() Position {
+	if i.follow {
+		if i.followed == nil {
+			return Position{}
+		}
+
+		return Position{File: i.patterns[0], Offset: i.followed.reader.offset, Record: i.followed.line}
+	}
+
+	if i.file == nil {
+		return i.last
+	}
+
+	position := Position{File: i.file.name, Record: i.file.line}
+
+	if rr, ok := i.file.reader.(*RecordReader); ok && i.file.plain {
+		position.Offset = rr.offset
+	}
+
+	return position
+}
+
+// Resume sets the position to resume reading the files from, the files before the one of the position being
+// skipped. It must be called before Next is called the first time.
+//
+// Returns error in follow mode, resumed from the offset file instead (see WithOffsetFile).
+func (i *FileInput) Resume(position Position) error {
+	if i.follow {
+		return fmt.Errorf("follow mode resumes from the offset file")
+	}
+
+	if i.started {
+		return fmt.Errorf("resume must be set before reading")
+	}
+
+	if position.File != "" {
+		i.position = &position
+	}
+
+	return nil
+}
+
+// skipFiles skips the files before the one of the position to resume from, if any.
+//
+// Returns error if the file of the position is not read by the input.
+func (i *FileInput) skipFiles() error {
+	if i.position == nil {
+		return nil
+	}
+
+	for n, name := range i.files {
+		if name == i.position.File {
+			i.files = i.files[n:]
+
+			return nil
+		}
+	}
+
+	return &os.PathError{Op: "resume", Path: i.position.File, Err: fmt.Errorf("file not matched by the patterns")}
+}
+
+// resumeFile moves the file just opened to the position to resume from, seeking to its offset when known, reading
+// the records up to it otherwise.
+func (i *FileInput) resumeFile() error {
+	position := i.position
+	if position == nil || position.File != i.file.name {
+		return nil
+	}
+
+	i.position = nil
+
+	if rr, ok := i.file.reader.(*RecordReader); ok && i.file.plain && position.Offset > 0 {
+		if _, err := i.file.f.Seek(position.Offset, io.SeekStart); err != nil {
+			return &os.PathError{Op: "seek", Path: i.file.name, Err: err}
+		}
+
+		rr.r.Reset(i.file.f)
+		rr.offset = position.Offset
+		i.file.line = position.Record
+
+		return nil
+	}
+
+	for i.file.line < position.Record {
+		_, err := i.file.reader.Read()
+		if _, ok := err.(*RecordTooLargeError); err != nil && !ok {
+			if err == io.EOF {
+				// the file is shorter than the position, there is nothing left to read.
+				return nil
+			}
+
+			return &os.PathError{Op: "read", Path: i.file.name, Err: err}
+		}
+
+		i.file.line++
+	}
+
+	return nil
+}
+
+// Receipt returns the receipt of the last record returned by Next to acknowledge it with, nil unless following the
+// file with acknowledgement (see WithAcknowledgement).
+func (i *FileInput) Receipt() interface{} {
+	if i.acks == nil {
+		return nil
+	}
+
+	return i.receipt
+}
+
+// Ack acknowledges the record of the receipt was processed, the offset stored moving past it once the previous
+// records are acknowledged as well.
+func (i *FileInput) Ack(_ context.Context, receipt interface{}) {
+	i.acks.ack(receipt)
+}
+
+// Nack acknowledges the record of the receipt failed processing. The file can not deliver the record again, the
+// offset stored moves past it like when acknowledged, the record being handled by the processor (i.e. written to a
+// dead letter output).
+func (i *FileInput) Nack(_ context.Context, receipt interface{}, _ error) {
+	i.acks.ack(receipt)
+}
+
+// Close closes the file being read, if any. Next keeps reading the following files. In follow mode, the offset
+// reached is stored, Next resuming from it.
+func (i *FileInput) Close() error {
+	if i.follow {
+		return i.closeFollowed()
+	}
+
+	if i.file == nil {
+		return nil
+	}
+
+	err := i.file.Close()
+	i.last = i.Position()
+	i.file = nil
+
+	return err
+}
+
+// newRecordReader creates the reader of the records of a file.
+func (i *FileInput) newRecordReader(r io.Reader) recordReader {
+	if i.jsonStream {
+		return NewJSONRecordReader(r).WithPointer(i.jsonPointer)
+	}
+
+	if i.avro {
+		return NewAvroRecordReader(r)
+	}
+
+	if i.framing != NoFraming {
+		return NewFrameReader(r).
+			WithFraming(i.framing).
+			WithMaxFrameSize(i.maxRecordSize)
+	}
+
+	return NewRecordReader(r).WithMaxRecordSize(i.maxRecordSize)
+}
+
+// recordReader reads the records of an input stream, see RecordReader, JSONRecordReader, FrameReader and
+// AvroRecordReader.
+type recordReader interface {
+	Read() (string, error)
+}
+
+// inputFile is a file being read, decompressed when needed.
+type inputFile struct {
+	name   string
+	reader recordReader
+	line   int
+
+	// f is the file read, plain when it is not decompressed, so its offsets are the ones of the records.
+	f     *os.File
+	plain bool
+
+	closers []io.Closer
+}
+
+// Close closes the decompressor and the file.
+func (f *inputFile) Close() error {
+	var err error
+
+	for _, c := range f.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// zstdCloser adapts zstd.Decoder, whose Close does not return an error, to io.Closer.
+type zstdCloser struct {
+	*zstd.Decoder
+}
+
+// Close releases the decoder resources.
+func (z zstdCloser) Close() error {
+	z.Decoder.Close()
+
+	return nil
+}
+
+// magic bytes of the compression formats.
+// nolint:gochecknoglobals
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	bzip2Magic = []byte("BZh")
+)
+
+// openInputFile opens the file, decompressing it by its extension or its magic bytes.
+func openInputFile(name string, newRecordReader func(r io.Reader) recordReader) (*inputFile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(f)
+
+	r, closers, err := decompress(br, strings.ToLower(filepath.Ext(name)))
+	if err != nil {
+		_ = f.Close() // nolint:errcheck
+
+		return nil, &os.PathError{Op: "decompress", Path: name, Err: err}
+	}
+
+	return &inputFile{
+		name:    name,
+		reader:  newRecordReader(r),
+		f:       f,
+		plain:   r == io.Reader(br),
+		closers: append(closers, f),
+	}, nil
+}
+
+// decompress returns the reader decompressing r when the extension or its magic bytes match a compression format,
+// r itself otherwise, along with the decompressor to close.
+func decompress(r *bufio.Reader, ext string) (io.Reader, []io.Closer, error) {
+	magic, _ := r.Peek(4) // nolint:errcheck
+
+	switch {
+	case ext == ".gz" || bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return zr, []io.Closer{zr}, nil
+	case ext == ".zst" || bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return zr, []io.Closer{zstdCloser{zr}}, nil
+	case ext == ".bz2" || bytes.HasPrefix(magic, bzip2Magic):
+		return bzip2.NewReader(r), nil, nil
+	}
+
+	return r, nil, nil
+}
+
+// expandPatterns returns the files matching the patterns, directories being walked recursively.
+//
+// *os.PathError is returned if a pattern does not match any file.
+func expandPatterns(patterns []string) ([]string, error) {
+	var files []string
+
+	seen := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, &os.PathError{Op: "glob", Path: pattern, Err: err}
+		}
+
+		if len(matches) == 0 {
+			return nil, &os.PathError{Op: "open", Path: pattern, Err: os.ErrNotExist}
+		}
+
+		sort.Strings(matches)
+
+		for _, match := range matches {
+			err := filepath.Walk(match, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+
+				if info.Mode().IsRegular() && !seen[path] {
+					seen[path] = true
+					files = append(files, path)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return files, nil
+}
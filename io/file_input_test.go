@@ -0,0 +1,368 @@
+package io_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+// bzip2Records are the records {"id":3} and {"id":4} compressed with bzip2, the standard library has no encoder.
+const bzip2Records = "QlpoOTFBWSZTWTmmYMQAAAdZgAAQEAAMEAQgAAogACEoBP1QgyYhOE8aJJwvxdyRThQkDmmYMQA="
+
+// writeFile writes the file in dir, creating the directories missing.
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, ioutil.WriteFile(path, data, 0644))
+
+	return path
+}
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+
+	var b bytes.Buffer
+
+	w := gzip.NewWriter(&b)
+	_, err := w.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	return b.Bytes()
+}
+
+func zstdCompressed(t *testing.T, data string) []byte {
+	t.Helper()
+
+	w, err := zstd.NewWriter(nil)
+	assert.NoError(t, err)
+
+	return w.EncodeAll([]byte(data), nil)
+}
+
+// readAll reads the input until io.EOF, returning the records and their source.
+func readAll(t *testing.T, input *sakio.FileInput) ([]interface{}, []string, error) {
+	t.Helper()
+
+	var (
+		records []interface{}
+		sources []string
+	)
+
+	for {
+		r, err := input.Next(context.TODO())
+		if err == io.EOF {
+			return records, sources, nil
+		}
+
+		if err != nil {
+			return records, sources, err
+		}
+
+		records = append(records, r)
+		sources = append(sources, input.Source().String())
+	}
+}
+
+func TestFileInputNext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	bz, err := base64.StdEncoding.DecodeString(bzip2Records)
+	assert.NoError(t, err)
+
+	plain := writeFile(t, dir, "a.json_dump", []byte("{\"id\":1}\n{\"id\":2}\n"))
+	bzipped := writeFile(t, dir, "b.json_dump.bz2", bz)
+	gz := writeFile(t, dir, "nested/c.json_dump.gz", gzipped(t, "{\"id\":5}\n"))
+	zst := writeFile(t, dir, "nested/deeper/d.zst", zstdCompressed(t, "{\"id\":6}\n{\"id\":7}"))
+	magic := writeFile(t, dir, "e.json_dump", gzipped(t, "{\"id\":8}\n"))
+
+	testCases := []struct {
+		scenario string
+		patterns []string
+		records  []interface{}
+		sources  []string
+	}{
+		{
+			scenario: "Read plain file",
+			patterns: []string{plain},
+			records:  []interface{}{`{"id":1}`, `{"id":2}`},
+			sources:  []string{plain + ":1", plain + ":2"},
+		},
+		{
+			scenario: "Read compressed files by extension",
+			patterns: []string{bzipped, gz, zst},
+			records:  []interface{}{`{"id":3}`, `{"id":4}`, `{"id":5}`, `{"id":6}`, `{"id":7}`},
+			sources:  []string{bzipped + ":1", bzipped + ":2", gz + ":1", zst + ":1", zst + ":2"},
+		},
+		{
+			scenario: "Read compressed file by magic bytes",
+			patterns: []string{magic},
+			records:  []interface{}{`{"id":8}`},
+			sources:  []string{magic + ":1"},
+		},
+		{
+			scenario: "Read files matching glob sorted by name",
+			patterns: []string{filepath.Join(dir, "*.json_dump*")},
+			records:  []interface{}{`{"id":1}`, `{"id":2}`, `{"id":3}`, `{"id":4}`, `{"id":8}`},
+			sources:  []string{plain + ":1", plain + ":2", bzipped + ":1", bzipped + ":2", magic + ":1"},
+		},
+		{
+			scenario: "Read directory recursively, each file once",
+			patterns: []string{filepath.Join(dir, "nested"), gz},
+			records:  []interface{}{`{"id":5}`, `{"id":6}`, `{"id":7}`},
+			sources:  []string{gz + ":1", zst + ":1", zst + ":2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			input := sakio.NewFileInput(tc.patterns...)
+
+			records, sources, err := readAll(t, input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.records, records)
+			assert.Equal(t, tc.sources, sources)
+			assert.NoError(t, input.Close())
+		})
+	}
+}
+
+func TestFileInputNextUnmarshaling(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	path := writeFile(t, dir, "a.json_dump", []byte("{\"id\":1}\nnot json\n[1]\n"))
+
+	input := sakio.NewFileInput(path).
+		WithUnmarshaling(func(_ context.Context, i string) (interface{}, error) {
+			var a interface{}
+
+			err := json.Unmarshal([]byte(i), &a)
+
+			return a, err
+		}).
+		WithSourceAnnotation("_source_file", "_source_line")
+
+	ctx := context.TODO()
+
+	r, err := input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "_source_file": path, "_source_line": 1}, r)
+
+	_, err = input.Next(ctx)
+	assert.IsType(t, &sakio.InvalidRecordError{}, err)
+	assert.Equal(t, "not json", input.Raw())
+	assert.Equal(t, sakio.Source{File: path, Line: 2}, input.Source())
+
+	// records that are not maps are not annotated.
+	r, err = input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{float64(1)}, r)
+
+	_, err = input.Next(ctx)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestFileInputNextError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	corrupted := writeFile(t, dir, "corrupted.gz", append(gzipped(t, "{\"id\":1}\n")[:15], 0, 0, 0))
+	invalid := writeFile(t, dir, "invalid.gz", []byte("not gzip"))
+
+	testCases := []struct {
+		scenario string
+		patterns []string
+		err      string
+	}{
+		{
+			scenario: "Pattern not matching any file",
+			patterns: []string{filepath.Join(dir, "missing*")},
+			err:      "open " + filepath.Join(dir, "missing*") + ": file does not exist",
+		},
+		{
+			scenario: "Invalid pattern",
+			patterns: []string{"[" + dir},
+			err:      "glob [" + dir + ": syntax error in pattern",
+		},
+		{
+			scenario: "Invalid compressed file",
+			patterns: []string{invalid},
+			err:      "decompress " + invalid + ": unexpected EOF",
+		},
+		{
+			scenario: "Corrupted compressed file",
+			patterns: []string{corrupted},
+			err:      "read " + corrupted + ": unexpected EOF",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			_, _, err := readAll(t, sakio.NewFileInput(tc.patterns...))
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestFileInputNextRecordTooLarge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	path := writeFile(t, dir, "a.json_dump", []byte("{\"id\":1}\n{\"id\":12345}\n{\"id\":3}\n"))
+
+	input := sakio.NewFileInput(path).
+		WithMaxRecordSize(8)
+
+	ctx := context.TODO()
+
+	r, err := input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1}`, r)
+
+	// the record exceeding the max record size is skipped.
+	_, err = input.Next(ctx)
+	assert.Equal(t, &sakio.InvalidRecordError{Err: &sakio.RecordTooLargeError{Size: 12, MaxSize: 8}}, err)
+	assert.Equal(t, `{"id":12`, input.Raw())
+	assert.Equal(t, sakio.Source{File: path, Line: 2}, input.Source())
+
+	r, err = input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":3}`, r)
+	assert.Equal(t, sakio.Source{File: path, Line: 3}, input.Source())
+
+	_, err = input.Next(ctx)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestFileInputResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	a := writeFile(t, dir, "a.json_dump", []byte("{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"))
+	b := writeFile(t, dir, "b.json_dump.gz", gzipped(t, "{\"id\":4}\n{\"id\":5}\n"))
+	c := writeFile(t, dir, "c.json_dump", []byte("{\"id\":6}\n"))
+
+	testCases := []struct {
+		scenario string
+		position sakio.Position
+		records  []interface{}
+		sources  []string
+	}{
+		{
+			scenario: "From the beginning",
+			records:  []interface{}{`{"id":1}`, `{"id":2}`, `{"id":3}`, `{"id":4}`, `{"id":5}`, `{"id":6}`},
+			sources:  []string{a + ":1", a + ":2", a + ":3", b + ":1", b + ":2", c + ":1"},
+		},
+		{
+			scenario: "From the offset",
+			position: sakio.Position{File: a, Offset: 9, Record: 1},
+			records:  []interface{}{`{"id":2}`, `{"id":3}`, `{"id":4}`, `{"id":5}`, `{"id":6}`},
+			sources:  []string{a + ":2", a + ":3", b + ":1", b + ":2", c + ":1"},
+		},
+		{
+			scenario: "From the record of a compressed file",
+			position: sakio.Position{File: b, Record: 1},
+			records:  []interface{}{`{"id":5}`, `{"id":6}`},
+			sources:  []string{b + ":2", c + ":1"},
+		},
+		{
+			scenario: "From the end of a file",
+			position: sakio.Position{File: b, Record: 2},
+			records:  []interface{}{`{"id":6}`},
+			sources:  []string{c + ":1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			input := sakio.NewFileInput(dir)
+			assert.NoError(t, input.Resume(tc.position))
+
+			records, sources, err := readAll(t, input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.records, records)
+			assert.Equal(t, tc.sources, sources)
+			assert.Equal(t, sakio.Position{File: c, Offset: 9, Record: 1}, input.Position())
+		})
+	}
+}
+
+func TestFileInputPosition(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	a := writeFile(t, dir, "a.json_dump", []byte("{\"id\":1}\r\n{\"id\":2}\n"))
+	b := writeFile(t, dir, "b.json_dump.gz", gzipped(t, "{\"id\":3}\n"))
+
+	input := sakio.NewFileInput(a, b)
+	assert.Equal(t, sakio.Position{}, input.Position())
+
+	ctx := context.TODO()
+
+	for _, expected := range []sakio.Position{
+		{File: a, Offset: 10, Record: 1},
+		{File: a, Offset: 19, Record: 2},
+		// the offset of a decompressed file is not known.
+		{File: b, Record: 1},
+	} {
+		_, err := input.Next(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, input.Position())
+	}
+
+	_, err = input.Next(ctx)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, sakio.Position{File: b, Record: 1}, input.Position())
+
+	// the position must be set before reading.
+	assert.Error(t, input.Resume(sakio.Position{File: a}))
+}
+
+func TestFileInputResumeError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	writeFile(t, dir, "a.json_dump", []byte("{\"id\":1}\n"))
+
+	input := sakio.NewFileInput(dir)
+	assert.NoError(t, input.Resume(sakio.Position{File: filepath.Join(dir, "other.json_dump"), Record: 1}))
+
+	_, err = input.Next(context.TODO())
+	assert.IsType(t, &os.PathError{}, err)
+
+	// follow mode resumes from the offset file.
+	assert.EqualError(t, sakio.NewFileInput(dir).WithFollow(0).Resume(sakio.Position{}), "follow mode resumes from the offset file")
+}
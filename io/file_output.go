@@ -0,0 +1,496 @@
+package io
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the format the parts written by FileOutput are compressed with.
+type Compression string
+
+const (
+	// NoCompression writes the parts uncompressed.
+	NoCompression Compression = ""
+	// GzipCompression compresses the parts with gzip, adding the .gz extension.
+	GzipCompression Compression = "gzip"
+	// ZstdCompression compresses the parts with zstd, adding the .zst extension.
+	ZstdCompression Compression = "zstd"
+)
+
+// extension returns the extension added to the parts compressed.
+func (c Compression) extension() string {
+	switch c {
+	case GzipCompression:
+		return ".gz"
+	case ZstdCompression:
+		return ".zst"
+	}
+
+	return ""
+}
+
+// ParseCompression returns the Compression by its name, none for NoCompression.
+func ParseCompression(name string) (Compression, error) {
+	switch Compression(name) {
+	case GzipCompression, ZstdCompression:
+		return Compression(name), nil
+	case NoCompression, "none":
+		return NoCompression, nil
+	}
+
+	return NoCompression, fmt.Errorf("unknown compression %q, valid are none, gzip and zstd", name)
+}
+
+// FileOutput writes the output data into files, one record per line or length prefixed when framed (see
+// WithFraming), rotating them by size, amount of records or time interval.
+//
+// The files, called parts, are named by a path template where %Y, %m, %d, %H, %M and %S are replaced by the UTC
+// time the part is opened, %% by %, and {n} by the part number, starting from 0 and skipping the parts already
+// existing. {n} is required to rotate the parts (see ValidFileRotation), without it an existing part is replaced.
+// The directories are created as needed.
+//
+// Parts are written to a hidden temporary file in the same directory, renamed once closed, so the parts are never
+// seen half-written. A part is only created when a record is appended to it.
+//
+// Common initialization example:
+//
+//      output := NewFileOutput("out/%Y-%m-%d/part-{n}.ndjson").
+//			WithMarshaling(marshal).
+//			WithMaxSize(64 << 20).
+//			WithRotationInterval(time.Hour).
+//			WithCompression(GzipCompression)
+//		// nolint:errcheck
+//		defer output.Close(ctx)
+//
+type FileOutput struct {
+	marshalOutput MarshalOutput
+
+	template         string
+	maxSize          int64
+	maxRecords       int64
+	rotationInterval time.Duration
+	compression      Compression
+	framing          Framing
+
+	mu   sync.Mutex
+	part *outputPart
+	n    int
+	err  error
+	// marshalErr is the first error marshaling the output data appended by Append.
+	marshalErr error
+}
+
+var (
+	_ CommitOutput = new(FileOutput)
+	_ RecordOutput = new(FileOutput)
+)
+
+// NewFileOutput creates an instance of FileOutput writing the parts named by the path template.
+func NewFileOutput(template string) *FileOutput {
+	return &FileOutput{
+		template: template,
+	}
+}
+
+// Append writes the output data into the current part, opening one if needed, and closes the part once it reaches
+// the max size or the max amount of records.
+//
+// Errors are kept and returned by the next call to Write, Flush, Commit or Close. Once an error writing occurred the
+// output data is discarded, while the output data failing to marshal is the only one discarded (see AppendRecord).
+func (o *FileOutput) Append(ctx context.Context, output interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.appendRecord(ctx, output); err != nil && o.marshalErr == nil {
+		o.marshalErr = err
+	}
+}
+
+// AppendRecord writes the output data into the current part, like Append does.
+//
+// Returns the error marshaling the output data, which is discarded. Errors writing are kept and returned by the
+// next call to Write, Flush, Commit or Close, once an error writing occurred the output data is discarded.
+func (o *FileOutput) AppendRecord(ctx context.Context, output interface{}) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.appendRecord(ctx, output)
+}
+
+// appendRecord writes the output data into the current part, returning the error marshaling it.
+func (o *FileOutput) appendRecord(ctx context.Context, output interface{}) error {
+	if o.err != nil {
+		return nil
+	}
+
+	if o.marshalOutput != nil {
+		r, err := o.marshalOutput(ctx, output)
+		if err != nil {
+			return err
+		}
+
+		output = r
+	}
+
+	if o.part == nil {
+		if o.err = o.open(); o.err != nil {
+			return nil
+		}
+	}
+
+	n, err := writeRecord(o.part.bw, output, o.framing)
+	if err != nil {
+		o.err = err
+
+		return nil
+	}
+
+	o.part.size += int64(n)
+	o.part.records++
+
+	if (o.maxSize > 0 && o.part.size >= o.maxSize) || (o.maxRecords > 0 && o.part.records >= o.maxRecords) {
+		o.err = o.rotate()
+	}
+
+	return nil
+}
+
+// open opens the next part.
+func (o *FileOutput) open() error {
+	if err := ValidFileRotation(o.template, o.maxSize, o.maxRecords, o.rotationInterval); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	var path string
+
+	for {
+		path = formatPath(o.template, now, o.n) + o.compression.extension()
+
+		if !strings.Contains(o.template, "{n}") {
+			break
+		}
+
+		o.n++
+
+		// any error but the part existing is reported by opening it.
+		if _, err := os.Stat(path); err != nil {
+			break
+		}
+	}
+
+	part, err := openOutputPart(path, o.compression)
+	if err != nil {
+		return err
+	}
+
+	o.part = part
+
+	if o.rotationInterval > 0 {
+		part.timer = time.AfterFunc(o.rotationInterval, func() {
+			o.mu.Lock()
+			defer o.mu.Unlock()
+
+			// the part could have been rotated already by size or amount of records.
+			if o.part == part && o.err == nil {
+				o.err = o.rotate()
+			}
+		})
+	}
+
+	return nil
+}
+
+// rotate closes the current part, the next record appended opens a new one.
+func (o *FileOutput) rotate() error {
+	if o.part == nil {
+		return nil
+	}
+
+	err := o.part.Close()
+	o.part = nil
+
+	return err
+}
+
+// Write flushes the buffered output data into the current part.
+//
+// Returns any error that occurred.
+func (o *FileOutput) Write(ctx context.Context) error {
+	return o.Flush(ctx)
+}
+
+// Flush flushes the buffered output data into the current part, which is still not visible until closed.
+//
+// Returns any error that occurred.
+func (o *FileOutput) Flush(_ context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.err == nil && o.part != nil {
+		o.err = o.part.bw.Flush()
+	}
+
+	return o.error()
+}
+
+// Commit closes the current part, making it visible, the next record appended opens a new one. Without {n} in the
+// path template the next part replaces the committed one.
+//
+// Returns any error that occurred.
+func (o *FileOutput) Commit(_ context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.err == nil {
+		o.err = o.rotate()
+	}
+
+	return o.error()
+}
+
+// Close closes the current part, making it visible. The part is discarded when an error writing occurred.
+//
+// Returns any error that occurred.
+func (o *FileOutput) Close(_ context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.err != nil {
+		if o.part != nil {
+			o.part.discard()
+			o.part = nil
+		}
+
+		return o.err
+	}
+
+	o.err = o.rotate()
+
+	return o.error()
+}
+
+// error returns the error writing, if any, otherwise the one marshaling the output data appended by Append.
+func (o *FileOutput) error() error {
+	if o.err != nil {
+		return o.err
+	}
+
+	return o.marshalErr
+}
+
+// WithMarshaling set MarshalOutput func into FileOutput.
+func (o *FileOutput) WithMarshaling(marshalOutput MarshalOutput) *FileOutput {
+	o.marshalOutput = marshalOutput
+
+	return o
+}
+
+// WithMaxSize set the size, in bytes before compression, a part is rotated at into FileOutput.
+// Zero means no limit.
+func (o *FileOutput) WithMaxSize(maxSize int64) *FileOutput {
+	o.maxSize = maxSize
+
+	return o
+}
+
+// WithMaxRecords set the amount of records a part is rotated at into FileOutput. Zero means no limit.
+func (o *FileOutput) WithMaxRecords(maxRecords int64) *FileOutput {
+	o.maxRecords = maxRecords
+
+	return o
+}
+
+// WithRotationInterval set the interval a part is rotated after being opened into FileOutput. Zero means no limit.
+func (o *FileOutput) WithRotationInterval(rotationInterval time.Duration) *FileOutput {
+	o.rotationInterval = rotationInterval
+
+	return o
+}
+
+// WithCompression set the Compression of the parts into FileOutput.
+func (o *FileOutput) WithCompression(compression Compression) *FileOutput {
+	o.compression = compression
+
+	return o
+}
+
+// WithFraming set the Framing of the records into FileOutput, Uint32Framing or VarintFraming to write each record
+// prefixed by its length instead of followed by a newline, for binary records to be read by FrameReader.
+func (o *FileOutput) WithFraming(framing Framing) *FileOutput {
+	o.framing = framing
+
+	return o
+}
+
+// outputPart is a part being written into its temporary file.
+type outputPart struct {
+	path string
+	file *os.File
+	bw   *bufio.Writer
+	// compressor is flushed and closed before the file, nil when the part is not compressed.
+	compressor io.WriteCloser
+	timer      *time.Timer
+
+	size    int64
+	records int64
+}
+
+// openOutputPart creates the temporary file of the part.
+func openOutputPart(path string, compression Compression) (*outputPart, error) {
+	dir, base := filepath.Split(path)
+
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	// the temporary name is unique, not to collide with another process or the leftover of a crashed one.
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+base+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	p := &outputPart{path: path, file: f}
+
+	// the part is readable by others once visible, as if it was created directly.
+	if err := f.Chmod(0644); err != nil {
+		p.discard()
+
+		return nil, err
+	}
+
+	var w io.Writer = f
+
+	switch compression {
+	case GzipCompression:
+		p.compressor = gzip.NewWriter(f)
+	case ZstdCompression:
+		zw, err := zstd.NewWriter(f)
+		if err != nil {
+			p.discard()
+
+			return nil, err
+		}
+
+		p.compressor = zw
+	}
+
+	if p.compressor != nil {
+		w = p.compressor
+	}
+
+	p.bw = bufio.NewWriterSize(w, defaultBufferSize)
+
+	return p, nil
+}
+
+// Close flushes the part, syncs it to disk and renames it to its path.
+func (p *outputPart) Close() error {
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+
+	err := p.bw.Flush()
+
+	if err == nil && p.compressor != nil {
+		err = p.compressor.Close()
+	}
+
+	if err == nil {
+		err = p.file.Sync()
+	}
+
+	if cerr := p.file.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		_ = os.Remove(p.file.Name()) // nolint:errcheck
+
+		return err
+	}
+
+	return os.Rename(p.file.Name(), p.path)
+}
+
+// discard closes and removes the temporary file of the part.
+func (p *outputPart) discard() {
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+
+	_ = p.file.Close()           // nolint:errcheck
+	_ = os.Remove(p.file.Name()) // nolint:errcheck
+}
+
+// ValidFileRotation returns an error when the parts are rotated by size, amount of records or time interval and the
+// path template has no {n}, each part replacing the previous one otherwise.
+func ValidFileRotation(template string, maxSize, maxRecords int64, rotationInterval time.Duration) error {
+	if strings.Contains(template, "{n}") || (maxSize <= 0 && maxRecords <= 0 && rotationInterval <= 0) {
+		return nil
+	}
+
+	return fmt.Errorf("rotating the parts requires {n} in the path template %q", template)
+}
+
+// formatPath replaces the time directives and the part number of the path template.
+func formatPath(template string, t time.Time, n int) string {
+	var b strings.Builder
+
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+
+		if c == '{' && strings.HasPrefix(template[i:], "{n}") {
+			b.WriteString(strconv.Itoa(n))
+
+			i += 2
+
+			continue
+		}
+
+		if c != '%' || i+1 == len(template) {
+			b.WriteByte(c)
+
+			continue
+		}
+
+		i++
+
+		switch template[i] {
+		case 'Y':
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case 'm':
+			fmt.Fprintf(&b, "%02d", t.Month())
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(template[i])
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,410 @@
+package io_test
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+// readParts returns the content of the files in dir by their path relative to dir, decompressing them by extension.
+func readParts(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	parts := make(map[string]string)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close() // nolint:errcheck
+
+		var data []byte
+
+		switch filepath.Ext(path) {
+		case ".gz":
+			r, err := gzip.NewReader(f)
+			if err != nil {
+				return err
+			}
+
+			data, err = ioutil.ReadAll(r)
+			if err != nil {
+				return err
+			}
+		case ".zst":
+			r, err := zstd.NewReader(f)
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+
+			data, err = ioutil.ReadAll(r)
+			if err != nil {
+				return err
+			}
+		default:
+			data, err = ioutil.ReadAll(f)
+			if err != nil {
+				return err
+			}
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		parts[rel] = string(data)
+
+		return nil
+	})
+	assert.NoError(t, err)
+
+	return parts
+}
+
+func TestFileOutputAppend(t *testing.T) {
+	year := time.Now().UTC().Format("2006")
+
+	testCases := []struct {
+		scenario string
+		template string
+		init     func(output *sakio.FileOutput)
+		existing map[string]string
+		parts    map[string]string
+	}{
+		{
+			scenario: "Write one part",
+			template: "out/part-{n}.ndjson",
+			parts: map[string]string{
+				"out/part-0.ndjson": "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n{\"id\":4}\n{\"id\":5}\n",
+			},
+		},
+		{
+			scenario: "Rotate by amount of records",
+			template: "out/part-{n}.ndjson",
+			init: func(output *sakio.FileOutput) {
+				output.WithMaxRecords(2)
+			},
+			parts: map[string]string{
+				"out/part-0.ndjson": "{\"id\":1}\n{\"id\":2}\n",
+				"out/part-1.ndjson": "{\"id\":3}\n{\"id\":4}\n",
+				"out/part-2.ndjson": "{\"id\":5}\n",
+			},
+		},
+		{
+			scenario: "Rotate by size",
+			template: "out/part-{n}.ndjson",
+			init: func(output *sakio.FileOutput) {
+				output.WithMaxSize(20)
+			},
+			parts: map[string]string{
+				"out/part-0.ndjson": "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n",
+				"out/part-1.ndjson": "{\"id\":4}\n{\"id\":5}\n",
+			},
+		},
+		{
+			scenario: "Skip existing parts",
+			template: "part-{n}.ndjson",
+			init: func(output *sakio.FileOutput) {
+				output.WithMaxRecords(3)
+			},
+			existing: map[string]string{
+				"part-0.ndjson": "old\n",
+				"part-2.ndjson": "old\n",
+			},
+			parts: map[string]string{
+				"part-0.ndjson": "old\n",
+				"part-1.ndjson": "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n",
+				"part-2.ndjson": "old\n",
+				"part-3.ndjson": "{\"id\":4}\n{\"id\":5}\n",
+			},
+		},
+		{
+			scenario: "Replace existing part without part number",
+			template: "out.ndjson",
+			existing: map[string]string{
+				"out.ndjson": "old\n",
+			},
+			parts: map[string]string{
+				"out.ndjson": "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n{\"id\":4}\n{\"id\":5}\n",
+			},
+		},
+		{
+			scenario: "Format time directives",
+			template: "%Y/100%%-%q-{n}.ndjson",
+			parts: map[string]string{
+				year + "/100%-%q-0.ndjson": "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n{\"id\":4}\n{\"id\":5}\n",
+			},
+		},
+		{
+			scenario: "Compress parts with gzip",
+			template: "part-{n}.ndjson",
+			init: func(output *sakio.FileOutput) {
+				output.WithMaxRecords(3).WithCompression(sakio.GzipCompression)
+			},
+			parts: map[string]string{
+				"part-0.ndjson.gz": "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n",
+				"part-1.ndjson.gz": "{\"id\":4}\n{\"id\":5}\n",
+			},
+		},
+		{
+			scenario: "Compress parts with zstd",
+			template: "part-{n}.ndjson",
+			init: func(output *sakio.FileOutput) {
+				output.WithCompression(sakio.ZstdCompression)
+			},
+			parts: map[string]string{
+				"part-0.ndjson.zst": "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n{\"id\":4}\n{\"id\":5}\n",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "file-output")
+			assert.NoError(t, err)
+
+			defer os.RemoveAll(dir) // nolint:errcheck
+
+			for name, data := range tc.existing {
+				writeFile(t, dir, name, []byte(data))
+			}
+
+			ctx := context.TODO()
+
+			output := sakio.NewFileOutput(filepath.Join(dir, tc.template)).
+				WithMarshaling(func(_ context.Context, i interface{}) (string, error) {
+					return "{\"id\":" + i.(string) + "}", nil
+				})
+
+			if tc.init != nil {
+				tc.init(output)
+			}
+
+			for _, id := range []string{"1", "2", "3", "4", "5"} {
+				output.Append(ctx, id)
+			}
+
+			assert.NoError(t, output.Close(ctx))
+
+			parts := make(map[string]string, len(tc.parts))
+			for name, data := range tc.parts {
+				parts[filepath.FromSlash(name)] = data
+			}
+
+			assert.Equal(t, parts, readParts(t, dir))
+		})
+	}
+}
+
+func TestFileOutputAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-output")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	ctx := context.TODO()
+
+	// the leftover of a crashed process.
+	writeFile(t, dir, ".part-0.ndjson.tmp", []byte("leftover\n"))
+
+	output := sakio.NewFileOutput(filepath.Join(dir, "part-{n}.ndjson"))
+
+	// no part is created until a record is appended.
+	assert.NoError(t, output.Flush(ctx))
+	assert.Len(t, readParts(t, dir), 1)
+
+	output.Append(ctx, `{"id":1}`)
+	assert.NoError(t, output.Flush(ctx))
+
+	// the part being written is hidden until it is closed, in a temporary file of its own.
+	parts := readParts(t, dir)
+	assert.Len(t, parts, 2)
+
+	for name, data := range parts {
+		if name != ".part-0.ndjson.tmp" {
+			assert.Regexp(t, `^\.part-0\.ndjson\.\d+\.tmp$`, name)
+			assert.Equal(t, "{\"id\":1}\n", data)
+		}
+	}
+
+	assert.NoError(t, output.Close(ctx))
+	assert.Equal(t, map[string]string{
+		".part-0.ndjson.tmp": "leftover\n",
+		"part-0.ndjson":      "{\"id\":1}\n",
+	}, readParts(t, dir))
+
+	info, err := os.Stat(filepath.Join(dir, "part-0.ndjson"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+}
+
+func TestFileOutputCommit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-output")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	ctx := context.TODO()
+
+	output := sakio.NewFileOutput(filepath.Join(dir, "part-{n}.ndjson"))
+
+	// nothing to commit.
+	assert.NoError(t, output.Commit(ctx))
+	assert.Empty(t, readParts(t, dir))
+
+	output.Append(ctx, `{"id":1}`)
+
+	// the part committed is visible, the next record opens a new one.
+	assert.NoError(t, output.Commit(ctx))
+	assert.Equal(t, map[string]string{"part-0.ndjson": "{\"id\":1}\n"}, readParts(t, dir))
+
+	output.Append(ctx, `{"id":2}`)
+	assert.NoError(t, output.Close(ctx))
+
+	assert.Equal(t, map[string]string{
+		"part-0.ndjson": "{\"id\":1}\n",
+		"part-1.ndjson": "{\"id\":2}\n",
+	}, readParts(t, dir))
+}
+
+func TestFileOutputRotationInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-output")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	ctx := context.TODO()
+
+	output := sakio.NewFileOutput(filepath.Join(dir, "part-{n}.ndjson")).
+		WithRotationInterval(20 * time.Millisecond)
+
+	output.Append(ctx, `{"id":1}`)
+
+	// the part is rotated by the interval even if no other record is appended.
+	deadline := time.Now().Add(time.Second)
+	for _, ok := readParts(t, dir)["part-0.ndjson"]; !ok && time.Now().Before(deadline); _, ok = readParts(t, dir)["part-0.ndjson"] {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	assert.Equal(t, map[string]string{"part-0.ndjson": "{\"id\":1}\n"}, readParts(t, dir))
+
+	output.Append(ctx, `{"id":2}`)
+	assert.NoError(t, output.Close(ctx))
+
+	assert.Equal(t, map[string]string{
+		"part-0.ndjson": "{\"id\":1}\n",
+		"part-1.ndjson": "{\"id\":2}\n",
+	}, readParts(t, dir))
+}
+
+func TestFileOutputError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-output")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	file := writeFile(t, dir, "file", []byte("not a directory"))
+
+	ctx := context.TODO()
+
+	output := sakio.NewFileOutput(filepath.Join(file, "part-{n}.ndjson"))
+	output.Append(ctx, `{"id":1}`)
+
+	// the error is kept until the output is closed.
+	assert.IsType(t, &os.PathError{}, output.Flush(ctx))
+	assert.IsType(t, &os.PathError{}, output.Close(ctx))
+}
+
+func TestFileOutputRotationWithoutPartNumber(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-output")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	ctx := context.TODO()
+
+	template := filepath.Join(dir, "out.ndjson")
+	expected := fmt.Sprintf("rotating the parts requires {n} in the path template %q", template)
+
+	assert.NoError(t, sakio.ValidFileRotation(template, 0, 0, 0))
+	assert.NoError(t, sakio.ValidFileRotation(filepath.Join(dir, "part-{n}.ndjson"), 0, 2, 0))
+	assert.EqualError(t, sakio.ValidFileRotation(template, 1024, 0, 0), expected)
+	assert.EqualError(t, sakio.ValidFileRotation(template, 0, 2, 0), expected)
+	assert.EqualError(t, sakio.ValidFileRotation(template, 0, 0, time.Hour), expected)
+
+	output := sakio.NewFileOutput(template).WithMaxRecords(2)
+	output.Append(ctx, `{"id":1}`)
+
+	// the part is not written, the next one would replace it.
+	assert.EqualError(t, output.Close(ctx), expected)
+	assert.Empty(t, readParts(t, dir))
+}
+
+func TestFileOutputMarshalError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-output")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	ctx := context.TODO()
+
+	output := sakio.NewFileOutput(filepath.Join(dir, "part-{n}.ndjson")).
+		WithMarshaling(func(_ context.Context, i interface{}) (string, error) {
+			if i == `{"id":2}` {
+				return "", errors.New("marshal fails")
+			}
+
+			return i.(string), nil
+		})
+
+	// only the record failing to marshal is discarded.
+	assert.NoError(t, output.AppendRecord(ctx, `{"id":1}`))
+	assert.EqualError(t, output.AppendRecord(ctx, `{"id":2}`), "marshal fails")
+	assert.NoError(t, output.AppendRecord(ctx, `{"id":3}`))
+	assert.NoError(t, output.Commit(ctx))
+
+	// appended by Append, the error is kept, the part written so far not being discarded.
+	output.Append(ctx, `{"id":4}`)
+	output.Append(ctx, `{"id":2}`)
+	output.Append(ctx, `{"id":5}`)
+	assert.EqualError(t, output.Close(ctx), "marshal fails")
+
+	assert.Equal(t, map[string]string{
+		"part-0.ndjson": "{\"id\":1}\n{\"id\":3}\n",
+		"part-1.ndjson": "{\"id\":4}\n{\"id\":5}\n",
+	}, readParts(t, dir))
+}
+
+func TestParseCompression(t *testing.T) {
+	for name, compression := range map[string]sakio.Compression{
+		"":     sakio.NoCompression,
+		"none": sakio.NoCompression,
+		"gzip": sakio.GzipCompression,
+		"zstd": sakio.ZstdCompression,
+	} {
+		c, err := sakio.ParseCompression(name)
+		assert.NoError(t, err)
+		assert.Equal(t, compression, c)
+	}
+
+	_, err := sakio.ParseCompression("bzip2")
+	assert.EqualError(t, err, `unknown compression "bzip2", valid are none, gzip and zstd`)
+}
@@ -0,0 +1,244 @@
+package io
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// Framing is the way the records of a stream are delimited.
+type Framing int
+
+const (
+	// NoFraming delimits the records by a newline, as text lines.
+	NoFraming Framing = iota
+	// Uint32Framing prefixes the records by their size in bytes as a 32 bit big endian unsigned integer, i.e. for
+	// MessagePack or CBOR values.
+	Uint32Framing
+	// VarintFraming prefixes the records by their size in bytes as a varint, as protobuf length-delimited messages are.
+	VarintFraming
+)
+
+// uint32HeaderSize is the size of the length prefix of the frames with Uint32Framing.
+const uint32HeaderSize = 4
+
+// FrameReader reads length prefixed records, called frames, of a binary stream (i.e. MessagePack, CBOR or protobuf
+// values). Each frame is the size of the record in bytes, as a 32 bit big endian unsigned integer by default or as a
+// varint (see Framing), followed by the record.
+type FrameReader struct {
+	r            *bufio.Reader
+	framing      Framing
+	maxFrameSize int
+}
+
+// NewFrameReader creates an instance of FrameReader reading the frames from r.
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{
+		r:            bufio.NewReader(r),
+		framing:      Uint32Framing,
+		maxFrameSize: DefaultMaxRecordSize,
+	}
+}
+
+// Read returns the record of the next frame.
+//
+// Returns any error that occurred, including io.EOF when no more frame is available, io.ErrUnexpectedEOF when the
+// stream ends in the middle of a frame and *RecordTooLargeError, along with the beginning of the record up to the
+// max frame size, when the record exceeds it.
+func (r *FrameReader) Read() (string, error) {
+	size, err := r.readSize()
+	if err != nil {
+		return "", err
+	}
+
+	n := size
+	if n > int64(r.maxFrameSize) {
+		n = int64(r.maxFrameSize)
+	}
+
+	record := make([]byte, n)
+	if _, err := io.ReadFull(r.r, record); err != nil {
+		return "", unexpectedEOF(err)
+	}
+
+	if size > n {
+		// the remainder of the record is discarded, the next read returns the following frame.
+		if _, err := io.CopyN(ioutil.Discard, r.r, size-n); err != nil {
+			return "", unexpectedEOF(err)
+		}
+
+		return string(record), &RecordTooLargeError{Size: size, MaxSize: r.maxFrameSize}
+	}
+
+	return string(record), nil
+}
+
+// readSize reads the length prefix of the next frame.
+func (r *FrameReader) readSize() (int64, error) {
+	if r.framing == VarintFraming {
+		size, err := binary.ReadUvarint(r.r)
+		if err != nil {
+			return 0, err
+		}
+
+		if size > math.MaxInt64 {
+			return 0, fmt.Errorf("frame size %d overflows", size)
+		}
+
+		return int64(size), nil
+	}
+
+	var header [uint32HeaderSize]byte
+
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		return 0, err
+	}
+
+	return int64(binary.BigEndian.Uint32(header[:])), nil
+}
+
+// WithFraming set the Framing of the frames into FrameReader, VarintFraming or Uint32Framing, the default.
+func (r *FrameReader) WithFraming(framing Framing) *FrameReader {
+	r.framing = framing
+
+	return r
+}
+
+// WithMaxFrameSize set the max size in bytes of the records into FrameReader.
+func (r *FrameReader) WithMaxFrameSize(maxFrameSize int) *FrameReader {
+	if maxFrameSize > 0 {
+		r.maxFrameSize = maxFrameSize
+	}
+
+	return r
+}
+
+// unexpectedEOF returns io.ErrUnexpectedEOF when the error is io.EOF, the stream ending in the middle of a frame.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// writeRecord writes the record delimited by the framing, followed by a newline with NoFraming or prefixed by its
+// length otherwise (see FrameReader). Returns the amount of bytes written.
+func writeRecord(w io.Writer, record interface{}, framing Framing) (int, error) {
+	if framing == NoFraming {
+		return fmt.Fprintf(w, "%v\n", record)
+	}
+
+	payload := fmt.Sprint(record)
+
+	var header []byte
+
+	if framing == VarintFraming {
+		header = make([]byte, binary.MaxVarintLen64)
+		header = header[:binary.PutUvarint(header, uint64(len(payload)))]
+	} else {
+		if int64(len(payload)) > math.MaxUint32 {
+			return 0, fmt.Errorf("record of %d bytes exceeds the max frame size of %d bytes", len(payload), uint32(math.MaxUint32))
+		}
+
+		header = make([]byte, uint32HeaderSize)
+		binary.BigEndian.PutUint32(header, uint32(len(payload)))
+	}
+
+	n, err := w.Write(header)
+	if err != nil {
+		return n, err
+	}
+
+	m, err := io.WriteString(w, payload)
+
+	return n + m, err
+}
+
+// FrameInput reads the input data from a binary stream of length prefixed records (see FrameReader), i.e.
+// MessagePack or CBOR values to be unmarshaled with UnmarshalMsgpack or UnmarshalCBOR, or protobuf messages with
+// VarintFraming to be unmarshaled with ProtoMessage.Unmarshal.
+//
+// Common initialization example:
+//
+//      input := NewFrameInput(os.Stdin).
+//			WithMaxFrameSize(1 << 20).
+//			WithUnmarshaling(UnmarshalMsgpack)
+//
+type FrameInput struct {
+	reader         *FrameReader
+	unmarshalInput UnmarshalInput
+
+	raw string
+}
+
+var _ RawInput = new(FrameInput)
+
+// NewFrameInput creates an instance of FrameInput reading the frames from r.
+func NewFrameInput(r io.Reader) *FrameInput {
+	return &FrameInput{
+		reader: NewFrameReader(r),
+	}
+}
+
+// Next returns the record of the next frame. If unmarshalInput is set, the record will be unmarshaled.
+// Starting from the first frame when it is call the first time.
+//
+// Returns any error that occurred, including io.EOF when no more frame is available and *InvalidRecordError when
+// unmarshal the record fails or the record exceeds the max frame size (see RecordTooLargeError).
+func (i *FrameInput) Next(ctx context.Context) (interface{}, error) {
+	raw, err := i.reader.Read()
+	if err != nil {
+		if _, ok := err.(*RecordTooLargeError); ok {
+			i.raw = raw
+
+			return nil, &InvalidRecordError{Err: err}
+		}
+
+		return nil, err
+	}
+
+	i.raw = raw
+
+	if i.unmarshalInput == nil {
+		return i.raw, nil
+	}
+
+	r, err := i.unmarshalInput(ctx, i.raw)
+	if err != nil {
+		return nil, &InvalidRecordError{Err: err}
+	}
+
+	return r, nil
+}
+
+// Raw returns the record of the last frame returned by Next, without the length prefix.
+func (i *FrameInput) Raw() string {
+	return i.raw
+}
+
+// WithFraming set the Framing of the frames into FrameInput, VarintFraming or Uint32Framing, the default.
+func (i *FrameInput) WithFraming(framing Framing) *FrameInput {
+	i.reader.WithFraming(framing)
+
+	return i
+}
+
+// WithMaxFrameSize set the max size in bytes of the records read into FrameInput, the records exceeding it are
+// skipped. DefaultMaxRecordSize when not set.
+func (i *FrameInput) WithMaxFrameSize(maxFrameSize int) *FrameInput {
+	i.reader.WithMaxFrameSize(maxFrameSize)
+
+	return i
+}
+
+// WithUnmarshaling set UnmarshalInput func into FrameInput.
+func (i *FrameInput) WithUnmarshaling(unmarshalInput UnmarshalInput) *FrameInput {
+	i.unmarshalInput = unmarshalInput
+
+	return i
+}
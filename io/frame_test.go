@@ -0,0 +1,244 @@
+package io_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/stretchr/testify/assert"
+)
+
+// frames returns the records length prefixed.
+func frames(records ...string) string {
+	var buf bytes.Buffer
+
+	for _, r := range records {
+		var header [4]byte
+
+		binary.BigEndian.PutUint32(header[:], uint32(len(r)))
+
+		buf.Write(header[:])
+		buf.WriteString(r)
+	}
+
+	return buf.String()
+}
+
+// varintFrames returns the records prefixed by their length as a varint.
+func varintFrames(records ...string) string {
+	var buf bytes.Buffer
+
+	for _, r := range records {
+		header := make([]byte, binary.MaxVarintLen64)
+
+		buf.Write(header[:binary.PutUvarint(header, uint64(len(r)))])
+		buf.WriteString(r)
+	}
+
+	return buf.String()
+}
+
+func TestFrameReaderRead(t *testing.T) {
+	long := strings.Repeat("a", 10000)
+
+	testCases := []struct {
+		scenario     string
+		data         string
+		framing      sakio.Framing
+		maxFrameSize int
+		records      []string
+		errs         []error
+	}{
+		{
+			scenario: "Read frames",
+			data:     frames("a", "", "b\nc", long),
+			records:  []string{"a", "", "b\nc", long},
+			errs:     []error{nil, nil, nil, nil},
+		},
+		{
+			scenario:     "Skip frames exceeding the max frame size",
+			data:         frames("aaaa", "bbbbb", long, "c"),
+			maxFrameSize: 4,
+			records:      []string{"aaaa", "bbbb", "aaaa", "c"},
+			errs: []error{
+				nil,
+				&sakio.RecordTooLargeError{Size: 5, MaxSize: 4},
+				&sakio.RecordTooLargeError{Size: 10000, MaxSize: 4},
+				nil,
+			},
+		},
+		{
+			scenario: "Read varint frames",
+			data:     varintFrames("a", "", long, "b"),
+			framing:  sakio.VarintFraming,
+			records:  []string{"a", "", long, "b"},
+			errs:     []error{nil, nil, nil, nil},
+		},
+		{
+			scenario:     "Skip varint frames exceeding the max frame size",
+			data:         varintFrames(long, "c"),
+			framing:      sakio.VarintFraming,
+			maxFrameSize: 4,
+			records:      []string{"aaaa", "c"},
+			errs:         []error{&sakio.RecordTooLargeError{Size: 10000, MaxSize: 4}, nil},
+		},
+		{
+			scenario: "Read truncated varint header",
+			data:     varintFrames("a") + "\x90",
+			framing:  sakio.VarintFraming,
+			records:  []string{"a", ""},
+			errs:     []error{nil, io.ErrUnexpectedEOF},
+		},
+		{
+			scenario: "Read truncated header",
+			data:     frames("a") + "\x00\x00",
+			records:  []string{"a", ""},
+			errs:     []error{nil, io.ErrUnexpectedEOF},
+		},
+		{
+			scenario: "Read truncated frame",
+			data:     frames("a") + frames("bbbb")[:6],
+			records:  []string{"a", ""},
+			errs:     []error{nil, io.ErrUnexpectedEOF},
+		},
+		{
+			scenario:     "Read truncated frame exceeding the max frame size",
+			data:         frames("bbbbbb")[:8],
+			maxFrameSize: 2,
+			records:      []string{""},
+			errs:         []error{io.ErrUnexpectedEOF},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			// reading one byte at a time, the frames are split in as many chunks as possible.
+			r := sakio.NewFrameReader(iotest.OneByteReader(strings.NewReader(tc.data))).
+				WithMaxFrameSize(tc.maxFrameSize)
+
+			if tc.framing != sakio.NoFraming {
+				r.WithFraming(tc.framing)
+			}
+
+			var (
+				records []string
+				errs    []error
+			)
+
+			for {
+				record, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+
+				records = append(records, record)
+				errs = append(errs, err)
+
+				if err == io.ErrUnexpectedEOF {
+					break
+				}
+			}
+
+			assert.Equal(t, tc.records, records)
+			assert.Equal(t, tc.errs, errs)
+		})
+	}
+}
+
+func TestFrameInputNext(t *testing.T) {
+	ctx := context.TODO()
+
+	input := sakio.NewFrameInput(strings.NewReader(frames("\x81\xa2id\x01", "\xc1", "\x81\xa2id\xcd\x01\x00", "\x81\xa2id\x03"))).
+		WithMaxFrameSize(6).
+		WithUnmarshaling(sakio.UnmarshalMsgpack)
+
+	r, err := input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": float64(1)}, r)
+	assert.Equal(t, "\x81\xa2id\x01", input.Raw())
+
+	// the record not unmarshaled is still available.
+	_, err = input.Next(ctx)
+	assert.IsType(t, &sakio.InvalidRecordError{}, err)
+	assert.Equal(t, "\xc1", input.Raw())
+
+	// the record exceeding the max frame size is skipped.
+	_, err = input.Next(ctx)
+	assert.Equal(t, &sakio.InvalidRecordError{Err: &sakio.RecordTooLargeError{Size: 7, MaxSize: 6}}, err)
+	assert.Equal(t, "\x81\xa2id\xcd\x01", input.Raw())
+
+	r, err = input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": float64(3)}, r)
+
+	_, err = input.Next(ctx)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestFileInputNextFraming(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	a := writeFile(t, dir, "a.cbor", []byte(frames("\xa1\x62id\x01", "\xa1\x62id\x02")))
+	b := writeFile(t, dir, "b.cbor.gz", gzipped(t, frames("\xa1\x62id\x03")))
+
+	input := sakio.NewFileInput(filepath.Join(dir, "*")).
+		WithFraming(sakio.Uint32Framing).
+		WithUnmarshaling(sakio.UnmarshalCBOR).
+		WithSourceAnnotation("_source_file", "_source_line")
+
+	records, _, err := readAll(t, input)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": float64(1), "_source_file": a, "_source_line": 1},
+		map[string]interface{}{"id": float64(2), "_source_file": a, "_source_line": 2},
+		map[string]interface{}{"id": float64(3), "_source_file": b, "_source_line": 1},
+	}, records)
+}
+
+func TestOutputFraming(t *testing.T) {
+	ctx := context.TODO()
+
+	var buf bytes.Buffer
+
+	output := sakio.NewWriterOutput(&buf).
+		WithFraming(sakio.Uint32Framing)
+
+	output.Append(ctx, "a")
+	output.Append(ctx, "")
+	output.Append(ctx, "b\nc")
+
+	assert.NoError(t, output.Close(ctx))
+	assert.Equal(t, frames("a", "", "b\nc"), buf.String())
+
+	dir, err := ioutil.TempDir("", "file-output")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	fileOutput := sakio.NewFileOutput(filepath.Join(dir, "part-{n}.msgpack")).
+		WithFraming(sakio.Uint32Framing).
+		WithMarshaling(sakio.MarshalMsgpack).
+		WithMaxRecords(2)
+
+	for _, id := range []float64{1, 2, 3} {
+		fileOutput.Append(ctx, map[string]interface{}{"id": id})
+	}
+
+	assert.NoError(t, fileOutput.Close(ctx))
+	assert.Equal(t, map[string]string{
+		"part-0.msgpack": frames("\x81\xa2id\x01", "\x81\xa2id\x02"),
+		"part-1.msgpack": frames("\x81\xa2id\x03"),
+	}, readParts(t, dir))
+}
@@ -0,0 +1,339 @@
+package io
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// errHTTPInputClosed is returned by Listen when the input was closed.
+var errHTTPInputClosed = errors.New("http input closed")
+
+// httpShutdownTimeout is the time given to the requests being served to be answered when HTTPInput is closed.
+const httpShutdownTimeout = 5 * time.Second
+
+// HTTPInput reads the input data posted to an HTTP endpoint, the body of each request being either newline
+// delimited JSON records or a JSON array of records. Requests are served concurrently, their records merged in the
+// order they are read.
+//
+// A request is answered once all its records are returned by Next, so when the records are not taken (i.e. the first
+// ChannelConveyor is full) the clients wait instead of the records being held in memory. The response is
+// 202 Accepted along with the amount of records taken, 400 Bad Request when the body is not valid JSON, the records
+// read before the error being still taken, or 503 Service Unavailable when the input is closed meanwhile.
+//
+// HTTPInput is an http.Handler, to be served by Listen or mounted into another server.
+//
+// Common initialization example:
+//
+//      input := NewHTTPInput(":8080").
+//			WithPath("/ingest").
+//			WithUnmarshaling(unmarshal)
+//		// nolint:errcheck
+//		defer input.Close()
+//
+type HTTPInput struct {
+	address        string
+	path           string
+	unmarshalInput UnmarshalInput
+	maxRecordSize  int
+
+	mu       sync.Mutex
+	listener net.Listener
+	server   *http.Server
+	records  chan socketRecord
+	done     chan struct{}
+	wg       sync.WaitGroup
+	closed   bool
+
+	raw string
+}
+
+var _ RawInput = new(HTTPInput)
+var _ http.Handler = new(HTTPInput)
+
+// httpResponse is the body of the responses of HTTPInput.
+type httpResponse struct {
+	Records int    `json:"records"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NewHTTPInput creates an instance of HTTPInput listening on the TCP address, accepting the records posted to /.
+// The address can be empty when the input is only used as an http.Handler.
+func NewHTTPInput(address string) *HTTPInput {
+	return &HTTPInput{
+		address: address,
+		path:    "/",
+		records: make(chan socketRecord),
+		done:    make(chan struct{}),
+	}
+}
+
+// ParseHTTPAddress parses the endpoint an HTTPInput listens on, http://host:port/path, returning the address to pass
+// to NewHTTPInput and the path to pass to WithPath, / when missing.
+func ParseHTTPAddress(s string) (address, path string, err error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "http" || u.Port() == "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", "", fmt.Errorf("invalid listen address %q, must be http://host:port/path", s)
+	}
+
+	path = u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return u.Host, path, nil
+}
+
+// Listen starts serving the address, Next calls it when it is not called before. Nothing is listened when the
+// address is empty.
+//
+// Returns any error that occurred.
+func (i *HTTPInput) Listen() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.closed {
+		return errHTTPInputClosed
+	}
+
+	if i.listener != nil || i.address == "" {
+		return nil
+	}
+
+	l, err := net.Listen("tcp", i.address)
+	if err != nil {
+		return err
+	}
+
+	i.listener = l
+	i.server = &http.Server{Handler: i}
+
+	i.wg.Add(1)
+
+	go func() {
+		defer i.wg.Done()
+
+		if err := i.server.Serve(l); err != http.ErrServerClosed {
+			select {
+			case <-i.done:
+			case i.records <- socketRecord{err: err}:
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Addr returns the address listened on, i.e. the port chosen when listening on port 0, nil when not listening.
+func (i *HTTPInput) Addr() net.Addr {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.listener == nil {
+		return nil
+	}
+
+	return i.listener.Addr()
+}
+
+// ServeHTTP reads the records of the body of a POST request to the path, answering once they are all taken by Next.
+func (i *HTTPInput) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != i.path {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	body := bufio.NewReader(r.Body)
+
+	var reader recordReader = NewRecordReader(body).WithMaxRecordSize(i.maxRecordSize)
+	if isJSONArray(body) {
+		reader = NewJSONRecordReader(body)
+	}
+
+	var res httpResponse
+
+	for {
+		raw, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if _, ok := err.(*RecordTooLargeError); err != nil && !ok {
+			// the body is not valid JSON, or the client went away.
+			res.Error = err.Error()
+			writeHTTPResponse(w, http.StatusBadRequest, res)
+
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-i.done:
+			res.Error = errHTTPInputClosed.Error()
+			writeHTTPResponse(w, http.StatusServiceUnavailable, res)
+
+			return
+		case i.records <- socketRecord{raw: raw, err: err}:
+			res.Records++
+		}
+	}
+
+	writeHTTPResponse(w, http.StatusAccepted, res)
+}
+
+// isJSONArray reports whether the first character of the body, other than white spaces, opens a JSON array. The
+// white spaces are consumed.
+func isJSONArray(body *bufio.Reader) bool {
+	for {
+		c, err := body.ReadByte()
+		if err != nil {
+			return false
+		}
+
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+
+		_ = body.UnreadByte() // nolint:errcheck
+
+		return c == '['
+	}
+}
+
+// writeHTTPResponse writes the response as JSON with the status code.
+func writeHTTPResponse(w http.ResponseWriter, code int, res httpResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(res) // nolint:errcheck
+}
+
+// Next returns the next record posted to the endpoint. If unmarshalInput is set, the record will be unmarshaled.
+// Listening starts when it is called the first time, unless Listen was called before.
+//
+// Returns any error that occurred, including io.EOF once the input is closed, the context error when the context is
+// done, the listen or serve error and *InvalidRecordError when unmarshal the record fails or the record exceeds the
+// max record size (see RecordTooLargeError).
+func (i *HTTPInput) Next(ctx context.Context) (interface{}, error) {
+	if err := i.Listen(); err != nil {
+		if err == errHTTPInputClosed {
+			return nil, io.EOF
+		}
+
+		return nil, err
+	}
+
+	var rec socketRecord
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-i.done:
+		return nil, io.EOF
+	case rec = <-i.records:
+	}
+
+	if _, ok := rec.err.(*RecordTooLargeError); rec.err != nil && !ok {
+		return nil, rec.err
+	}
+
+	i.raw = rec.raw
+
+	if rec.err != nil {
+		return nil, &InvalidRecordError{Err: rec.err}
+	}
+
+	if i.unmarshalInput == nil {
+		return i.raw, nil
+	}
+
+	r, err := i.unmarshalInput(ctx, i.raw)
+	if err != nil {
+		return nil, &InvalidRecordError{Err: err}
+	}
+
+	return r, nil
+}
+
+// Raw returns the last record returned by Next as it was posted, as compact JSON for the elements of an array.
+func (i *HTTPInput) Raw() string {
+	return i.raw
+}
+
+// Close stops serving, the requests being served are answered 503 Service Unavailable, the ones still being sent
+// after a while are cut off.
+//
+// Returns any error that occurred closing the listener.
+func (i *HTTPInput) Close() error {
+	i.mu.Lock()
+
+	if i.closed {
+		i.mu.Unlock()
+
+		return nil
+	}
+
+	i.closed = true
+
+	close(i.done)
+
+	server := i.server
+
+	i.mu.Unlock()
+
+	var err error
+
+	if server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		defer cancel()
+
+		if err = server.Shutdown(ctx); err == context.DeadlineExceeded {
+			// the clients still sending their requests are cut off.
+			err = server.Close()
+		}
+	}
+
+	i.wg.Wait()
+
+	return err
+}
+
+// WithPath set the path of the endpoint the records are posted to into HTTPInput, / by default.
+func (i *HTTPInput) WithPath(path string) *HTTPInput {
+	i.path = path
+
+	return i
+}
+
+// WithUnmarshaling set UnmarshalInput func into HTTPInput.
+func (i *HTTPInput) WithUnmarshaling(unmarshalInput UnmarshalInput) *HTTPInput {
+	i.unmarshalInput = unmarshalInput
+
+	return i
+}
+
+// WithMaxRecordSize set the max size in bytes of the newline delimited records read into HTTPInput, the records
+// exceeding it are skipped. DefaultMaxRecordSize when not set.
+func (i *HTTPInput) WithMaxRecordSize(maxRecordSize int) *HTTPInput {
+	i.maxRecordSize = maxRecordSize
+
+	return i
+}
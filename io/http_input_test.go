@@ -0,0 +1,228 @@
+package io_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/stretchr/testify/assert"
+)
+
+// post posts the body to the url, returning the status code and the body of the response.
+func post(t *testing.T, url, body string) (int, string) {
+	t.Helper()
+
+	res, err := http.Post(url, "application/x-ndjson", strings.NewReader(body))
+	if !assert.NoError(t, err) {
+		return 0, ""
+	}
+
+	defer res.Body.Close() // nolint:errcheck
+
+	data, err := ioutil.ReadAll(res.Body)
+	assert.NoError(t, err)
+
+	return res.StatusCode, string(data)
+}
+
+func TestHTTPInputNext(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		body     string
+		code     int
+		res      string
+		records  []interface{}
+		raws     []string
+	}{
+		{
+			scenario: "NDJSON",
+			body:     "{\"id\":1}\n{\"id\":2}\n",
+			code:     http.StatusAccepted,
+			res:      "{\"records\":2}\n",
+			records:  []interface{}{map[string]interface{}{"id": float64(1)}, map[string]interface{}{"id": float64(2)}},
+			raws:     []string{`{"id":1}`, `{"id":2}`},
+		},
+		{
+			scenario: "JSON array",
+			body:     " [\n  {\"id\": 1},\n  {\"id\": 2}\n]\n",
+			code:     http.StatusAccepted,
+			res:      "{\"records\":2}\n",
+			records:  []interface{}{map[string]interface{}{"id": float64(1)}, map[string]interface{}{"id": float64(2)}},
+			raws:     []string{`{"id":1}`, `{"id":2}`},
+		},
+		{
+			scenario: "Invalid NDJSON record",
+			body:     "{\"id\":1}\nnot json\n",
+			code:     http.StatusAccepted,
+			res:      "{\"records\":2}\n",
+			records:  []interface{}{map[string]interface{}{"id": float64(1)}, nil},
+			raws:     []string{`{"id":1}`, "not json"},
+		},
+		{
+			scenario: "Invalid JSON array",
+			body:     "[{\"id\":1},x]",
+			code:     http.StatusBadRequest,
+			res:      "{\"records\":1,\"error\":\"invalid character 'x' looking for beginning of value\"}\n",
+			records:  []interface{}{map[string]interface{}{"id": float64(1)}},
+			raws:     []string{`{"id":1}`},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			ctx := context.TODO()
+
+			input := sakio.NewHTTPInput("").
+				WithPath("/ingest").
+				WithUnmarshaling(func(_ context.Context, i string) (interface{}, error) {
+					var v interface{}
+					err := json.Unmarshal([]byte(i), &v)
+
+					return v, err
+				})
+
+			srv := httptest.NewServer(input)
+			defer srv.Close()
+
+			type response struct {
+				code int
+				body string
+			}
+
+			done := make(chan response)
+
+			go func() {
+				code, body := post(t, srv.URL+"/ingest", tc.body)
+				done <- response{code: code, body: body}
+			}()
+
+			for n, expected := range tc.records {
+				r, err := input.Next(ctx)
+				if expected == nil {
+					assert.IsType(t, &sakio.InvalidRecordError{}, err)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, expected, r)
+				}
+
+				assert.Equal(t, tc.raws[n], input.Raw())
+			}
+
+			res := <-done
+			assert.Equal(t, tc.code, res.code)
+			assert.Equal(t, tc.res, res.body)
+		})
+	}
+}
+
+func TestHTTPInputServeHTTP(t *testing.T) {
+	srv := httptest.NewServer(sakio.NewHTTPInput("").WithPath("/ingest"))
+	defer srv.Close()
+
+	code, _ := post(t, srv.URL+"/other", "{}")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	res, err := http.Get(srv.URL + "/ingest")
+	assert.NoError(t, err)
+	assert.NoError(t, res.Body.Close())
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	assert.Equal(t, http.MethodPost, res.Header.Get("Allow"))
+}
+
+func TestHTTPInputClose(t *testing.T) {
+	input := sakio.NewHTTPInput("127.0.0.1:0")
+	assert.NoError(t, input.Listen())
+
+	url := "http://" + input.Addr().String() + "/"
+
+	done := make(chan int)
+
+	go func() {
+		code, _ := post(t, url, "a\nb\n")
+		done <- code
+	}()
+
+	r, err := input.Next(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "a", r)
+
+	// the request waits for the second record to be taken.
+	select {
+	case <-done:
+		t.Fatal("request answered before its records are taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.NoError(t, input.Close())
+	assert.NoError(t, input.Close())
+	assert.Equal(t, http.StatusServiceUnavailable, <-done)
+
+	_, err = input.Next(context.TODO())
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestHTTPInputListenError(t *testing.T) {
+	input := sakio.NewHTTPInput("127.0.0.1:-1")
+
+	_, err := input.Next(context.TODO())
+	assert.Error(t, err)
+	assert.Nil(t, input.Addr())
+	assert.NoError(t, input.Close())
+}
+
+func TestParseHTTPAddress(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		s        string
+		address  string
+		path     string
+		err      string
+	}{
+		{
+			scenario: "Endpoint",
+			s:        "http://127.0.0.1:8080/ingest",
+			address:  "127.0.0.1:8080",
+			path:     "/ingest",
+		},
+		{
+			scenario: "Without path",
+			s:        "http://:8080",
+			address:  ":8080",
+			path:     "/",
+		},
+		{
+			scenario: "Without port",
+			s:        "http://localhost/ingest",
+			err:      `invalid listen address "http://localhost/ingest", must be http://host:port/path`,
+		},
+		{
+			scenario: "TLS",
+			s:        "https://:8443/ingest",
+			err:      `invalid listen address "https://:8443/ingest", must be http://host:port/path`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			address, path, err := sakio.ParseHTTPAddress(tc.s)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.address, address)
+			assert.Equal(t, tc.path, path)
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package io
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHTTPBatchSize is the amount of records posted at once by HTTPOutput when none is set.
+	DefaultHTTPBatchSize = 100
+	// DefaultHTTPTimeout is the timeout of each request of HTTPOutput when none is set.
+	DefaultHTTPTimeout = 10 * time.Second
+	// DefaultHTTPRetries is the amount of times HTTPOutput retries a failed request when none is set.
+	DefaultHTTPRetries = 3
+)
+
+// HTTPStatusError is the error of a request of HTTPOutput answered with a status code other than 2xx.
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+// Error returns the error message.
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("POST %s: %s", e.URL, e.Status)
+}
+
+// temporary reports whether the request may succeed when retried, on server errors and rate limiting.
+func (e *HTTPStatusError) temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// HTTPOutput posts the output data to an HTTP endpoint in batches, the body of each request being the records of
+// the batch, one per line (NDJSON).
+//
+// A batch is posted once it holds the batch size records, when the flush interval is elapsed and when Write, Flush or
+// Close are called. Appending waits for the batch to be posted, slowing down the processor instead of holding the
+// records in memory.
+//
+// The requests failing with a network error, a timeout, a 5xx or a 429 Too Many Requests status code are retried,
+// waiting between the attempts a backoff doubled each time up to the max backoff. Once a batch could not be posted,
+// the error is kept and the following output data is discarded, while the output data failing to marshal is the only
+// one discarded (see AppendRecord). The requests are not cancelled with the context
+// passed, so the records appended while the processor drains are posted too.
+type HTTPOutput struct {
+	marshalOutput MarshalOutput
+
+	url           string
+	client        *http.Client
+	contentType   string
+	batchSize     int
+	flushInterval time.Duration
+	timeout       time.Duration
+	retries       int
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+
+	mu      sync.Mutex
+	batch   bytes.Buffer
+	records int
+	err     error
+	// marshalErr is the first error marshaling the output data appended by Append.
+	marshalErr error
+	ticker     *time.Ticker
+	done       chan struct{}
+}
+
+var (
+	_ StreamOutput = new(HTTPOutput)
+	_ RecordOutput = new(HTTPOutput)
+)
+
+// NewHTTPOutput create an instance of HTTPOutput posting the records to the url.
+//
+// Common initialization example:
+//
+//      output := NewHTTPOutput("https://example.com/webhook").
+//			WithMarshaling(marshal).
+//			WithBatchSize(500).
+//			WithFlushInterval(time.Second)
+//		// nolint:errcheck
+//		defer output.Close(ctx)
+//
+func NewHTTPOutput(url string) *HTTPOutput {
+	return &HTTPOutput{
+		url:         url,
+		client:      http.DefaultClient,
+		contentType: "application/x-ndjson",
+		batchSize:   DefaultHTTPBatchSize,
+		timeout:     DefaultHTTPTimeout,
+		retries:     DefaultHTTPRetries,
+		minBackoff:  100 * time.Millisecond,
+		maxBackoff:  10 * time.Second,
+	}
+}
+
+// Append adds the output data to the batch, posting it once full.
+//
+// Errors are kept and returned by the next call to Write, Flush or Close. Once a batch could not be posted the output
+// data is discarded, while the output data failing to marshal is the only one discarded (see AppendRecord).
+func (o *HTTPOutput) Append(ctx context.Context, output interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.appendRecord(ctx, output); err != nil && o.marshalErr == nil {
+		o.marshalErr = err
+	}
+}
+
+// AppendRecord adds the output data to the batch, posting it once full.
+//
+// Returns the error marshaling the output data, which is discarded. Errors posting are kept and returned by the next
+// call to Write, Flush or Close, once a batch could not be posted the output data is discarded.
+func (o *HTTPOutput) AppendRecord(ctx context.Context, output interface{}) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.appendRecord(ctx, output)
+}
+
+// appendRecord adds the output data to the batch, returning the error marshaling it.
+func (o *HTTPOutput) appendRecord(ctx context.Context, output interface{}) error {
+	if o.err != nil {
+		return nil
+	}
+
+	if o.flushInterval > 0 && o.ticker == nil {
+		o.start()
+	}
+
+	if o.marshalOutput != nil {
+		r, err := o.marshalOutput(ctx, output)
+		if err != nil {
+			return err
+		}
+
+		output = r
+	}
+
+	if _, err := writeRecord(&o.batch, output, NoFraming); err != nil {
+		o.err = err
+
+		return nil
+	}
+
+	o.records++
+
+	if o.records >= o.batchSize {
+		o.post()
+	}
+
+	return nil
+}
+
+// start starts the periodic post of the batch.
+func (o *HTTPOutput) start() {
+	o.ticker = time.NewTicker(o.flushInterval)
+	o.done = make(chan struct{})
+
+	go func(ticker *time.Ticker, done chan struct{}) {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				o.mu.Lock()
+				o.post()
+				o.mu.Unlock()
+			}
+		}
+	}(o.ticker, o.done)
+}
+
+// post posts the batch, retrying the failed requests, keeping the first error that occurred. The batch is emptied
+// either way.
+//
+// The requests are bound by the timeout only, not by the context of the caller, for the records drained once the
+// process is interrupted or aborted to be posted as well.
+func (o *HTTPOutput) post() {
+	if o.records == 0 {
+		return
+	}
+
+	defer func() {
+		o.batch.Reset()
+		o.records = 0
+	}()
+
+	if o.err != nil {
+		return
+	}
+
+	backoff := o.minBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := o.send(o.batch.Bytes())
+		if err == nil {
+			return
+		}
+
+		if attempt >= o.retries || !retryable(err) {
+			o.err = err
+
+			return
+		}
+
+		time.Sleep(backoff)
+
+		if backoff *= 2; backoff > o.maxBackoff {
+			backoff = o.maxBackoff
+		}
+	}
+}
+
+// send posts the body in a single request.
+//
+// Returns any error that occurred, including *HTTPStatusError when the status code is other than 2xx.
+func (o *HTTPOutput) send(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", o.contentType)
+
+	res, err := o.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	// the body is drained for the connection to be reused.
+	_, _ = io.Copy(ioutil.Discard, res.Body) // nolint:errcheck
+	_ = res.Body.Close()                     // nolint:errcheck
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &HTTPStatusError{URL: o.url, StatusCode: res.StatusCode, Status: res.Status}
+	}
+
+	return nil
+}
+
+// retryable reports whether the failed request may succeed when retried.
+func retryable(err error) bool {
+	if se, ok := err.(*HTTPStatusError); ok {
+		return se.temporary()
+	}
+
+	// network errors and timeouts.
+	return true
+}
+
+// Write posts the batch.
+//
+// Returns any error that occurred.
+func (o *HTTPOutput) Write(ctx context.Context) error {
+	return o.Flush(ctx)
+}
+
+// Flush posts the batch.
+//
+// Returns any error that occurred.
+func (o *HTTPOutput) Flush(_ context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.post()
+
+	return o.error()
+}
+
+// error returns the error posting, if any, otherwise the one marshaling the output data appended by Append.
+func (o *HTTPOutput) error() error {
+	if o.err != nil {
+		return o.err
+	}
+
+	return o.marshalErr
+}
+
+// Close posts the batch and stops the periodic post.
+//
+// Returns any error that occurred.
+func (o *HTTPOutput) Close(_ context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.ticker != nil {
+		o.ticker.Stop()
+		close(o.done)
+
+		o.ticker = nil
+	}
+
+	o.post()
+
+	return o.error()
+}
+
+// WithMarshaling set MarshalOutput func into HTTPOutput.
+func (o *HTTPOutput) WithMarshaling(marshalOutput MarshalOutput) *HTTPOutput {
+	o.marshalOutput = marshalOutput
+
+	return o
+}
+
+// WithClient set the http.Client sending the requests into HTTPOutput, http.DefaultClient by default.
+func (o *HTTPOutput) WithClient(client *http.Client) *HTTPOutput {
+	o.client = client
+
+	return o
+}
+
+// WithContentType set the Content-Type of the requests into HTTPOutput, application/x-ndjson by default.
+func (o *HTTPOutput) WithContentType(contentType string) *HTTPOutput {
+	o.contentType = contentType
+
+	return o
+}
+
+// WithBatchSize set the amount of records posted at once into HTTPOutput, DefaultHTTPBatchSize by default.
+func (o *HTTPOutput) WithBatchSize(batchSize int) *HTTPOutput {
+	if batchSize > 0 {
+		o.batchSize = batchSize
+	}
+
+	return o
+}
+
+// WithFlushInterval set the interval to post the batch, even if not full, into HTTPOutput.
+// Zero means posting the batch only once full.
+func (o *HTTPOutput) WithFlushInterval(flushInterval time.Duration) *HTTPOutput {
+	o.flushInterval = flushInterval
+
+	return o
+}
+
+// WithTimeout set the timeout of each request into HTTPOutput, DefaultHTTPTimeout by default.
+func (o *HTTPOutput) WithTimeout(timeout time.Duration) *HTTPOutput {
+	if timeout > 0 {
+		o.timeout = timeout
+	}
+
+	return o
+}
+
+// WithRetries set the amount of times a failed request is retried into HTTPOutput, DefaultHTTPRetries by default.
+// Zero means not retrying.
+func (o *HTTPOutput) WithRetries(retries int) *HTTPOutput {
+	if retries >= 0 {
+		o.retries = retries
+	}
+
+	return o
+}
+
+// WithBackoff set the time waited before the first retry, doubled on each retry up to the max backoff, into
+// HTTPOutput. 100ms and 10s by default.
+func (o *HTTPOutput) WithBackoff(minBackoff, maxBackoff time.Duration) *HTTPOutput {
+	o.minBackoff = minBackoff
+	o.maxBackoff = maxBackoff
+
+	return o
+}
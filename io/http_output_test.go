@@ -0,0 +1,234 @@
+package io_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/stretchr/testify/assert"
+)
+
+// webhook is an httptest stand-in of an endpoint answering the requests with the status codes in order, 200 once
+// they are exhausted, keeping the bodies of the requests answered 200.
+type webhook struct {
+	mu       sync.Mutex
+	codes    []int
+	requests int
+	bodies   []string
+}
+
+func (h *webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, _ := ioutil.ReadAll(r.Body) // nolint:errcheck
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.requests++
+
+	code := http.StatusOK
+	if len(h.codes) > 0 {
+		code, h.codes = h.codes[0], h.codes[1:]
+	}
+
+	if code == http.StatusOK {
+		h.bodies = append(h.bodies, r.Header.Get("Content-Type")+" "+string(data))
+	}
+
+	w.WriteHeader(code)
+}
+
+func TestHTTPOutputAppend(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		codes    []int
+		marshal  sakio.MarshalOutput
+		requests int
+		bodies   []string
+		err      string
+	}{
+		{
+			scenario: "Batches",
+			requests: 2,
+			bodies:   []string{"application/x-ndjson a\nb\n", "application/x-ndjson c\n"},
+		},
+		{
+			scenario: "Retries",
+			codes:    []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			requests: 4,
+			bodies:   []string{"application/x-ndjson a\nb\n", "application/x-ndjson c\n"},
+		},
+		{
+			scenario: "Retries exhausted",
+			codes:    []int{500, 502, 503, 504},
+			requests: 4,
+			err:      "POST {url}: 504 Gateway Timeout",
+		},
+		{
+			scenario: "Client error",
+			codes:    []int{http.StatusBadRequest},
+			requests: 1,
+			err:      "POST {url}: 400 Bad Request",
+		},
+		{
+			scenario: "Marshal error",
+			marshal: func(_ context.Context, i interface{}) (string, error) {
+				return "", errors.New("marshal failed")
+			},
+			err: "marshal failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			ctx := context.TODO()
+
+			h := &webhook{codes: tc.codes}
+
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+
+			output := sakio.NewHTTPOutput(srv.URL).
+				WithBatchSize(2).
+				WithBackoff(time.Millisecond, 2*time.Millisecond)
+
+			if tc.marshal != nil {
+				output.WithMarshaling(tc.marshal)
+			}
+
+			for _, v := range []string{"a", "b", "c"} {
+				output.Append(ctx, v)
+			}
+
+			err := output.Close(ctx)
+			if tc.err != "" {
+				assert.EqualError(t, err, strings.Replace(tc.err, "{url}", srv.URL, 1))
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.requests, h.requests)
+			assert.Equal(t, tc.bodies, h.bodies)
+		})
+	}
+}
+
+func TestHTTPOutputFlushInterval(t *testing.T) {
+	ctx := context.TODO()
+
+	h := &webhook{}
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	output := sakio.NewHTTPOutput(srv.URL).
+		WithFlushInterval(10 * time.Millisecond)
+
+	output.Append(ctx, "a")
+
+	time.Sleep(100 * time.Millisecond)
+
+	h.mu.Lock()
+	assert.Equal(t, []string{"application/x-ndjson a\n"}, h.bodies)
+	h.mu.Unlock()
+
+	assert.NoError(t, output.Close(ctx))
+	assert.Equal(t, 1, h.requests)
+}
+
+func TestHTTPOutputContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	h := &webhook{codes: []int{http.StatusOK, http.StatusServiceUnavailable}}
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	output := sakio.NewHTTPOutput(srv.URL).
+		WithBatchSize(2).
+		WithBackoff(time.Millisecond, time.Millisecond)
+
+	output.Append(ctx, "a")
+	output.Append(ctx, "b")
+
+	// the records drained once the process is interrupted are still posted, retries included.
+	cancel()
+
+	output.Append(ctx, "c")
+	output.Append(ctx, "d")
+	output.Append(ctx, "e")
+
+	assert.NoError(t, output.Close(ctx))
+	assert.Equal(t, []string{
+		"application/x-ndjson a\nb\n",
+		"application/x-ndjson c\nd\n",
+		"application/x-ndjson e\n",
+	}, h.bodies)
+}
+
+func TestHTTPOutputMarshalError(t *testing.T) {
+	ctx := context.TODO()
+
+	h := &webhook{}
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	output := sakio.NewHTTPOutput(srv.URL).
+		WithBatchSize(2).
+		WithMarshaling(func(_ context.Context, i interface{}) (string, error) {
+			if i == "b" {
+				return "", errors.New("marshal fails")
+			}
+
+			return i.(string), nil
+		})
+
+	// only the record failing to marshal is discarded.
+	assert.NoError(t, output.AppendRecord(ctx, "a"))
+	assert.EqualError(t, output.AppendRecord(ctx, "b"), "marshal fails")
+	assert.NoError(t, output.AppendRecord(ctx, "c"))
+	assert.NoError(t, output.Flush(ctx))
+
+	// appended by Append, the error is kept, the following records being posted.
+	output.Append(ctx, "d")
+	output.Append(ctx, "b")
+	output.Append(ctx, "e")
+	assert.EqualError(t, output.Close(ctx), "marshal fails")
+
+	assert.Equal(t, []string{"application/x-ndjson a\nc\n", "application/x-ndjson d\ne\n"}, h.bodies)
+}
+
+func TestHTTPOutputTimeout(t *testing.T) {
+	ctx := context.TODO()
+
+	block := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	output := sakio.NewHTTPOutput(srv.URL).
+		WithTimeout(10*time.Millisecond).
+		WithRetries(1).
+		WithBackoff(time.Millisecond, time.Millisecond)
+
+	output.Append(ctx, "a")
+
+	err := output.Flush(ctx)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "deadline exceeded")
+
+	// the following records are discarded once an error occurred.
+	output.Append(ctx, "b")
+	assert.Equal(t, err, output.Close(ctx))
+}
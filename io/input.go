@@ -14,6 +14,75 @@ type Input interface {
 	Next(ctx context.Context) (interface{}, error)
 }
 
+// RawInput defines a contract for input data source able to provide the records as they were read,
+// before being unmarshaled.
+type RawInput interface {
+	Input
+
+	// Raw returns the last record returned by Next as it was read from the input source,
+	// including when Next failed unmarshaling it.
+	Raw() string
+}
+
+// Position is the position reached in an input source, to resume reading from it (see ResumableInput).
+type Position struct {
+	// File is the file being read, empty when nothing was read yet.
+	File string `json:"file,omitempty"`
+	// Offset is the amount of bytes of the records read in the file, zero when the records can not be sought by
+	// offset (i.e. the file is compressed).
+	Offset int64 `json:"offset"`
+	// Record is the amount of records read in the file.
+	Record int `json:"record"`
+}
+
+// ResumableInput defines a contract for input data source able to provide the position reached and to resume
+// reading from it, i.e. after a restart.
+type ResumableInput interface {
+	Input
+
+	// Position returns the position after the last record returned by Next.
+	Position() Position
+
+	// Resume sets the position to resume reading from, before Next is called the first time.
+	// Returns any error that occurred.
+	Resume(position Position) error
+}
+
+// AckableInput defines a contract for input data source that needs to learn whether each record returned by Next was
+// processed, i.e. to commit the offset of the records processed only, for at-least-once delivery.
+//
+// Ack and Nack may be called concurrently with Next and between them, and in a different order than the records
+// were returned. Errors acknowledging the records are kept by the input source, i.e. returned by Next or Close.
+type AckableInput interface {
+	Input
+
+	// Receipt returns the receipt of the last record returned by Next, including when Next failed with
+	// *InvalidRecordError, to acknowledge the record with.
+	Receipt() interface{}
+
+	// Ack acknowledges the record of the receipt was processed, appended to the output or dropped.
+	Ack(ctx context.Context, receipt interface{})
+
+	// Nack acknowledges the record of the receipt failed processing, with the error that occurred.
+	Nack(ctx context.Context, receipt interface{}, err error)
+}
+
+// InvalidRecordError is returned by Next when the record read is not valid (i.e. it could not be unmarshaled).
+// The input source is still readable, the next call to Next returns the following record.
+type InvalidRecordError struct {
+	Err error
+}
+
+// Error returns the error message of the error that made the record invalid.
+func (e *InvalidRecordError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error that made the record invalid.
+func (e *InvalidRecordError) Unwrap() error {
+	return e.Err
+}
+
 // UnmarshalInput function to unmarshal a stream object.
 //
 // Returns error if unmarshal fails
@@ -22,36 +91,97 @@ type UnmarshalInput func(ctx context.Context, i string) (interface{}, error)
 // StdinInput reads the input data coming from os.Stdin.
 type StdinInput struct {
 	scanner        *bufio.Scanner
+	reader         *RecordReader
 	unmarshalInput UnmarshalInput
+
+	raw string
 }
 
-var _ Input = new(StdinInput)
+var _ RawInput = new(StdinInput)
 
-// NewStdinInput create an instance of StdinInput.
+// NewStdinInput create an instance of StdinInput reading the records with the scanner.
+//
+// The scanner stops at the first record exceeding its buffer size (bufio.MaxScanTokenSize by default), Next
+// returning bufio.ErrTooLong. Use NewStdinReaderInput to skip such records instead.
 func NewStdinInput(scanner *bufio.Scanner) *StdinInput {
 	return &StdinInput{
 		scanner: scanner,
 	}
 }
 
+// NewStdinReaderInput create an instance of StdinInput reading the records from r with a RecordReader,
+// the records exceeding the max record size (see WithMaxRecordSize) are skipped.
+//
+// Common initialization example:
+//
+//      input := NewStdinReaderInput(os.Stdin).
+//			WithMaxRecordSize(1 << 20).
+//			WithUnmarshaling(unmarshal)
+//
+func NewStdinReaderInput(r io.Reader) *StdinInput {
+	return &StdinInput{
+		reader: NewRecordReader(r),
+	}
+}
+
 // Next returns the next record of the io.Stdin. If unmarshalInput is set, the record will be unmarshaled.
 // Starting from the first record when it is call the first time.
 //
-// Returns any error that occurred, including io.EOF when no more record is available.
+// Returns any error that occurred, including io.EOF when no more record is available and *InvalidRecordError
+// when unmarshal the record fails or the record exceeds the max record size (see RecordTooLargeError), Raw
+// returning the beginning of the record up to the max record size in such case.
 func (i *StdinInput) Next(ctx context.Context) (interface{}, error) {
-	if !i.scanner.Scan() {
-		return nil, io.EOF
-	}
+	if i.reader != nil {
+		raw, err := i.reader.Read()
+		if _, ok := err.(*RecordTooLargeError); ok {
+			i.raw = raw
+
+			return nil, &InvalidRecordError{Err: err}
+		}
+
+		if err != nil {
+			return nil, err
+		}
 
-	if err := i.scanner.Err(); err != nil {
-		return nil, err
+		i.raw = raw
+	} else {
+		if !i.scanner.Scan() {
+			// Scan returns false on error too, io.EOF is only returned when the input is exhausted.
+			if err := i.scanner.Err(); err != nil {
+				return nil, err
+			}
+
+			return nil, io.EOF
+		}
+
+		i.raw = i.scanner.Text()
 	}
 
 	if i.unmarshalInput != nil {
-		return i.unmarshalInput(ctx, i.scanner.Text())
+		r, err := i.unmarshalInput(ctx, i.raw)
+		if err != nil {
+			return nil, &InvalidRecordError{Err: err}
+		}
+
+		return r, nil
+	}
+
+	return i.raw, nil
+}
+
+// Raw returns the last record returned by Next as it was read from io.Stdin.
+func (i *StdinInput) Raw() string {
+	return i.raw
+}
+
+// WithMaxRecordSize set the max size in bytes of the records read into StdinInput, only when created by
+// NewStdinReaderInput.
+func (i *StdinInput) WithMaxRecordSize(maxRecordSize int) *StdinInput {
+	if i.reader != nil {
+		i.reader.WithMaxRecordSize(maxRecordSize)
 	}
 
-	return i.scanner.Text(), nil
+	return i
 }
 
 // WithUnmarshaling set UnmarshalInput func into StdinInput.
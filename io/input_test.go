@@ -95,3 +95,45 @@ func TestStdinInputNext(t *testing.T) {
 		})
 	}
 }
+
+func TestStdinInputNextScannerError(t *testing.T) {
+	ctx := context.TODO()
+
+	scanner := bufio.NewScanner(strings.NewReader("{\"id\":1}\n" + strings.Repeat("a", 100) + "\n{\"id\":2}\n"))
+	scanner.Buffer(nil, 64)
+
+	input := sakio.NewStdinInput(scanner)
+
+	r, err := input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1}`, r)
+
+	// the scanner error is returned instead of io.EOF.
+	_, err = input.Next(ctx)
+	assert.Equal(t, bufio.ErrTooLong, err)
+}
+
+func TestStdinReaderInputNext(t *testing.T) {
+	ctx := context.TODO()
+
+	input := sakio.NewStdinReaderInput(strings.NewReader("{\"id\":1}\n{\"id\":\"" + strings.Repeat("a", 100) + "\"}\n{\"id\":2}")).
+		WithMaxRecordSize(64)
+
+	r, err := input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":1}`, r)
+
+	// the record exceeding the max record size is skipped.
+	_, err = input.Next(ctx)
+	assert.Equal(t, &sakio.InvalidRecordError{Err: &sakio.RecordTooLargeError{Size: 109, MaxSize: 64}}, err)
+	assert.EqualError(t, err, "record of 109 bytes exceeds the max record size of 64 bytes")
+	assert.Equal(t, "{\"id\":\""+strings.Repeat("a", 57), input.Raw())
+
+	r, err = input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":2}`, r)
+	assert.Equal(t, `{"id":2}`, input.Raw())
+
+	_, err = input.Next(ctx)
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,374 @@
+package io
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// JSONRecordReader reads the records of a JSON stream with a token level decoder, without loading the whole
+// document into memory.
+//
+// The records are the elements of the top level arrays and the other top level values, so a single array, pretty
+// printed values concatenated one after another, or both, can be read. When a JSON pointer (RFC 6901) is set, the
+// records are the elements of the array it points to in each top level value, or the value itself when it is not
+// an array.
+type JSONRecordReader struct {
+	dec     *json.Decoder
+	pointer string
+
+	// streaming is true while reading the elements of an array.
+	streaming bool
+	// depth is the amount of containers enclosing the array or the value pointed to, to skip once it is read.
+	depth int
+}
+
+// NewJSONRecordReader creates an instance of JSONRecordReader reading the records from r.
+func NewJSONRecordReader(r io.Reader) *JSONRecordReader {
+	dec := json.NewDecoder(r)
+	// numbers are kept as they were read.
+	dec.UseNumber()
+
+	return &JSONRecordReader{
+		dec: dec,
+	}
+}
+
+// WithPointer set the JSON pointer, i.e. /data/items, to the array to read the elements of into JSONRecordReader.
+func (r *JSONRecordReader) WithPointer(pointer string) *JSONRecordReader {
+	r.pointer = pointer
+
+	return r
+}
+
+// Read returns the next record as compact JSON.
+//
+// Returns any error that occurred, including io.EOF when no more record is available, *json.SyntaxError when
+// the stream is not valid JSON and an error when the JSON pointer is not valid or not found. The stream is not
+// readable after an error.
+func (r *JSONRecordReader) Read() (string, error) {
+	for {
+		if r.streaming {
+			if r.dec.More() {
+				var raw json.RawMessage
+				if err := r.dec.Decode(&raw); err != nil {
+					return "", err
+				}
+
+				return compact(raw)
+			}
+
+			// closing bracket of the array.
+			if _, err := r.dec.Token(); err != nil {
+				return "", err
+			}
+
+			r.streaming = false
+
+			if err := r.skipEnclosing(); err != nil {
+				return "", err
+			}
+
+			continue
+		}
+
+		if r.pointer == "" {
+			tok, err := r.dec.Token()
+			if err != nil {
+				return "", err
+			}
+
+			if tok == json.Delim('[') {
+				r.streaming = true
+
+				continue
+			}
+
+			return r.value(tok)
+		}
+
+		record, err := r.seek()
+		if err != nil {
+			return "", err
+		}
+
+		if r.streaming {
+			continue
+		}
+
+		return record, r.skipEnclosing()
+	}
+}
+
+// seek reads the next top level value up to the value the pointer points to. The elements are streamed if it is an
+// array, otherwise the value is returned.
+func (r *JSONRecordReader) seek() (string, error) {
+	if !strings.HasPrefix(r.pointer, "/") {
+		return "", fmt.Errorf("invalid json pointer %q, must start with /", r.pointer)
+	}
+
+	tok, err := r.dec.Token()
+	if err != nil {
+		return "", err
+	}
+
+	r.depth = 0
+
+	for _, segment := range strings.Split(r.pointer[1:], "/") {
+		segment = strings.Replace(strings.Replace(segment, "~1", "/", -1), "~0", "~", -1)
+
+		found, err := r.enter(tok, segment)
+		if err != nil {
+			return "", err
+		}
+
+		if !found {
+			return "", fmt.Errorf("json pointer %q not found", r.pointer)
+		}
+
+		r.depth++
+
+		if tok, err = r.dec.Token(); err != nil {
+			return "", err
+		}
+	}
+
+	if tok == json.Delim('[') {
+		r.streaming = true
+
+		return "", nil
+	}
+
+	return r.value(tok)
+}
+
+// enter reads the container, whose opening token is tok, up to the value of the key or index given by the segment.
+// Returns false if the container has no such key or index, or tok is not a container.
+func (r *JSONRecordReader) enter(tok json.Token, segment string) (bool, error) {
+	switch tok {
+	case json.Delim('{'):
+		for r.dec.More() {
+			key, err := r.dec.Token()
+			if err != nil {
+				return false, err
+			}
+
+			if key == segment {
+				return true, nil
+			}
+
+			if err := r.skip(); err != nil {
+				return false, err
+			}
+		}
+	case json.Delim('['):
+		index, err := strconv.Atoi(segment)
+		if err != nil {
+			return false, nil
+		}
+
+		for i := 0; r.dec.More(); i++ {
+			if i == index {
+				return true, nil
+			}
+
+			if err := r.skip(); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// skip reads the next value token by token, without holding it.
+func (r *JSONRecordReader) skip() error {
+	depth := 0
+
+	for {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// skipEnclosing reads the rest of the containers enclosing the value pointed to, up to the end of the top level value.
+func (r *JSONRecordReader) skipEnclosing() error {
+	for r.depth > 0 {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			r.depth++
+		case json.Delim('}'), json.Delim(']'):
+			r.depth--
+		}
+	}
+
+	return nil
+}
+
+// value returns the value, whose first token is tok, as compact JSON.
+func (r *JSONRecordReader) value(tok json.Token) (string, error) {
+	var buf bytes.Buffer
+
+	switch tok {
+	case json.Delim('{'):
+		buf.WriteByte('{')
+
+		for r.dec.More() {
+			key, err := r.dec.Token()
+			if err != nil {
+				return "", err
+			}
+
+			var v json.RawMessage
+			if err := r.dec.Decode(&v); err != nil {
+				return "", err
+			}
+
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+
+			k, _ := json.Marshal(key) // nolint:errcheck
+
+			buf.Write(k)
+			buf.WriteByte(':')
+			buf.Write(v)
+		}
+
+		buf.WriteByte('}')
+	case json.Delim('['):
+		buf.WriteByte('[')
+
+		for r.dec.More() {
+			var v json.RawMessage
+			if err := r.dec.Decode(&v); err != nil {
+				return "", err
+			}
+
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+
+			buf.Write(v)
+		}
+
+		buf.WriteByte(']')
+	default:
+		v, err := json.Marshal(tok)
+		if err != nil {
+			return "", err
+		}
+
+		return string(v), nil
+	}
+
+	// closing token of the container.
+	if _, err := r.dec.Token(); err != nil {
+		return "", err
+	}
+
+	return compact(buf.Bytes())
+}
+
+// compact returns the JSON without insignificant spaces.
+func compact(raw []byte) (string, error) {
+	var buf bytes.Buffer
+
+	if err := json.Compact(&buf, raw); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// JSONStreamInput reads the input data from a JSON stream, being the records the elements of a top level array,
+// concatenated values (i.e. pretty printed objects) or the elements of the nested array a JSON pointer points to,
+// see JSONRecordReader.
+//
+// Common initialization example:
+//
+//      input := NewJSONStreamInput(os.Stdin).
+//			WithPointer("/data/items").
+//			WithUnmarshaling(unmarshal)
+//
+type JSONStreamInput struct {
+	reader         *JSONRecordReader
+	unmarshalInput UnmarshalInput
+
+	raw string
+}
+
+var _ RawInput = new(JSONStreamInput)
+
+// NewJSONStreamInput creates an instance of JSONStreamInput reading the JSON stream from r.
+func NewJSONStreamInput(r io.Reader) *JSONStreamInput {
+	return &JSONStreamInput{
+		reader: NewJSONRecordReader(r),
+	}
+}
+
+// Next returns the next record of the JSON stream, as compact JSON. If unmarshalInput is set, the record will be
+// unmarshaled. Starting from the first record when it is call the first time.
+//
+// Returns any error that occurred, including io.EOF when no more record is available and *InvalidRecordError
+// when unmarshal the record fails.
+func (i *JSONStreamInput) Next(ctx context.Context) (interface{}, error) {
+	raw, err := i.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	i.raw = raw
+
+	if i.unmarshalInput == nil {
+		return i.raw, nil
+	}
+
+	r, err := i.unmarshalInput(ctx, i.raw)
+	if err != nil {
+		return nil, &InvalidRecordError{Err: err}
+	}
+
+	return r, nil
+}
+
+// Raw returns the last record returned by Next as compact JSON.
+func (i *JSONStreamInput) Raw() string {
+	return i.raw
+}
+
+// WithPointer set the JSON pointer to the array to read the elements of into JSONStreamInput.
+func (i *JSONStreamInput) WithPointer(pointer string) *JSONStreamInput {
+	i.reader.WithPointer(pointer)
+
+	return i
+}
+
+// WithUnmarshaling set UnmarshalInput func into JSONStreamInput.
+func (i *JSONStreamInput) WithUnmarshaling(unmarshalInput UnmarshalInput) *JSONStreamInput {
+	i.unmarshalInput = unmarshalInput
+
+	return i
+}
@@ -0,0 +1,157 @@
+package io_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONRecordReaderRead(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		data     string
+		pointer  string
+		records  []string
+		err      string
+	}{
+		{
+			scenario: "Read top level array",
+			data:     `[{"id": 1}, {"id": 2, "tags": ["a", "b"]}, 3, "four", null]`,
+			records:  []string{`{"id":1}`, `{"id":2,"tags":["a","b"]}`, `3`, `"four"`, `null`},
+		},
+		{
+			scenario: "Read concatenated pretty printed values",
+			data: `{
+  "id": 1,
+  "driver": {
+    "name": "john"
+  }
+}
+{
+  "id": 2.50
+}
+true 12345678901234567890`,
+			records: []string{`{"id":1,"driver":{"name":"john"}}`, `{"id":2.50}`, `true`, `12345678901234567890`},
+		},
+		{
+			scenario: "Read concatenated arrays and values",
+			data:     "[{\"id\":1},{\"id\":2}]\n{\"id\":3}\n[]\n[[4]]",
+			records:  []string{`{"id":1}`, `{"id":2}`, `{"id":3}`, `[4]`},
+		},
+		{
+			scenario: "Read array pointed to",
+			data: `{"meta": {"count": 2, "items": [0]}, "data": {"items": [{"id": 1}, {"id": 2}], "next": null}, "tail": [1]}
+{"data": {"items": [{"id": 3}]}}`,
+			pointer: "/data/items",
+			records: []string{`{"id":1}`, `{"id":2}`, `{"id":3}`},
+		},
+		{
+			scenario: "Read value pointed to by index and escaped keys",
+			data:     `{"a/b": [{"m~n": {"id": 1}}, {"m~n": {"id": 2}, "x": 1}]}`,
+			pointer:  "/a~1b/1/m~0n",
+			records:  []string{`{"id":2}`},
+		},
+		{
+			scenario: "Read empty stream",
+			data:     " \n",
+		},
+		{
+			scenario: "Pointer not found",
+			data:     `{"data": {"item": []}}`,
+			pointer:  "/data/items",
+			err:      `json pointer "/data/items" not found`,
+		},
+		{
+			scenario: "Invalid pointer",
+			data:     `{"data": []}`,
+			pointer:  "data",
+			err:      `invalid json pointer "data", must start with /`,
+		},
+		{
+			scenario: "Invalid JSON",
+			data:     `[{"id": 1}, {"id": 2,}]`,
+			records:  []string{`{"id":1}`},
+			err:      "invalid character '}' looking for beginning of object key string",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			r := sakio.NewJSONRecordReader(strings.NewReader(tc.data)).
+				WithPointer(tc.pointer)
+
+			var records []string
+
+			for {
+				record, err := r.Read()
+				if err == io.EOF {
+					assert.Empty(t, tc.err)
+
+					break
+				}
+
+				if err != nil {
+					assert.EqualError(t, err, tc.err)
+
+					break
+				}
+
+				records = append(records, record)
+			}
+
+			assert.Equal(t, tc.records, records)
+		})
+	}
+}
+
+func TestJSONStreamInputNext(t *testing.T) {
+	ctx := context.TODO()
+
+	input := sakio.NewJSONStreamInput(strings.NewReader(`{"data": [{"id": 1}, [2]]}`)).
+		WithPointer("/data").
+		WithUnmarshaling(func(_ context.Context, i string) (interface{}, error) {
+			var a map[string]interface{}
+
+			err := json.Unmarshal([]byte(i), &a)
+
+			return a, err
+		})
+
+	r, err := input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": float64(1)}, r)
+	assert.Equal(t, `{"id":1}`, input.Raw())
+
+	_, err = input.Next(ctx)
+	assert.IsType(t, &sakio.InvalidRecordError{}, err)
+	assert.Equal(t, `[2]`, input.Raw())
+
+	_, err = input.Next(ctx)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestFileInputNextJSONStream(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	array := writeFile(t, dir, "a.json.gz", gzipped(t, "[\n  {\"id\": 1},\n  {\"id\": 2}\n]\n"))
+	invalid := writeFile(t, dir, "b.json", []byte(`[{"id": 3}, {`))
+
+	input := sakio.NewFileInput(array, invalid).
+		WithJSONStream("")
+
+	records, sources, err := readAll(t, input)
+	assert.EqualError(t, err, "read "+invalid+": unexpected EOF")
+	assert.Equal(t, []interface{}{`{"id":1}`, `{"id":2}`, `{"id":3}`}, records)
+	assert.Equal(t, []string{array + ":1", array + ":2", invalid + ":1"}, sources)
+}
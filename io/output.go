@@ -1,9 +1,13 @@
 package io
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"sync"
+	"time"
 )
 
 // Output defines a contract for output data target.
@@ -72,3 +76,261 @@ func (o *StdoutOutput) WithMarshaling(marshalOutput MarshalOutput) *StdoutOutput
 
 	return o
 }
+
+// StreamOutput defines a contract for output data target that writes the output data as soon as it is appended,
+// instead of holding it until Write is called.
+type StreamOutput interface {
+	Output
+
+	// Flush writes any buffered output data into the target.
+	// Returns any error that occurred, including the ones that occurred while appending.
+	Flush(ctx context.Context) error
+
+	// Close flushes the buffered output data and releases the resources held by the output.
+	// Returns any error that occurred.
+	Close(ctx context.Context) error
+}
+
+// CommitOutput defines a contract for stream output data target that needs more than flushing to make the output
+// data appended so far durable, i.e. to take a checkpoint of the process.
+type CommitOutput interface {
+	StreamOutput
+
+	// Commit makes the output data appended so far durable and visible in the target.
+	// Returns any error that occurred, including the ones that occurred while appending.
+	Commit(ctx context.Context) error
+}
+
+// RecordOutput defines a contract for output data target able to report the output data it can not append, i.e.
+// failing to marshal it, so only that output data is discarded.
+type RecordOutput interface {
+	Output
+
+	// AppendRecord adds output data.
+	// Returns the error that made the output data be discarded, the target still taking the next output data.
+	AppendRecord(ctx context.Context, output interface{}) error
+}
+
+// defaultBufferSize is the size of the buffer used by WriterOutput when none is set.
+const defaultBufferSize = 4096
+
+// WriterOutput writes the output data to an io.Writer as soon as it is appended, one record per line, or length
+// prefixed when framed (see WithFraming).
+//
+// By default each record is flushed to the io.Writer right after being appended. When a flush interval is set,
+// records are buffered and flushed periodically, when the buffer is full and when Write, Flush or Close are called.
+type WriterOutput struct {
+	marshalOutput MarshalOutput
+
+	w             io.Writer
+	bufferSize    int
+	flushInterval time.Duration
+	framing       Framing
+
+	mu  sync.Mutex
+	bw  *bufio.Writer
+	err error
+	// marshalErr is the first error marshaling the output data appended by Append.
+	marshalErr error
+	ticker     *time.Ticker
+	done       chan struct{}
+}
+
+var (
+	_ StreamOutput = new(WriterOutput)
+	_ RecordOutput = new(WriterOutput)
+)
+
+// NewWriterOutput create an instance of WriterOutput.
+//
+// Common initialization example:
+//
+//      output := NewWriterOutput(os.Stdout).
+//			WithFlushInterval(time.Second)
+//
+func NewWriterOutput(w io.Writer) *WriterOutput {
+	return &WriterOutput{
+		w:          w,
+		bufferSize: defaultBufferSize,
+	}
+}
+
+// Append writes the output data into the io.Writer.
+//
+// Errors are kept and returned by the next call to Write, Flush or Close. Once an error writing occurred the output
+// data is discarded, while the output data failing to marshal is the only one discarded (see AppendRecord).
+func (o *WriterOutput) Append(ctx context.Context, output interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.appendRecord(ctx, output); err != nil && o.marshalErr == nil {
+		o.marshalErr = err
+	}
+}
+
+// AppendRecord writes the output data into the io.Writer.
+//
+// Returns the error marshaling the output data, which is discarded. Errors writing are kept and returned by the
+// next call to Write, Flush or Close, once an error writing occurred the output data is discarded.
+func (o *WriterOutput) AppendRecord(ctx context.Context, output interface{}) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.appendRecord(ctx, output)
+}
+
+// appendRecord writes the output data into the buffered writer, returning the error marshaling it.
+func (o *WriterOutput) appendRecord(ctx context.Context, output interface{}) error {
+	if o.err != nil {
+		return nil
+	}
+
+	if o.bw == nil {
+		o.start()
+	}
+
+	if o.marshalOutput != nil {
+		r, err := o.marshalOutput(ctx, output)
+		if err != nil {
+			return err
+		}
+
+		output = r
+	}
+
+	if _, err := writeRecord(o.bw, output, o.framing); err != nil {
+		o.err = err
+
+		return nil
+	}
+
+	if o.flushInterval == 0 {
+		o.err = o.bw.Flush()
+	}
+
+	return nil
+}
+
+// start initializes the buffered writer and the periodic flush, if any.
+func (o *WriterOutput) start() {
+	o.bw = bufio.NewWriterSize(o.w, o.bufferSize)
+
+	if o.flushInterval == 0 {
+		return
+	}
+
+	o.ticker = time.NewTicker(o.flushInterval)
+	o.done = make(chan struct{})
+
+	go func(ticker *time.Ticker, done chan struct{}) {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				o.mu.Lock()
+				o.flush()
+				o.mu.Unlock()
+			}
+		}
+	}(o.ticker, o.done)
+}
+
+// fail keeps the error, unless an error already occurred, to be returned by the next call to Write, Flush or Close.
+func (o *WriterOutput) fail(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.err == nil {
+		o.err = err
+	}
+}
+
+// flush flushes the buffered writer keeping the first error that occurred.
+func (o *WriterOutput) flush() {
+	if o.err != nil || o.bw == nil {
+		return
+	}
+
+	o.err = o.bw.Flush()
+}
+
+// Write flushes the buffered output data into the io.Writer.
+//
+// Returns any error that occurred.
+func (o *WriterOutput) Write(ctx context.Context) error {
+	return o.Flush(ctx)
+}
+
+// Flush flushes the buffered output data into the io.Writer.
+//
+// Returns any error that occurred.
+func (o *WriterOutput) Flush(_ context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.flush()
+
+	return o.error()
+}
+
+// error returns the error writing, if any, otherwise the one marshaling the output data appended by Append.
+func (o *WriterOutput) error() error {
+	if o.err != nil {
+		return o.err
+	}
+
+	return o.marshalErr
+}
+
+// Close flushes the buffered output data into the io.Writer and stops the periodic flush.
+// The io.Writer is not closed.
+//
+// Returns any error that occurred.
+func (o *WriterOutput) Close(_ context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.ticker != nil {
+		o.ticker.Stop()
+		close(o.done)
+
+		o.ticker = nil
+	}
+
+	o.flush()
+
+	return o.error()
+}
+
+// WithMarshaling set MarshalOutput func into WriterOutput.
+func (o *WriterOutput) WithMarshaling(marshalOutput MarshalOutput) *WriterOutput {
+	o.marshalOutput = marshalOutput
+
+	return o
+}
+
+// WithFlushInterval set the interval to flush the buffered output data into WriterOutput.
+// Zero means flushing after each record appended.
+func (o *WriterOutput) WithFlushInterval(flushInterval time.Duration) *WriterOutput {
+	o.flushInterval = flushInterval
+
+	return o
+}
+
+// WithBufferSize set the size of the buffer into WriterOutput.
+func (o *WriterOutput) WithBufferSize(bufferSize int) *WriterOutput {
+	if bufferSize > 0 {
+		o.bufferSize = bufferSize
+	}
+
+	return o
+}
+
+// WithFraming set the Framing of the records into WriterOutput, Uint32Framing or VarintFraming to write each record
+// prefixed by its length instead of followed by a newline, for binary records to be read by FrameReader.
+func (o *WriterOutput) WithFraming(framing Framing) *WriterOutput {
+	o.framing = framing
+
+	return o
+}
@@ -1,9 +1,12 @@
 package io_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	sakio "github.com/dohernandez/swiss-army-knife/io"
 	"github.com/dohernandez/swiss-army-knife/test"
@@ -65,3 +68,141 @@ func TestStdoutOutputWrite(t *testing.T) {
 		})
 	}
 }
+
+func TestWriterOutputAppend(t *testing.T) {
+	records := strings.Split(stdoutOutput, "\n")
+
+	testCases := []struct {
+		scenario string
+		init     func(output *sakio.WriterOutput)
+		assert   func(t *testing.T, ctx context.Context, output *sakio.WriterOutput, buf *bytes.Buffer)
+	}{
+		{
+			scenario: "Append writes each record as soon as it is appended",
+			assert: func(t *testing.T, ctx context.Context, output *sakio.WriterOutput, buf *bytes.Buffer) {
+				for i, r := range records {
+					output.Append(ctx, r)
+
+					assert.Equal(t, strings.Join(records[:i+1], "\n")+"\n", buf.String())
+				}
+
+				assert.NoError(t, output.Close(ctx))
+			},
+		},
+		{
+			scenario: "Append buffers records until flushed",
+			init: func(output *sakio.WriterOutput) {
+				output.WithFlushInterval(time.Hour)
+			},
+			assert: func(t *testing.T, ctx context.Context, output *sakio.WriterOutput, buf *bytes.Buffer) {
+				for _, r := range records {
+					output.Append(ctx, r)
+				}
+
+				assert.Empty(t, buf.String())
+
+				assert.NoError(t, output.Flush(ctx))
+				assert.Equal(t, stdoutOutput+"\n", buf.String())
+
+				assert.NoError(t, output.Close(ctx))
+			},
+		},
+		{
+			scenario: "Append writes records when buffer is full",
+			init: func(output *sakio.WriterOutput) {
+				output.WithFlushInterval(time.Hour).
+					WithBufferSize(len(records[0]) + 1)
+			},
+			assert: func(t *testing.T, ctx context.Context, output *sakio.WriterOutput, buf *bytes.Buffer) {
+				output.Append(ctx, records[0])
+				assert.Empty(t, buf.String())
+
+				output.Append(ctx, records[1])
+				assert.True(t, strings.HasPrefix(buf.String(), records[0]+"\n"))
+
+				assert.NoError(t, output.Close(ctx))
+				assert.Equal(t, strings.Join(records[:2], "\n")+"\n", buf.String())
+			},
+		},
+		{
+			scenario: "Append fails, marshal error returned on close",
+			init: func(output *sakio.WriterOutput) {
+				output.WithMarshaling(func(_ context.Context, i interface{}) (string, error) {
+					return "", errors.New("marshal fails")
+				})
+			},
+			assert: func(t *testing.T, ctx context.Context, output *sakio.WriterOutput, buf *bytes.Buffer) {
+				output.Append(ctx, records[0])
+
+				assert.EqualError(t, output.Close(ctx), "marshal fails")
+				assert.Empty(t, buf.String())
+			},
+		},
+		{
+			scenario: "AppendRecord fails, only the record failing to marshal is discarded",
+			init: func(output *sakio.WriterOutput) {
+				output.WithMarshaling(func(_ context.Context, i interface{}) (string, error) {
+					if i == records[0] {
+						return "", errors.New("marshal fails")
+					}
+
+					return i.(string), nil
+				})
+			},
+			assert: func(t *testing.T, ctx context.Context, output *sakio.WriterOutput, buf *bytes.Buffer) {
+				assert.EqualError(t, output.AppendRecord(ctx, records[0]), "marshal fails")
+				assert.NoError(t, output.AppendRecord(ctx, records[1]))
+
+				assert.NoError(t, output.Close(ctx))
+				assert.Equal(t, records[1]+"\n", buf.String())
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			ctx := context.TODO()
+
+			var buf bytes.Buffer
+
+			output := sakio.NewWriterOutput(&buf)
+
+			if tc.init != nil {
+				tc.init(output)
+			}
+
+			tc.assert(t, ctx, output, &buf)
+		})
+	}
+}
+
+// notifyingWriter notifies every time it is written.
+type notifyingWriter struct {
+	written chan string
+}
+
+func (w notifyingWriter) Write(p []byte) (int, error) {
+	w.written <- string(p)
+
+	return len(p), nil
+}
+
+func TestWriterOutputFlushInterval(t *testing.T) {
+	ctx := context.TODO()
+
+	w := notifyingWriter{written: make(chan string, 1)}
+	output := sakio.NewWriterOutput(w).
+		WithFlushInterval(10 * time.Millisecond)
+
+	output.Append(ctx, "1629")
+
+	select {
+	case r := <-w.written:
+		assert.Equal(t, "1629\n", r)
+	case <-time.After(time.Second):
+		t.Fatal("output was not flushed")
+	}
+
+	assert.NoError(t, output.Close(ctx))
+}
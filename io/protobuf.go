@@ -0,0 +1,355 @@
+package io
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ProtoMessage decodes and encodes the protobuf messages of a type loaded at runtime from a FileDescriptorSet,
+// without the generated code, its Unmarshal and Marshal methods being UnmarshalInput and MarshalOutput funcs.
+//
+// Messages are decoded into map[string]interface{} records keyed by the field names as declared in the .proto file,
+// only the fields set. Numbers are float64, enums the name of their value, bytes base64 strings, repeated fields
+// []interface{} and maps map[string]interface{}, as unmarshaling JSON does, so the operations work the same way
+// whatever the input format is. The records are encoded back the same way, the fields being found by their name or
+// their JSON name, the keys not matching any field (i.e. appended or prefixed by an operation) are discarded.
+//
+// Common initialization example:
+//
+//      data, err := ioutil.ReadFile("rides.pb")
+//		...
+//		message, err := LoadProtoMessage(data, "rides.v1.Location")
+//		...
+//		input := NewFrameInput(os.Stdin).
+//			WithFraming(VarintFraming).
+//			WithUnmarshaling(message.Unmarshal)
+//
+type ProtoMessage struct {
+	desc protoreflect.MessageDescriptor
+}
+
+// LoadProtoMessage returns the ProtoMessage of the message type with the full name given, i.e. rides.v1.Location,
+// from the serialized FileDescriptorSet, i.e. generated with protoc --include_imports --descriptor_set_out.
+//
+// Returns an error if the FileDescriptorSet is not valid or the message type is not found.
+func LoadProtoMessage(descriptorSet []byte, name string) (*ProtoMessage, error) {
+	var fds descriptorpb.FileDescriptorSet
+
+	if err := proto.Unmarshal(descriptorSet, &fds); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %v", err)
+	}
+
+	files, err := protodesc.NewFiles(&fds)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %v", err)
+	}
+
+	d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("message %q not found in the descriptor set", name)
+	}
+
+	desc, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a message", name)
+	}
+
+	return &ProtoMessage{desc: desc}, nil
+}
+
+// Name returns the full name of the message type.
+func (m *ProtoMessage) Name() string {
+	return string(m.desc.FullName())
+}
+
+// Unmarshal decodes a protobuf message into a map[string]interface{} record.
+func (m *ProtoMessage) Unmarshal(_ context.Context, i string) (interface{}, error) {
+	msg := dynamicpb.NewMessage(m.desc)
+
+	if err := proto.Unmarshal([]byte(i), msg); err != nil {
+		return nil, err
+	}
+
+	return protoToMap(msg), nil
+}
+
+// Marshal encodes a map[string]interface{} record as a protobuf message.
+func (m *ProtoMessage) Marshal(_ context.Context, i interface{}) (string, error) {
+	record, ok := i.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("protobuf output expects map[string]interface{} records, got %T", i)
+	}
+
+	msg := dynamicpb.NewMessage(m.desc)
+
+	if err := mapToProto(msg, record); err != nil {
+		return "", err
+	}
+
+	r, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+
+	return string(r), nil
+}
+
+// protoToMap returns the fields set of the message keyed by their name.
+func protoToMap(msg protoreflect.Message) map[string]interface{} {
+	record := make(map[string]interface{})
+
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList():
+			l := v.List()
+			s := make([]interface{}, l.Len())
+
+			for n := range s {
+				s[n] = protoToValue(fd, l.Get(n))
+			}
+
+			record[string(fd.Name())] = s
+		case fd.IsMap():
+			m := make(map[string]interface{}, v.Map().Len())
+
+			v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				m[fmt.Sprint(k.Interface())] = protoToValue(fd.MapValue(), v)
+
+				return true
+			})
+
+			record[string(fd.Name())] = m
+		default:
+			record[string(fd.Name())] = protoToValue(fd, v)
+		}
+
+		return true
+	})
+
+	return record
+}
+
+// protoToValue returns the value of a singular field, or an element of a repeated or map field.
+func protoToValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool()
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+
+		return float64(v.Enum())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return float64(v.Int())
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return float64(v.Uint())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float()
+	case protoreflect.StringKind:
+		return v.String()
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protoToMap(v.Message())
+	}
+
+	return v.Interface()
+}
+
+// mapToProto sets the fields of the message from the record, the keys not matching any field are discarded.
+func mapToProto(msg protoreflect.Message, record map[string]interface{}) error {
+	fields := msg.Descriptor().Fields()
+
+	for k, v := range record {
+		fd := fields.ByName(protoreflect.Name(k))
+		if fd == nil {
+			fd = fields.ByJSONName(k)
+		}
+
+		if fd == nil || v == nil {
+			continue
+		}
+
+		switch {
+		case fd.IsList():
+			s, ok := v.([]interface{})
+			if !ok {
+				return fmt.Errorf("invalid value %v (%T) for repeated field %s", v, v, fd.Name())
+			}
+
+			l := msg.Mutable(fd).List()
+
+			for _, e := range s {
+				pv, err := valueToProto(fd, e, l.NewElement)
+				if err != nil {
+					return err
+				}
+
+				l.Append(pv)
+			}
+		case fd.IsMap():
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("invalid value %v (%T) for map field %s", v, v, fd.Name())
+			}
+
+			pm := msg.Mutable(fd).Map()
+
+			for key, e := range m {
+				pk, err := valueToProto(fd.MapKey(), key, nil)
+				if err != nil {
+					return err
+				}
+
+				pv, err := valueToProto(fd.MapValue(), e, pm.NewValue)
+				if err != nil {
+					return err
+				}
+
+				pm.Set(pk.MapKey(), pv)
+			}
+		default:
+			pv, err := valueToProto(fd, v, func() protoreflect.Value { return msg.NewField(fd) })
+			if err != nil {
+				return err
+			}
+
+			msg.Set(fd, pv)
+		}
+	}
+
+	return nil
+}
+
+// valueToProto returns the protobuf value of a singular field, or an element of a repeated or map field, newMessage
+// creating the value of the message fields.
+func valueToProto(fd protoreflect.FieldDescriptor, v interface{}, newMessage func() protoreflect.Value) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if b, ok := v.(bool); ok {
+			return protoreflect.ValueOfBool(b), nil
+		}
+
+		// map keys are strings.
+		if b, err := strconv.ParseBool(fmt.Sprint(v)); err == nil {
+			return protoreflect.ValueOfBool(b), nil
+		}
+	case protoreflect.EnumKind:
+		if s, ok := v.(string); ok {
+			if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+				return protoreflect.ValueOfEnum(ev.Number()), nil
+			}
+
+			break
+		}
+
+		if n, ok := protoNumber(v, math.MinInt32, math.MaxInt32); ok {
+			return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if n, ok := protoNumber(v, math.MinInt32, math.MaxInt32); ok {
+			return protoreflect.ValueOfInt32(int32(n)), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if n, ok := protoNumber(v, math.MinInt64, math.MaxInt64); ok {
+			return protoreflect.ValueOfInt64(int64(n)), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if n, ok := protoNumber(v, 0, math.MaxUint32); ok {
+			return protoreflect.ValueOfUint32(uint32(n)), nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if n, ok := protoNumber(v, 0, math.MaxUint64); ok {
+			return protoreflect.ValueOfUint64(uint64(n)), nil
+		}
+	case protoreflect.FloatKind:
+		if n, ok := toFloat64(v); ok {
+			return protoreflect.ValueOfFloat32(float32(n)), nil
+		}
+	case protoreflect.DoubleKind:
+		if n, ok := toFloat64(v); ok {
+			return protoreflect.ValueOfFloat64(n), nil
+		}
+	case protoreflect.StringKind:
+		if s, ok := v.(string); ok {
+			return protoreflect.ValueOfString(s), nil
+		}
+	case protoreflect.BytesKind:
+		switch b := v.(type) {
+		case []byte:
+			return protoreflect.ValueOfBytes(b), nil
+		case string:
+			if d, err := base64.StdEncoding.DecodeString(b); err == nil {
+				return protoreflect.ValueOfBytes(d), nil
+			}
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		m, ok := v.(map[string]interface{})
+		if !ok || newMessage == nil {
+			break
+		}
+
+		pv := newMessage()
+
+		if err := mapToProto(pv.Message(), m); err != nil {
+			return protoreflect.Value{}, err
+		}
+
+		return pv, nil
+	}
+
+	return protoreflect.Value{}, fmt.Errorf("invalid value %v (%T) for %s field %s", v, v, fd.Kind(), fd.Name())
+}
+
+// protoNumber returns the value as a whole number within min and max, false if it is not.
+func protoNumber(v interface{}, min, max float64) (float64, bool) {
+	n, ok := toFloat64(v)
+	if !ok || n != math.Trunc(n) || n < min || n > max {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// toFloat64 returns the number, or the string holding one (i.e. a map key or an int64 as protobuf JSON encodes it),
+// as float64.
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+
+		return f, err == nil
+	}
+
+	return 0, false
+}
@@ -0,0 +1,265 @@
+package io_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ridesDescriptorSet returns the serialized FileDescriptorSet of the following .proto file.
+//
+//      syntax = "proto3";
+//		package rides.v1;
+//
+//		enum Status {
+//			UNKNOWN = 0;
+//			MOVING = 1;
+//			STOPPED = 2;
+//		}
+//
+//		message Location {
+//			int64 id = 1;
+//			double lat = 2;
+//			string city_name = 3;
+//			Status status = 4;
+//			repeated Stop stops = 5;
+//			map<string, string> tags = 6;
+//			bytes checksum = 7;
+//
+//			message Stop {
+//				uint32 id = 1;
+//			}
+//		}
+//
+func ridesDescriptorSet(t *testing.T) []byte {
+	t.Helper()
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(jsonName(name)),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+
+		return f
+	}
+
+	repeated := func(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+		return f
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("rides/v1/location.proto"),
+		Package: proto.String("rides.v1"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Status"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("UNKNOWN"), Number: proto.Int32(0)},
+				{Name: proto.String("MOVING"), Number: proto.Int32(1)},
+				{Name: proto.String("STOPPED"), Number: proto.Int32(2)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Location"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+				field("lat", 2, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, ""),
+				field("city_name", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("status", 4, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".rides.v1.Status"),
+				repeated(field("stops", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".rides.v1.Location.Stop")),
+				repeated(field("tags", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".rides.v1.Location.TagsEntry")),
+				field("checksum", 7, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""),
+			},
+			NestedType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Stop"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_UINT32, ""),
+					},
+				},
+				{
+					Name: proto.String("TagsEntry"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+						field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				},
+			},
+		}},
+	}
+
+	data, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}})
+	assert.NoError(t, err)
+
+	return data
+}
+
+// jsonName returns the lowerCamelCase JSON name of the field.
+func jsonName(name string) string {
+	parts := strings.Split(name, "_")
+	for n := 1; n < len(parts); n++ {
+		parts[n] = strings.Title(parts[n])
+	}
+
+	return strings.Join(parts, "")
+}
+
+func TestProtoMessage(t *testing.T) {
+	message, err := sakio.LoadProtoMessage(ridesDescriptorSet(t), "rides.v1.Location")
+	assert.NoError(t, err)
+	assert.Equal(t, "rides.v1.Location", message.Name())
+
+	testCases := []struct {
+		scenario string
+		record   map[string]interface{}
+		expected map[string]interface{}
+		err      string
+	}{
+		{
+			scenario: "Round trip all the field types",
+			record: map[string]interface{}{
+				"id":        float64(347),
+				"lat":       48.8566,
+				"city_name": "paris",
+				"status":    "STOPPED",
+				"stops":     []interface{}{map[string]interface{}{"id": float64(1)}, map[string]interface{}{}},
+				"tags":      map[string]interface{}{"source": "gps"},
+				"checksum":  "AQI=",
+			},
+		},
+		{
+			scenario: "Fields by JSON name, unknown keys discarded and unset fields omitted",
+			record: map[string]interface{}{
+				"id":       "12",
+				"cityName": "paris",
+				"status":   float64(1),
+				"c_lat":    48.8566,
+				"lat":      nil,
+			},
+			expected: map[string]interface{}{
+				"id":        float64(12),
+				"city_name": "paris",
+				"status":    "MOVING",
+			},
+		},
+		{
+			scenario: "Invalid value",
+			record:   map[string]interface{}{"id": 1.5},
+			err:      "invalid value 1.5 (float64) for int64 field id",
+		},
+		{
+			scenario: "Invalid enum value",
+			record:   map[string]interface{}{"status": "PARKED"},
+			err:      "invalid value PARKED (string) for enum field status",
+		},
+		{
+			scenario: "Invalid nested value",
+			record:   map[string]interface{}{"stops": []interface{}{map[string]interface{}{"id": float64(-1)}}},
+			err:      "invalid value -1 (float64) for uint32 field id",
+		},
+		{
+			scenario: "Invalid repeated value",
+			record:   map[string]interface{}{"stops": "1,2"},
+			err:      "invalid value 1,2 (string) for repeated field stops",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			ctx := context.TODO()
+
+			raw, err := message.Marshal(ctx, tc.record)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			r, err := message.Unmarshal(ctx, raw)
+			assert.NoError(t, err)
+
+			expected := tc.expected
+			if expected == nil {
+				expected = tc.record
+			}
+
+			assert.Equal(t, expected, r)
+		})
+	}
+}
+
+func TestProtoMessageNotRecord(t *testing.T) {
+	message, err := sakio.LoadProtoMessage(ridesDescriptorSet(t), "rides.v1.Location")
+	assert.NoError(t, err)
+
+	_, err = message.Marshal(context.TODO(), "347")
+	assert.EqualError(t, err, "protobuf output expects map[string]interface{} records, got string")
+
+	_, err = message.Unmarshal(context.TODO(), "\xff")
+	assert.Error(t, err)
+}
+
+func TestLoadProtoMessageError(t *testing.T) {
+	_, err := sakio.LoadProtoMessage(ridesDescriptorSet(t), "rides.v1.Ride")
+	assert.EqualError(t, err, `message "rides.v1.Ride" not found in the descriptor set`)
+
+	_, err = sakio.LoadProtoMessage(ridesDescriptorSet(t), "rides.v1.Status")
+	assert.EqualError(t, err, `"rides.v1.Status" is not a message`)
+
+	_, err = sakio.LoadProtoMessage([]byte("\xff"), "rides.v1.Location")
+	assert.Error(t, err)
+}
+
+func TestFrameInputNextProtobuf(t *testing.T) {
+	message, err := sakio.LoadProtoMessage(ridesDescriptorSet(t), "rides.v1.Location")
+	assert.NoError(t, err)
+
+	ctx := context.TODO()
+
+	var buf strings.Builder
+
+	output := sakio.NewWriterOutput(&buf).
+		WithFraming(sakio.VarintFraming).
+		WithMarshaling(message.Marshal)
+
+	output.Append(ctx, map[string]interface{}{"id": float64(1), "city_name": strings.Repeat("a", 200)})
+	output.Append(ctx, map[string]interface{}{"id": float64(2)})
+	assert.NoError(t, output.Close(ctx))
+
+	// the length of the first message takes two bytes as a varint.
+	assert.Equal(t, "\xcd\x01", buf.String()[:2])
+
+	input := sakio.NewFrameInput(strings.NewReader(buf.String())).
+		WithFraming(sakio.VarintFraming).
+		WithUnmarshaling(message.Unmarshal)
+
+	r, err := input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "city_name": strings.Repeat("a", 200)}, r)
+
+	r, err = input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": float64(2)}, r)
+
+	_, err = input.Next(ctx)
+	assert.Equal(t, io.EOF, err)
+}
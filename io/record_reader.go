@@ -0,0 +1,129 @@
+package io
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxRecordSize is the max size of the records read by RecordReader when none is set.
+const DefaultMaxRecordSize = 16 << 20
+
+// RecordTooLargeError is returned when a record exceeds the max record size. The record is skipped, the next read
+// returns the following record.
+type RecordTooLargeError struct {
+	// Size is the size of the record in bytes.
+	Size int64
+	// MaxSize is the max record size in bytes.
+	MaxSize int
+}
+
+// Error returns the error message.
+func (e *RecordTooLargeError) Error() string {
+	return fmt.Sprintf("record of %d bytes exceeds the max record size of %d bytes", e.Size, e.MaxSize)
+}
+
+// RecordReader reads newline delimited records, unlike bufio.Scanner the records exceeding the max record size do
+// not stop the reading.
+type RecordReader struct {
+	r             *bufio.Reader
+	maxRecordSize int
+
+	// follow holds the last record until its newline is read, the file being still written (see FileInput.WithFollow).
+	follow bool
+	// record, size and tail are the record being read, kept across reads in follow mode.
+	record []byte
+	size   int64
+	tail   [2]byte
+	// offset is the amount of bytes of the records read, newlines included.
+	offset int64
+}
+
+// NewRecordReader creates an instance of RecordReader reading the records from r.
+func NewRecordReader(r io.Reader) *RecordReader {
+	return &RecordReader{
+		r:             bufio.NewReader(r),
+		maxRecordSize: DefaultMaxRecordSize,
+	}
+}
+
+// Read returns the next record, without the trailing newline (\n or \r\n). The last record does not need to end
+// with a newline.
+//
+// Returns any error that occurred, including io.EOF when no more record is available and *RecordTooLargeError,
+// along with the beginning of the record up to the max record size, when the record exceeds it.
+func (r *RecordReader) Read() (string, error) {
+	for {
+		line, err := r.r.ReadSlice('\n')
+		r.size += int64(len(line))
+
+		from := len(line) - 2
+		if from < 0 {
+			from = 0
+		}
+
+		// tail are the last two bytes read, to find out the size of the newline.
+		for _, c := range line[from:] {
+			r.tail[0], r.tail[1] = r.tail[1], c
+		}
+
+		// the record is kept up to the max record size plus the newline, the remainder is discarded.
+		if room := r.maxRecordSize + 2 - len(r.record); room > 0 {
+			if len(line) > room {
+				line = line[:room]
+			}
+
+			r.record = append(r.record, line...)
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+
+		if err != nil && (err != io.EOF || r.size == 0 || r.follow) {
+			return "", err
+		}
+
+		break
+	}
+
+	record, size, tail := r.record, r.size, r.tail
+	r.record, r.size, r.tail = nil, 0, [2]byte{}
+	r.offset += size
+
+	if tail[1] == '\n' {
+		size--
+
+		if tail[0] == '\r' {
+			size--
+		}
+	}
+
+	if size > int64(r.maxRecordSize) {
+		return string(record[:r.maxRecordSize]), &RecordTooLargeError{Size: size, MaxSize: r.maxRecordSize}
+	}
+
+	return string(dropNewline(record)), nil
+}
+
+// WithMaxRecordSize set the max size in bytes of the records into RecordReader.
+func (r *RecordReader) WithMaxRecordSize(maxRecordSize int) *RecordReader {
+	if maxRecordSize > 0 {
+		r.maxRecordSize = maxRecordSize
+	}
+
+	return r
+}
+
+// dropNewline drops the trailing \n or \r\n.
+func dropNewline(b []byte) []byte {
+	if len(b) > 0 && b[len(b)-1] == '\n' {
+		b = b[:len(b)-1]
+
+		if len(b) > 0 && b[len(b)-1] == '\r' {
+			b = b[:len(b)-1]
+		}
+	}
+
+	return b
+}
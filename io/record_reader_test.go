@@ -0,0 +1,111 @@
+package io_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordReaderRead(t *testing.T) {
+	// longer than the bufio.Reader buffer, to be read in several chunks.
+	long := strings.Repeat("a", 10000)
+
+	testCases := []struct {
+		scenario      string
+		data          string
+		maxRecordSize int
+		records       []string
+		errs          []error
+	}{
+		{
+			scenario: "Read records",
+			data:     "a\nbb\r\n\nccc",
+			records:  []string{"a", "bb", "", "ccc"},
+			errs:     []error{nil, nil, nil, nil},
+		},
+		{
+			scenario: "Read records ending with newline",
+			data:     "a\nbb\n",
+			records:  []string{"a", "bb"},
+			errs:     []error{nil, nil},
+		},
+		{
+			scenario: "Read records longer than the default scanner buffer",
+			data:     strings.Repeat("b", 100000) + "\n" + long,
+			records:  []string{strings.Repeat("b", 100000), long},
+			errs:     []error{nil, nil},
+		},
+		{
+			scenario:      "Read records up to the max record size",
+			data:          "aaaa\r\nbbbbb\ncccc\n",
+			maxRecordSize: 4,
+			records:       []string{"aaaa", "bbbb", "cccc"},
+			errs:          []error{nil, &sakio.RecordTooLargeError{Size: 5, MaxSize: 4}, nil},
+		},
+		{
+			scenario:      "Skip records exceeding the max record size read in several chunks",
+			data:          long + "\n" + long + long + "\na",
+			maxRecordSize: 5000,
+			records:       []string{long[:5000], long[:5000], "a"},
+			errs: []error{
+				&sakio.RecordTooLargeError{Size: 10000, MaxSize: 5000},
+				&sakio.RecordTooLargeError{Size: 20000, MaxSize: 5000},
+				nil,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			// reading one byte at a time, the records are split in as many chunks as possible.
+			r := sakio.NewRecordReader(iotest.OneByteReader(strings.NewReader(tc.data))).
+				WithMaxRecordSize(tc.maxRecordSize)
+
+			var (
+				records []string
+				errs    []error
+			)
+
+			for {
+				record, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+
+				records = append(records, record)
+				errs = append(errs, err)
+			}
+
+			assert.Equal(t, tc.records, records)
+			assert.Equal(t, tc.errs, errs)
+		})
+	}
+}
+
+// failingReader fails reading with err.
+type failingReader struct {
+	err error
+}
+
+func (r failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func TestRecordReaderReadError(t *testing.T) {
+	errRead := errors.New("read failed")
+
+	r := sakio.NewRecordReader(io.MultiReader(strings.NewReader("a\nb"), failingReader{err: errRead}))
+
+	record, err := r.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "a", record)
+
+	_, err = r.Read()
+	assert.Equal(t, errRead, err)
+}
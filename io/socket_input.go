@@ -0,0 +1,411 @@
+package io
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errSocketInputClosed is returned by Listen when the input was closed.
+var errSocketInputClosed = errors.New("socket input closed")
+
+// Connection is the connection a record of SocketInput was read from.
+type Connection struct {
+	// ID is the number of the connection, in the order they were accepted starting from 1.
+	ID int64
+	// RemoteAddr is the address of the peer, empty for the Unix sockets peers not bound to a path.
+	RemoteAddr string
+	// Line is the number of the record in the connection.
+	Line int
+}
+
+// SocketInput reads the input data pushed by the peers connected to a TCP address or a Unix socket, one record per
+// line, or length prefixed when framed (see WithFraming). Connections are read concurrently, their records merged in
+// the order they are read.
+//
+// Records are read from the connections only when Next is called, so when the records are not taken (i.e. the first
+// ChannelConveyor is full) the connections are not read anymore and the peers are slowed down by the flow control
+// of the socket instead of the records being held in memory.
+//
+// A connection is closed once its peer closes it or a read fails, the input keeps accepting new connections until it
+// is closed, Next returning io.EOF only then.
+//
+// Common initialization example:
+//
+//      input := NewSocketInput("tcp", ":7070").
+//			WithUnmarshaling(unmarshal).
+//			WithConnectionAnnotation("_remote_addr", "_connection")
+//		// nolint:errcheck
+//		defer input.Close()
+//
+type SocketInput struct {
+	network        string
+	address        string
+	unmarshalInput UnmarshalInput
+	maxRecordSize  int
+	framing        Framing
+	maxConnections int
+	addrKey        string
+	connectionKey  string
+
+	mu       sync.Mutex
+	listener net.Listener
+	records  chan socketRecord
+	done     chan struct{}
+	conns    map[net.Conn]struct{}
+	lastID   int64
+	wg       sync.WaitGroup
+	closed   bool
+
+	raw        string
+	connection Connection
+}
+
+var _ RawInput = new(SocketInput)
+
+// socketRecord is a record read from a connection, along with the error that occurred reading it, if any.
+type socketRecord struct {
+	raw        string
+	err        error
+	connection Connection
+}
+
+// NewSocketInput creates an instance of SocketInput listening on the address of the network, tcp, tcp4, tcp6 or
+// unix (see net.Listen).
+func NewSocketInput(network, address string) *SocketInput {
+	return &SocketInput{
+		network: network,
+		address: address,
+	}
+}
+
+// ParseSocketAddress parses the address a SocketInput listens on, either tcp://host:port, tcp4://, tcp6://, or
+// unix:///path/to/socket, returning the network and the address to pass to NewSocketInput. A host:port address
+// without scheme is a TCP one.
+func ParseSocketAddress(s string) (network, address string, err error) {
+	network, address = "tcp", s
+	if n := strings.Index(s, "://"); n >= 0 {
+		network, address = s[:n], s[n+3:]
+	}
+
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return "", "", fmt.Errorf("invalid listen address %q, must be host:port", s)
+		}
+	case "unix":
+		if address == "" {
+			return "", "", fmt.Errorf("invalid listen address %q, the socket path is missing", s)
+		}
+	default:
+		return "", "", fmt.Errorf("invalid listen address %q, valid schemes are tcp, tcp4, tcp6 and unix", s)
+	}
+
+	return network, address, nil
+}
+
+// Listen starts listening and accepting connections, Next calls it when it is not called before.
+//
+// Returns any error that occurred.
+func (i *SocketInput) Listen() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.closed {
+		return errSocketInputClosed
+	}
+
+	if i.listener != nil {
+		return nil
+	}
+
+	l, err := net.Listen(i.network, i.address)
+	if err != nil {
+		return err
+	}
+
+	i.listener = l
+	i.records = make(chan socketRecord)
+	i.done = make(chan struct{})
+	i.conns = make(map[net.Conn]struct{})
+
+	i.wg.Add(1)
+
+	go i.accept(l)
+
+	return nil
+}
+
+// Addr returns the address listened on, i.e. the port chosen when listening on port 0, nil when not listening.
+func (i *SocketInput) Addr() net.Addr {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.listener == nil {
+		return nil
+	}
+
+	return i.listener.Addr()
+}
+
+// accept accepts the connections until the listener is closed, each one being read in its own go routine.
+func (i *SocketInput) accept(l net.Listener) {
+	defer i.wg.Done()
+
+	var slots chan struct{}
+	if i.maxConnections > 0 {
+		slots = make(chan struct{}, i.maxConnections)
+	}
+
+	for {
+		if slots != nil {
+			// the connections beyond the max wait in the backlog of the listener.
+			select {
+			case slots <- struct{}{}:
+			case <-i.done:
+				return
+			}
+		}
+
+		conn, err := l.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				// i.e. too many open files, accepting is retried after a while.
+				time.Sleep(100 * time.Millisecond)
+
+				if slots != nil {
+					<-slots
+				}
+
+				continue
+			}
+
+			select {
+			case <-i.done:
+			case i.records <- socketRecord{err: err}:
+			}
+
+			return
+		}
+
+		id, ok := i.track(conn)
+		if !ok {
+			return
+		}
+
+		go i.read(conn, id, slots)
+	}
+}
+
+// track adds the connection to the ones to close along with the input, returning its ID. False when the input is
+// already closed.
+func (i *SocketInput) track(conn net.Conn) (int64, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.closed {
+		_ = conn.Close() // nolint:errcheck
+
+		return 0, false
+	}
+
+	i.lastID++
+	i.conns[conn] = struct{}{}
+	i.wg.Add(1)
+
+	return i.lastID, true
+}
+
+// read reads the records of the connection until it is closed, each record being handed to Next.
+func (i *SocketInput) read(conn net.Conn, id int64, slots chan struct{}) {
+	defer func() {
+		i.mu.Lock()
+		delete(i.conns, conn)
+		i.mu.Unlock()
+
+		_ = conn.Close() // nolint:errcheck
+
+		if slots != nil {
+			<-slots
+		}
+
+		i.wg.Done()
+	}()
+
+	connection := Connection{ID: id}
+
+	if addr := conn.RemoteAddr(); addr != nil {
+		connection.RemoteAddr = addr.String()
+	}
+
+	var reader recordReader = NewRecordReader(conn).WithMaxRecordSize(i.maxRecordSize)
+	if i.framing != NoFraming {
+		reader = NewFrameReader(conn).
+			WithFraming(i.framing).
+			WithMaxFrameSize(i.maxRecordSize)
+	}
+
+	for {
+		raw, err := reader.Read()
+		if _, ok := err.(*RecordTooLargeError); err != nil && !ok {
+			// the peer closed the connection, or the connection failed.
+			return
+		}
+
+		connection.Line++
+
+		select {
+		case <-i.done:
+			return
+		case i.records <- socketRecord{raw: raw, err: err, connection: connection}:
+		}
+	}
+}
+
+// Next returns the next record read from any of the connections. If unmarshalInput is set, the record will be
+// unmarshaled. Listening starts when it is called the first time, unless Listen was called before.
+//
+// Returns any error that occurred, including io.EOF once the input is closed, the context error when the context is
+// done, the listen or accept error and *InvalidRecordError when unmarshal the record fails or the
+// record exceeds the max record size (see RecordTooLargeError).
+func (i *SocketInput) Next(ctx context.Context) (interface{}, error) {
+	if err := i.Listen(); err != nil {
+		if err == errSocketInputClosed {
+			return nil, io.EOF
+		}
+
+		return nil, err
+	}
+
+	var rec socketRecord
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-i.done:
+		return nil, io.EOF
+	case rec = <-i.records:
+	}
+
+	if _, ok := rec.err.(*RecordTooLargeError); rec.err != nil && !ok {
+		return nil, rec.err
+	}
+
+	i.raw = rec.raw
+	i.connection = rec.connection
+
+	if rec.err != nil {
+		return nil, &InvalidRecordError{Err: rec.err}
+	}
+
+	if i.unmarshalInput == nil {
+		return i.raw, nil
+	}
+
+	r, err := i.unmarshalInput(ctx, i.raw)
+	if err != nil {
+		return nil, &InvalidRecordError{Err: err}
+	}
+
+	if m, ok := r.(map[string]interface{}); ok {
+		if i.addrKey != "" {
+			m[i.addrKey] = i.connection.RemoteAddr
+		}
+
+		if i.connectionKey != "" {
+			m[i.connectionKey] = i.connection.ID
+		}
+	}
+
+	return r, nil
+}
+
+// Raw returns the last record returned by Next as it was read from the connection.
+func (i *SocketInput) Raw() string {
+	return i.raw
+}
+
+// Connection returns the connection the last record returned by Next was read from.
+func (i *SocketInput) Connection() Connection {
+	return i.connection
+}
+
+// Close stops listening and closes the connections, the records read but not returned by Next are discarded.
+//
+// Returns any error that occurred closing the listener.
+func (i *SocketInput) Close() error {
+	i.mu.Lock()
+
+	if i.closed {
+		i.mu.Unlock()
+
+		return nil
+	}
+
+	i.closed = true
+
+	if i.listener == nil {
+		i.mu.Unlock()
+
+		return nil
+	}
+
+	close(i.done)
+
+	err := i.listener.Close()
+
+	for conn := range i.conns {
+		_ = conn.Close() // nolint:errcheck
+	}
+
+	i.mu.Unlock()
+
+	i.wg.Wait()
+
+	return err
+}
+
+// WithUnmarshaling set UnmarshalInput func into SocketInput.
+func (i *SocketInput) WithUnmarshaling(unmarshalInput UnmarshalInput) *SocketInput {
+	i.unmarshalInput = unmarshalInput
+
+	return i
+}
+
+// WithMaxRecordSize set the max size in bytes of the records read into SocketInput, the records exceeding it are
+// skipped. DefaultMaxRecordSize when not set.
+func (i *SocketInput) WithMaxRecordSize(maxRecordSize int) *SocketInput {
+	i.maxRecordSize = maxRecordSize
+
+	return i
+}
+
+// WithFraming set the Framing of the records into SocketInput, Uint32Framing or VarintFraming to read the
+// connections as binary streams of length prefixed records instead of one record per line (see FrameReader).
+func (i *SocketInput) WithFraming(framing Framing) *SocketInput {
+	i.framing = framing
+
+	return i
+}
+
+// WithMaxConnections set the max amount of connections read at the same time into SocketInput, the following ones
+// waiting to be accepted until a connection is closed. Zero means no limit.
+func (i *SocketInput) WithMaxConnections(maxConnections int) *SocketInput {
+	i.maxConnections = maxConnections
+
+	return i
+}
+
+// WithConnectionAnnotation sets the keys the remote address and the ID of the connection are added under to the
+// records unmarshaled as map[string]interface{}. An empty key is not added.
+func (i *SocketInput) WithConnectionAnnotation(addrKey, connectionKey string) *SocketInput {
+	i.addrKey = addrKey
+	i.connectionKey = connectionKey
+
+	return i
+}
@@ -0,0 +1,259 @@
+package io_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"github.com/stretchr/testify/assert"
+)
+
+// dial connects to the address the input listens on and writes the data.
+func dial(t *testing.T, input *sakio.SocketInput, data string) net.Conn {
+	t.Helper()
+
+	addr := input.Addr()
+
+	conn, err := net.Dial(addr.Network(), addr.String())
+	assert.NoError(t, err)
+
+	_, err = io.WriteString(conn, data)
+	assert.NoError(t, err)
+
+	return conn
+}
+
+func TestSocketInputNext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket-input")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	testCases := []struct {
+		scenario string
+		network  string
+		address  string
+	}{
+		{
+			scenario: "TCP",
+			network:  "tcp",
+			address:  "127.0.0.1:0",
+		},
+		{
+			scenario: "Unix socket",
+			network:  "unix",
+			address:  filepath.Join(dir, "sak.sock"),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			ctx := context.TODO()
+
+			input := sakio.NewSocketInput(tc.network, tc.address).
+				WithUnmarshaling(func(_ context.Context, i string) (interface{}, error) {
+					var v interface{}
+					err := json.Unmarshal([]byte(i), &v)
+
+					return v, err
+				}).
+				WithConnectionAnnotation("", "_connection")
+			assert.NoError(t, input.Listen())
+
+			a := dial(t, input, "{\"id\":1}\n{\"id\":2}\n")
+			defer a.Close() // nolint:errcheck
+
+			r, err := input.Next(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{"id": float64(1), "_connection": int64(1)}, r)
+			assert.Equal(t, `{"id":1}`, input.Raw())
+
+			b := dial(t, input, "{\"id\":3}\nnot json\n")
+			defer b.Close() // nolint:errcheck
+
+			var lines []string
+
+			for n := 0; n < 3; n++ {
+				r, err := input.Next(ctx)
+				if err != nil {
+					_, ok := err.(*sakio.InvalidRecordError)
+					assert.True(t, ok)
+				} else {
+					assert.Equal(t, input.Connection().ID, r.(map[string]interface{})["_connection"])
+				}
+
+				c := input.Connection()
+				lines = append(lines, fmt.Sprintf("%d:%d %s", c.ID, c.Line, input.Raw()))
+			}
+
+			sort.Strings(lines)
+			assert.Equal(t, []string{`1:2 {"id":2}`, `2:1 {"id":3}`, `2:2 not json`}, lines)
+
+			assert.NoError(t, input.Close())
+			assert.NoError(t, input.Close())
+
+			_, err = input.Next(ctx)
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestSocketInputNextRecordTooLarge(t *testing.T) {
+	ctx := context.TODO()
+
+	input := sakio.NewSocketInput("tcp", "127.0.0.1:0").
+		WithMaxRecordSize(4)
+	assert.NoError(t, input.Listen())
+
+	defer input.Close() // nolint:errcheck
+
+	conn := dial(t, input, "123456\n12\n")
+	defer conn.Close() // nolint:errcheck
+
+	_, err := input.Next(ctx)
+	assert.IsType(t, &sakio.InvalidRecordError{}, err)
+	assert.Equal(t, "1234", input.Raw())
+
+	r, err := input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "12", r)
+	assert.Equal(t, sakio.Connection{ID: 1, RemoteAddr: conn.LocalAddr().String(), Line: 2}, input.Connection())
+}
+
+func TestSocketInputNextMaxConnections(t *testing.T) {
+	ctx := context.TODO()
+
+	input := sakio.NewSocketInput("tcp", "127.0.0.1:0").
+		WithMaxConnections(1)
+	assert.NoError(t, input.Listen())
+
+	defer input.Close() // nolint:errcheck
+
+	a := dial(t, input, "a\n")
+	b := dial(t, input, "b\n")
+
+	defer b.Close() // nolint:errcheck
+
+	r, err := input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", r)
+
+	// the second connection waits until the first one is closed.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+
+	_, err = input.Next(timeoutCtx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	assert.NoError(t, a.Close())
+
+	r, err = input.Next(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", r)
+	assert.Equal(t, int64(2), input.Connection().ID)
+}
+
+func TestSocketInputCloseBlocked(t *testing.T) {
+	input := sakio.NewSocketInput("tcp", "127.0.0.1:0")
+	assert.NoError(t, input.Listen())
+
+	// the connection is not read further while the records are not taken by Next.
+	conn := dial(t, input, strings.Repeat("{\"id\":1}\n", 1000))
+	defer conn.Close() // nolint:errcheck
+
+	_, err := input.Next(context.TODO())
+	assert.NoError(t, err)
+
+	done := make(chan error)
+
+	go func() {
+		done <- input.Close()
+	}()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("close blocked by the connection")
+	}
+}
+
+func TestSocketInputListenError(t *testing.T) {
+	input := sakio.NewSocketInput("udp", "127.0.0.1:0")
+
+	_, err := input.Next(context.TODO())
+	assert.Error(t, err)
+	assert.Nil(t, input.Addr())
+	assert.NoError(t, input.Close())
+}
+
+func TestParseSocketAddress(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		s        string
+		network  string
+		address  string
+		err      string
+	}{
+		{
+			scenario: "TCP",
+			s:        "tcp://127.0.0.1:7070",
+			network:  "tcp",
+			address:  "127.0.0.1:7070",
+		},
+		{
+			scenario: "Without scheme",
+			s:        ":7070",
+			network:  "tcp",
+			address:  ":7070",
+		},
+		{
+			scenario: "Unix socket",
+			s:        "unix:///tmp/sak.sock",
+			network:  "unix",
+			address:  "/tmp/sak.sock",
+		},
+		{
+			scenario: "Without port",
+			s:        "tcp6://localhost",
+			err:      `invalid listen address "tcp6://localhost", must be host:port`,
+		},
+		{
+			scenario: "Without socket path",
+			s:        "unix://",
+			err:      `invalid listen address "unix://", the socket path is missing`,
+		},
+		{
+			scenario: "Unknown scheme",
+			s:        "udp://:7070",
+			err:      `invalid listen address "udp://:7070", valid schemes are tcp, tcp4, tcp6 and unix`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			network, address, err := sakio.ParseSocketAddress(tc.s)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.network, network)
+			assert.Equal(t, tc.address, address)
+		})
+	}
+}
@@ -2,7 +2,6 @@ package swissarmyknife
 
 import (
 	"context"
-	"fmt"
 )
 
 // Operation apply logic (decorate/filter/modify) to the input data.
@@ -10,7 +9,9 @@ import (
 type Operation func(ctx context.Context, value interface{}) (interface{}, error)
 
 type (
-	// Key represent a key name
+	// Key represent a key name, or a path to a key nested in maps and arrays using dot notation for map keys and
+	// brackets for array indices, i.e. `driver.location.lat` or `stops[0].id`. Dots, brackets and backslashes
+	// being part of a key name must be escaped with a backslash, i.e. `created\.at`.
 	Key string
 	// Value type is string intentionally. Anyway it has to be string during comparison.
 	Value string
@@ -33,10 +34,12 @@ type PairKeyValue struct {
 // NewFilteringOperation creates a filtering Operation based on pairs.
 // The PairKeyValue is used as a criteria to filter out the value. In case of using multiple PairKeyValue, it behave as an AND.
 // All the PairKeyValue must be in the value otherwise does not match.
+// It is the same as NewCriteriaFilteringOperation with the Equal criterion of every PairKeyValue combined with And.
 //
 // Accepts only value as a map[string]interface{} type.
 //
 // ErrTypeMismatch is returned if casting value interface{} to a map[string]interface{} fails.
+// ErrInvalidKeyPath is returned if any Key is not a valid path.
 // ErrDoNotEmit is returned when all PairKeyValue matched, allowing the value to be skipped.
 // value is returned when one PairKeyValue do not match, value will be emit.
 //
@@ -52,26 +55,85 @@ type PairKeyValue struct {
 //			},
 // 		)
 //
-func NewFilteringOperation(_ context.Context, pairs []PairKeyValue) Operation {
+func NewFilteringOperation(ctx context.Context, pairs []PairKeyValue) Operation {
+	criteria := make([]Criteria, len(pairs))
+
+	for i, pair := range pairs {
+		c, err := NewCriterion(pair.Key, Equal, pair.Value)
+		if err != nil {
+			return func(ctx context.Context, value interface{}) (interface{}, error) {
+				return nil, err
+			}
+		}
+
+		criteria[i] = c
+	}
+
+	return NewCriteriaFilteringOperation(ctx, And(criteria...))
+}
+
+// NewCriteriaFilteringOperation creates a filtering Operation based on criteria.
+// The Criteria is used to filter out the value, the values matching the criteria are skipped.
+//
+// Accepts only value as a map[string]interface{} type.
+//
+// ErrTypeMismatch is returned if casting value interface{} to a map[string]interface{} fails.
+// ErrDoNotEmit is returned when the criteria matched, allowing the value to be skipped.
+// value is returned when the criteria do not match, value will be emit.
+//
+// Common initialization example:
+//
+//      criteria, err := ParseCriteria("speed > 100 | status in [stopped,parked]")
+//      if err != nil {
+//			return err
+//		}
+//
+//      operation := NewCriteriaFilteringOperation(context.TODO(), criteria)
+//
+func NewCriteriaFilteringOperation(_ context.Context, criteria Criteria) Operation {
 	return func(ctx context.Context, value interface{}) (interface{}, error) {
 		m, ok := value.(map[string]interface{})
 		if !ok {
 			return nil, ErrTypeMismatch
 		}
 
-		match := true
-		for _, pair := range pairs {
-			// comparison is done using string (used fmt.Sprint) to avoid untyped constant
-			// which is the type to which the value is implicitly converted
-			if fmt.Sprint(m[pair.Key.String()]) != pair.Value.String() {
-				match = false
+		if criteria.Match(m) {
+			// criteria matched, value must be skipped
+			return nil, ErrDoNotEmit
+		}
 
-				break
-			}
+		return value, nil
+	}
+}
+
+// NewSelectOperation creates a selecting Operation based on criteria, the counterpart of
+// NewCriteriaFilteringOperation. The Criteria is used to select the value, only the values matching the criteria
+// are kept.
+//
+// Accepts only value as a map[string]interface{} type.
+//
+// ErrTypeMismatch is returned if casting value interface{} to a map[string]interface{} fails.
+// ErrDoNotEmit is returned when the criteria do not match, allowing the value to be skipped.
+// value is returned when the criteria matched, value will be emit.
+//
+// Common initialization example:
+//
+//      criteria, err := NewCriterion("id", Equal, "347")
+//      if err != nil {
+//			return err
+//		}
+//
+//      operation := NewSelectOperation(context.TODO(), criteria)
+//
+func NewSelectOperation(_ context.Context, criteria Criteria) Operation {
+	return func(ctx context.Context, value interface{}) (interface{}, error) {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, ErrTypeMismatch
 		}
 
-		if match {
-			// PairKeyValue matched, value must be skipped
+		if !criteria.Match(m) {
+			// criteria did not match, value must be skipped
 			return nil, ErrDoNotEmit
 		}
 
@@ -82,11 +144,13 @@ func NewFilteringOperation(_ context.Context, pairs []PairKeyValue) Operation {
 // NewAppendInformationOperation creates an append information Operation based on pairs.
 // The PairKeyValue is used to add an extra information to the value or replacing information, depending
 // if the key exists or not.
+// The maps missing in the Key path are created.
 //
 // Accepts only value as a map[string]interface{} type.
 //
 // ErrTypeMismatch is returned if casting value interface{} to a map[string]interface{} fails.
-// value is returned with all PairKeyValue appended.
+// ErrInvalidKeyPath is returned if any Key is not a valid path.
+// value is returned with all PairKeyValue appended, none of them is appended if any fails.
 //
 // Common initialization example:
 //
@@ -101,14 +165,32 @@ func NewFilteringOperation(_ context.Context, pairs []PairKeyValue) Operation {
 // 		)
 //
 func NewAppendInformationOperation(_ context.Context, pairs []PairKeyValue) Operation {
+	paths, pathErr := pairKeyValuePaths(pairs)
+	if pathErr == nil {
+		pathErr = checkNestedPaths(paths)
+	}
+
 	return func(ctx context.Context, value interface{}) (interface{}, error) {
+		if pathErr != nil {
+			return nil, pathErr
+		}
+
 		m, ok := value.(map[string]interface{})
 		if !ok {
 			return nil, ErrTypeMismatch
 		}
 
-		for _, pair := range pairs {
-			m[pair.Key.String()] = pair.Value.String()
+		// every path is checked before setting any value, not to leave the value partly updated.
+		for _, path := range paths {
+			if err := checkSetPath(m, path); err != nil {
+				return nil, err
+			}
+		}
+
+		for i, pair := range pairs {
+			if _, err := setPath(m, paths[i], pair.Value.String()); err != nil {
+				return nil, err
+			}
 		}
 
 		return m, nil
@@ -121,6 +203,7 @@ func NewAppendInformationOperation(_ context.Context, pairs []PairKeyValue) Oper
 // Accepts only value as a map[string]interface{} type.
 //
 // ErrTypeMismatch is returned if casting value interface{} to a map[string]interface{} fails.
+// ErrInvalidKeyPath is returned if any Key is not a valid path.
 // value is returned with all Key removed.
 //
 // Common initialization example:
@@ -131,14 +214,20 @@ func NewAppendInformationOperation(_ context.Context, pairs []PairKeyValue) Oper
 // 		)
 //
 func NewRemoveInformationOperation(_ context.Context, keys []Key) Operation {
+	paths, pathErr := keyPaths(keys)
+
 	return func(ctx context.Context, value interface{}) (interface{}, error) {
+		if pathErr != nil {
+			return nil, pathErr
+		}
+
 		m, ok := value.(map[string]interface{})
 		if !ok {
 			return nil, ErrTypeMismatch
 		}
 
-		for _, key := range keys {
-			delete(m, key.String())
+		for _, path := range paths {
+			deletePath(m, path)
 		}
 
 		return m, nil
@@ -157,6 +246,7 @@ type PairKeyPrefix struct {
 // Accepts only value as a map[string]interface{} type.
 //
 // ErrTypeMismatch is returned if casting value interface{} to a map[string]interface{} fails.
+// ErrInvalidKeyPath is returned if any Key is not a valid path or is an array element.
 // value is returned with all Key prefixed.
 //
 // Common initialization example:
@@ -172,21 +262,92 @@ type PairKeyPrefix struct {
 // 		)
 //
 func NewPrefixKeyOperation(_ context.Context, pairs []PairKeyPrefix) Operation {
+	keys := make([]Key, len(pairs))
+	for i, pair := range pairs {
+		keys[i] = pair.Key
+	}
+
+	paths, pathErr := keyPaths(keys)
+	if pathErr == nil {
+		for _, path := range paths {
+			// array elements have no key to prefix.
+			if path[len(path)-1].isIndex {
+				pathErr = ErrInvalidKeyPath
+
+				break
+			}
+		}
+	}
+
 	return func(ctx context.Context, value interface{}) (interface{}, error) {
+		if pathErr != nil {
+			return nil, pathErr
+		}
+
 		m, ok := value.(map[string]interface{})
 		if !ok {
 			return nil, ErrTypeMismatch
 		}
 
-		for _, pair := range pairs {
-			if v, ok := m[pair.Key.String()]; ok {
-				delete(m, pair.Key.String())
+		for i, pair := range pairs {
+			path := paths[i]
+			last := path[len(path)-1]
+
+			// the parent of the key being found, prefixing it does not fail.
+			if v, ok := getPath(m, path); ok {
+				deletePath(m, path)
 
-				k := pair.Prefix + pair.Key.String()
-				m[k] = v
+				prefixed := append(append([]pathSegment{}, path[:len(path)-1]...), pathSegment{name: pair.Prefix + last.name})
+				if _, err := setPath(m, prefixed, v); err != nil {
+					return nil, err
+				}
 			}
 		}
 
 		return m, nil
 	}
 }
+
+// keyPaths returns the paths of the keys.
+//
+// ErrInvalidKeyPath is returned if any of the keys is not a valid path.
+func keyPaths(keys []Key) ([][]pathSegment, error) {
+	paths := make([][]pathSegment, len(keys))
+
+	for i, key := range keys {
+		path, err := key.path()
+		if err != nil {
+			return nil, err
+		}
+
+		paths[i] = path
+	}
+
+	return paths, nil
+}
+
+// checkNestedPaths returns ErrTypeMismatch if a value is set into a path nested in the path of a value set before,
+// that is not a map or an array.
+func checkNestedPaths(paths [][]pathSegment) error {
+	for i, path := range paths {
+		for _, other := range paths[i+1:] {
+			if isPathPrefix(path, other) {
+				return ErrTypeMismatch
+			}
+		}
+	}
+
+	return nil
+}
+
+// pairKeyValuePaths returns the paths of the keys of the pairs.
+//
+// ErrInvalidKeyPath is returned if any of the keys is not a valid path.
+func pairKeyValuePaths(pairs []PairKeyValue) ([][]pathSegment, error) {
+	keys := make([]Key, len(pairs))
+	for i, pair := range pairs {
+		keys[i] = pair.Key
+	}
+
+	return keyPaths(keys)
+}
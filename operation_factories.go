@@ -0,0 +1,154 @@
+package swissarmyknife
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CriteriaConfig is the configuration of the filter and select operations.
+type CriteriaConfig struct {
+	Criteria string `config:"criteria,required" description:"Criteria the records are matched against, i.e. 'speed > 100 | status in [stopped,parked]'."`
+}
+
+// ExpressionConfig is the configuration of the where and set operations.
+type ExpressionConfig struct {
+	Expression string `config:"expression,required" description:"Expression evaluated against the records, i.e. 'distance_km = dist_m / 1000'."`
+}
+
+// PairsConfig is the configuration of the append and prefix operations.
+type PairsConfig struct {
+	Pairs [][2]string `config:"pairs,required" description:"Key/value pairs, keys being paths, applied in order, i.e. {driver.city: paris}."`
+}
+
+// KeysConfig is the configuration of the remove operation.
+type KeysConfig struct {
+	Keys []string `config:"keys,required" description:"Keys, being paths, i.e. [id, stops[0].id]."`
+}
+
+// builtinOperations are the operations registered in the DefaultOperationRegistry.
+// nolint:gochecknoglobals
+var builtinOperations = []OperationFactory{
+	{
+		Name:        "filter",
+		Description: "Drops the records matching the criteria.",
+		Config:      CriteriaConfig{},
+		New: func(ctx context.Context, config interface{}) (Operation, error) {
+			criteria, err := ParseCriteria(config.(*CriteriaConfig).Criteria)
+			if err != nil {
+				return nil, ParamError("criteria", err)
+			}
+
+			return NewCriteriaFilteringOperation(ctx, criteria), nil
+		},
+	},
+	{
+		Name:        "select",
+		Description: "Keeps only the records matching the criteria.",
+		Config:      CriteriaConfig{},
+		New: func(ctx context.Context, config interface{}) (Operation, error) {
+			criteria, err := ParseCriteria(config.(*CriteriaConfig).Criteria)
+			if err != nil {
+				return nil, ParamError("criteria", err)
+			}
+
+			return NewSelectOperation(ctx, criteria), nil
+		},
+	},
+	{
+		Name:        "where",
+		Description: "Keeps only the records the predicate expression is true for.",
+		Config:      ExpressionConfig{},
+		New: func(ctx context.Context, config interface{}) (Operation, error) {
+			expression, err := ParseExpression(config.(*ExpressionConfig).Expression)
+			if err != nil {
+				return nil, ParamError("expression", err)
+			}
+
+			if !expression.IsPredicate() {
+				return nil, ParamError("expression", errors.New("assignments are not allowed in where, use set"))
+			}
+
+			return NewExpressionOperation(ctx, expression), nil
+		},
+	},
+	{
+		Name:        "set",
+		Description: "Sets keys to the value of the expression assignments.",
+		Config:      ExpressionConfig{},
+		New: func(ctx context.Context, config interface{}) (Operation, error) {
+			expression, err := ParseExpression(config.(*ExpressionConfig).Expression)
+			if err != nil {
+				return nil, ParamError("expression", err)
+			}
+
+			if !expression.IsAssignment() {
+				return nil, ParamError("expression", errors.New("only assignments are allowed in set, use where"))
+			}
+
+			return NewExpressionOperation(ctx, expression), nil
+		},
+	},
+	{
+		Name:        "append",
+		Description: "Appends the key/value pairs, replacing the values of the existing keys.",
+		Config:      PairsConfig{},
+		New: func(ctx context.Context, config interface{}) (Operation, error) {
+			var pairs []PairKeyValue
+
+			for _, pair := range config.(*PairsConfig).Pairs {
+				if err := Key(pair[0]).Validate(); err != nil {
+					return nil, ParamError("pairs", fmt.Errorf("%s %q", err, pair[0]))
+				}
+
+				pairs = append(pairs, PairKeyValue{Key: Key(pair[0]), Value: Value(pair[1])})
+			}
+
+			return NewAppendInformationOperation(ctx, pairs), nil
+		},
+	},
+	{
+		Name:        "remove",
+		Description: "Removes the keys.",
+		Config:      KeysConfig{},
+		New: func(ctx context.Context, config interface{}) (Operation, error) {
+			var keys []Key
+
+			for _, key := range config.(*KeysConfig).Keys {
+				if err := Key(key).Validate(); err != nil {
+					return nil, ParamError("keys", fmt.Errorf("%s %q", err, key))
+				}
+
+				keys = append(keys, Key(key))
+			}
+
+			return NewRemoveInformationOperation(ctx, keys), nil
+		},
+	},
+	{
+		Name:        "prefix",
+		Description: "Prefixes the keys, pairs being key/prefix.",
+		Config:      PairsConfig{},
+		New: func(ctx context.Context, config interface{}) (Operation, error) {
+			var pairs []PairKeyPrefix
+
+			for _, pair := range config.(*PairsConfig).Pairs {
+				if err := Key(pair[0]).Validate(); err != nil {
+					return nil, ParamError("pairs", fmt.Errorf("%s %q", err, pair[0]))
+				}
+
+				pairs = append(pairs, PairKeyPrefix{Key: Key(pair[0]), Prefix: pair[1]})
+			}
+
+			return NewPrefixKeyOperation(ctx, pairs), nil
+		},
+	},
+}
+
+func init() {
+	for _, f := range builtinOperations {
+		if err := RegisterOperation(f); err != nil {
+			panic(err)
+		}
+	}
+}
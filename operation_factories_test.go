@@ -0,0 +1,116 @@
+package swissarmyknife_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	swiss_army_knife "github.com/dohernandez/swiss-army-knife"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltinOperations(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		name     string
+		params   map[string]interface{}
+		result   string
+	}{
+		{
+			scenario: "filter",
+			name:     "filter",
+			params:   map[string]interface{}{"criteria": "id:1629"},
+		},
+		{
+			scenario: "select",
+			name:     "select",
+			params:   map[string]interface{}{"criteria": "id:1629"},
+			result:   `{"id":1629,"lat":48.8,"driver":{"city":"paris"}}`,
+		},
+		{
+			scenario: "where",
+			name:     "where",
+			params:   map[string]interface{}{"expression": "lat > 50"},
+		},
+		{
+			scenario: "set",
+			name:     "set",
+			params:   map[string]interface{}{"expression": "lat = round(lat)"},
+			result:   `{"id":1629,"lat":49,"driver":{"city":"paris"}}`,
+		},
+		{
+			scenario: "append",
+			name:     "append",
+			params:   map[string]interface{}{"pairs": map[string]interface{}{"source": "gps", "driver.id": 347}},
+			result:   `{"id":1629,"lat":48.8,"source":"gps","driver":{"city":"paris","id":"347"}}`,
+		},
+		{
+			scenario: "append in order",
+			name:     "append",
+			params:   map[string]interface{}{"pairs": [][2]string{{"driver.id", "347"}, {"driver", "unknown"}}},
+			result:   `{"id":1629,"lat":48.8,"driver":"unknown"}`,
+		},
+		{
+			scenario: "remove",
+			name:     "remove",
+			params:   map[string]interface{}{"keys": []interface{}{"lat", "driver.city"}},
+			result:   `{"id":1629,"driver":{}}`,
+		},
+		{
+			scenario: "prefix",
+			name:     "prefix",
+			params:   map[string]interface{}{"pairs": map[string]interface{}{"lat": "c_", "driver.city": "_"}},
+			result:   `{"id":1629,"c_lat":48.8,"driver":{"_city":"paris"}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			var value interface{}
+
+			err := json.Unmarshal([]byte(`{"id":1629,"lat":48.8,"driver":{"city":"paris"}}`), &value)
+			assert.NoError(t, err)
+
+			ctx := context.TODO()
+
+			op, err := swiss_army_knife.NewRegisteredOperation(ctx, tc.name, tc.params)
+			assert.NoError(t, err)
+
+			r, err := op(ctx, value)
+			if tc.result == "" {
+				assert.EqualError(t, err, swiss_army_knife.ErrDoNotEmit.Error())
+			} else {
+				var result interface{}
+
+				assert.NoError(t, json.Unmarshal([]byte(tc.result), &result))
+				assert.NoError(t, err)
+				assert.Equal(t, result, r)
+			}
+		})
+	}
+}
+
+func TestBuiltinOperationsInvalid(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		name     string
+		params   map[string]interface{}
+		err      string
+	}{
+		{scenario: "filter", name: "filter", params: map[string]interface{}{"criteria": "id"}, err: `filter: parameter "criteria": invalid criteria: missing operator at offset 2`},
+		{scenario: "where", name: "where", params: map[string]interface{}{"expression": "a = 1"}, err: `where: parameter "expression": assignments are not allowed in where, use set`},
+		{scenario: "set", name: "set", params: map[string]interface{}{"expression": "a"}, err: `set: parameter "expression": only assignments are allowed in set, use where`},
+		{scenario: "append", name: "append", params: map[string]interface{}{"pairs": map[string]interface{}{"a.": "1"}}, err: `append: parameter "pairs": invalid key path "a."`},
+		{scenario: "append duplicate key", name: "append", params: map[string]interface{}{"pairs": []swiss_army_knife.KeyValue{{Key: "a", Value: 1}, {Key: "a", Value: 2}}}, err: `append: parameter "pairs": duplicate key "a"`},
+		{scenario: "remove", name: "remove", params: map[string]interface{}{"keys": []interface{}{"[0]"}}, err: `remove: parameter "keys": invalid key path "[0]"`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			_, err := swiss_army_knife.NewRegisteredOperation(context.TODO(), tc.name, tc.params)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
@@ -14,7 +14,7 @@ func TestFilteringOperation(t *testing.T) {
 	var value interface{}
 
 	// The value is already Unmarshal to make easy the test
-	err := json.Unmarshal([]byte(`{"id":1629,"lat":48.83168740132889,"lng":2.2485795413465577,"created_at":"2016-12-14 18:48:11"}`), &value)
+	err := json.Unmarshal([]byte(`{"id":1629,"code":"007","lat":48.83168740132889,"lng":2.2485795413465577,"created_at":"2016-12-14 18:48:11"}`), &value)
 	assert.NoError(t, err)
 
 	testCases := []struct {
@@ -68,6 +68,25 @@ func TestFilteringOperation(t *testing.T) {
 			},
 			result: value.(map[string]interface{}),
 		},
+		{
+			scenario: "Operation filter out by key/value pair matching the string exactly",
+			pairs: []swiss_army_knife.PairKeyValue{
+				{
+					Key:   "code",
+					Value: "007",
+				},
+			},
+		},
+		{
+			scenario: "Operation do not filter out by key/value pair being the number held by a string",
+			pairs: []swiss_army_knife.PairKeyValue{
+				{
+					Key:   "code",
+					Value: "7",
+				},
+			},
+			result: value.(map[string]interface{}),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -88,6 +107,120 @@ func TestFilteringOperation(t *testing.T) {
 	}
 }
 
+func TestCriteriaFilteringOperation(t *testing.T) {
+	// An artificial value source.
+	var value interface{}
+
+	// The value is already Unmarshal to make easy the test
+	err := json.Unmarshal([]byte(`{"id":1629,"lat":48.83168740132889,"lng":2.2485795413465577,"created_at":"2016-12-14 18:48:11"}`), &value)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		scenario string
+		criteria string
+		result   map[string]interface{}
+	}{
+		{
+			scenario: "Operation filter out by comparison",
+			criteria: "lat > 48.5 ; lat < 49",
+		},
+		{
+			scenario: "Operation filter out by timestamp comparison",
+			criteria: "created_at >= 2016-12-14T18:00:00Z",
+		},
+		{
+			scenario: "Operation filter out by any criteria",
+			criteria: "id in [347,1874] | lng < 3",
+		},
+		{
+			scenario: "Operation do not filter out by negated criteria",
+			criteria: "!id:1629",
+			result:   value.(map[string]interface{}),
+		},
+		{
+			scenario: "Operation do not filter out by regex",
+			criteria: `created_at !~ "^2016-"`,
+			result:   value.(map[string]interface{}),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			ctx := context.TODO()
+
+			criteria, err := swiss_army_knife.ParseCriteria(tc.criteria)
+			assert.NoError(t, err)
+
+			operation := swiss_army_knife.NewCriteriaFilteringOperation(ctx, criteria)
+
+			r, err := operation(ctx, value)
+			if tc.result == nil {
+				assert.EqualError(t, err, swiss_army_knife.ErrDoNotEmit.Error())
+				assert.Empty(t, r)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, r)
+			}
+		})
+	}
+}
+
+func TestSelectOperation(t *testing.T) {
+	// An artificial value source.
+	var value interface{}
+
+	// The value is already Unmarshal to make easy the test
+	err := json.Unmarshal([]byte(`{"id":1629,"lat":48.83168740132889,"lng":2.2485795413465577,"created_at":"2016-12-14 18:48:11"}`), &value)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		scenario string
+		criteria string
+		result   map[string]interface{}
+	}{
+		{
+			scenario: "Operation select by key/value pair",
+			criteria: "id:1629",
+			result:   value.(map[string]interface{}),
+		},
+		{
+			scenario: "Operation select by any criteria",
+			criteria: "id in [347,1874] | lng < 3",
+			result:   value.(map[string]interface{}),
+		},
+		{
+			scenario: "Operation do not select by key/value pair",
+			criteria: "id:347",
+		},
+		{
+			scenario: "Operation do not select by missing key",
+			criteria: "driver.id exists",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			ctx := context.TODO()
+
+			criteria, err := swiss_army_knife.ParseCriteria(tc.criteria)
+			assert.NoError(t, err)
+
+			operation := swiss_army_knife.NewSelectOperation(ctx, criteria)
+
+			r, err := operation(ctx, value)
+			if tc.result == nil {
+				assert.EqualError(t, err, swiss_army_knife.ErrDoNotEmit.Error())
+				assert.Empty(t, r)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, r)
+			}
+		})
+	}
+}
+
 func TestAppendInformationOperation(t *testing.T) {
 	// An artificial value source.
 	var value interface{}
@@ -325,3 +458,141 @@ func TestPrefixKeyOperation(t *testing.T) {
 		})
 	}
 }
+
+func TestOperationsNestedKeyPath(t *testing.T) {
+	// An artificial value source.
+	const value = `{"id":1629,"driver":{"location":{"lat":48.83168740132889,"lng":2.2485795413465577}},"stops":[{"id":1},{"id":2}],"created.at":"2016-12-14 18:48:11"}`
+
+	testCases := []struct {
+		scenario  string
+		operation swiss_army_knife.Operation
+		result    string
+		err       error
+	}{
+		{
+			scenario: "Operation filter out by nested key/value pair",
+			operation: swiss_army_knife.NewFilteringOperation(context.TODO(), []swiss_army_knife.PairKeyValue{
+				{Key: "driver.location.lat", Value: "48.83168740132889"},
+				{Key: "stops[1].id", Value: "2"},
+			}),
+			err: swiss_army_knife.ErrDoNotEmit,
+		},
+		{
+			scenario: "Operation do not filter out by escaped key/value pair",
+			operation: swiss_army_knife.NewFilteringOperation(context.TODO(), []swiss_army_knife.PairKeyValue{
+				{Key: `created\.at`, Value: "2016-12-14 18:48:12"},
+			}),
+			result: value,
+		},
+		{
+			scenario: "Operation append nested information creating missing maps",
+			operation: swiss_army_knife.NewAppendInformationOperation(context.TODO(), []swiss_army_knife.PairKeyValue{
+				{Key: "driver.location.country", Value: "fr"},
+				{Key: "stops[0].name", Value: "Darien"},
+				{Key: "ride.status", Value: "done"},
+			}),
+			result: `{"id":1629,"driver":{"location":{"lat":48.83168740132889,"lng":2.2485795413465577,"country":"fr"}},"stops":[{"id":1,"name":"Darien"},{"id":2}],"created.at":"2016-12-14 18:48:11","ride":{"status":"done"}}`,
+		},
+		{
+			scenario: "Operation append nested information fails, array index out of range",
+			operation: swiss_army_knife.NewAppendInformationOperation(context.TODO(), []swiss_army_knife.PairKeyValue{
+				{Key: "stops[2].id", Value: "3"},
+			}),
+			err: swiss_army_knife.ErrInvalidKeyPath,
+		},
+		{
+			scenario: "Operation append nested information fails, value is not a map",
+			operation: swiss_army_knife.NewAppendInformationOperation(context.TODO(), []swiss_army_knife.PairKeyValue{
+				{Key: "id.value", Value: "3"},
+			}),
+			err: swiss_army_knife.ErrTypeMismatch,
+		},
+		{
+			scenario: "Operation append nested information fails leaving the value untouched",
+			operation: swiss_army_knife.NewAppendInformationOperation(context.TODO(), []swiss_army_knife.PairKeyValue{
+				{Key: "driver.location.country", Value: "fr"},
+				{Key: "stops[2].id", Value: "3"},
+			}),
+			err: swiss_army_knife.ErrInvalidKeyPath,
+		},
+		{
+			scenario: "Operation append nested information fails, value appended before is not a map",
+			operation: swiss_army_knife.NewAppendInformationOperation(context.TODO(), []swiss_army_knife.PairKeyValue{
+				{Key: "ride", Value: "done"},
+				{Key: "ride.status", Value: "done"},
+			}),
+			err: swiss_army_knife.ErrTypeMismatch,
+		},
+		{
+			scenario: "Operation remove nested information",
+			operation: swiss_army_knife.NewRemoveInformationOperation(context.TODO(), []swiss_army_knife.Key{
+				"driver.location.lng", "stops[0]", `created\.at`,
+			}),
+			result: `{"id":1629,"driver":{"location":{"lat":48.83168740132889}},"stops":[{"id":2}]}`,
+		},
+		{
+			scenario: "Operation prefix nested key",
+			operation: swiss_army_knife.NewPrefixKeyOperation(context.TODO(), []swiss_army_knife.PairKeyPrefix{
+				{Key: "driver.location.lat", Prefix: "c_"},
+				{Key: "stops[1].id", Prefix: "_"},
+			}),
+			result: `{"id":1629,"driver":{"location":{"c_lat":48.83168740132889,"lng":2.2485795413465577}},"stops":[{"id":1},{"_id":2}],"created.at":"2016-12-14 18:48:11"}`,
+		},
+		{
+			scenario: "Operation prefix nested key fails, array element has no key",
+			operation: swiss_army_knife.NewPrefixKeyOperation(context.TODO(), []swiss_army_knife.PairKeyPrefix{
+				{Key: "stops[1]", Prefix: "_"},
+			}),
+			err: swiss_army_knife.ErrInvalidKeyPath,
+		},
+		{
+			scenario: "Operation prefix nested key fails leaving the value untouched",
+			operation: swiss_army_knife.NewPrefixKeyOperation(context.TODO(), []swiss_army_knife.PairKeyPrefix{
+				{Key: "driver.location.lat", Prefix: "c_"},
+				{Key: "stops[1]", Prefix: "_"},
+			}),
+			err: swiss_army_knife.ErrInvalidKeyPath,
+		},
+		{
+			scenario: "Operation fails, invalid key path",
+			operation: swiss_army_knife.NewRemoveInformationOperation(context.TODO(), []swiss_army_knife.Key{
+				"stops[0",
+			}),
+			err: swiss_army_knife.ErrInvalidKeyPath,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			var v interface{}
+
+			err := json.Unmarshal([]byte(value), &v)
+			assert.NoError(t, err)
+
+			r, err := tc.operation(context.TODO(), v)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+				assert.Empty(t, r)
+
+				var original interface{}
+
+				err = json.Unmarshal([]byte(value), &original)
+				assert.NoError(t, err)
+
+				assert.Equal(t, original, v, "value must be left untouched")
+
+				return
+			}
+
+			assert.NoError(t, err)
+
+			var expected interface{}
+
+			err = json.Unmarshal([]byte(tc.result), &expected)
+			assert.NoError(t, err)
+
+			assert.Equal(t, expected, r)
+		})
+	}
+}
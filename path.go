@@ -0,0 +1,262 @@
+package swissarmyknife
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidKeyPath is returned when the Key is not a valid path.
+var ErrInvalidKeyPath = errors.New("invalid key path")
+
+// pathSegment is a step of a Key path, either a map key or an array index.
+type pathSegment struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+// path returns the segments of the Key path.
+//
+// A Key is a path to a value nested in maps and arrays, using dot notation for map keys and brackets for array
+// indices, i.e. `driver.location.lat` or `stops[0].id`. Dots, brackets and backslashes being part of a map key
+// must be escaped with a backslash, i.e. `created\.at`.
+//
+// ErrInvalidKeyPath is returned if the Key is not a valid path.
+func (k Key) path() ([]pathSegment, error) {
+	var segments []pathSegment
+
+	s := k.String()
+	i := 0
+
+	for {
+		// map key, until the next unescaped '.' or '['.
+		var name strings.Builder
+
+		for ; i < len(s) && s[i] != '.' && s[i] != '['; i++ {
+			switch s[i] {
+			case ']':
+				return nil, ErrInvalidKeyPath
+			case '\\':
+				i++
+
+				if i == len(s) {
+					return nil, ErrInvalidKeyPath
+				}
+			}
+
+			name.WriteByte(s[i])
+		}
+
+		if name.Len() == 0 {
+			return nil, ErrInvalidKeyPath
+		}
+
+		segments = append(segments, pathSegment{name: name.String()})
+
+		// array indices following the map key.
+		for i < len(s) && s[i] == '[' {
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, ErrInvalidKeyPath
+			}
+
+			index, err := strconv.Atoi(s[i+1 : i+end])
+			if err != nil || index < 0 {
+				return nil, ErrInvalidKeyPath
+			}
+
+			segments = append(segments, pathSegment{index: index, isIndex: true})
+
+			i += end + 1
+		}
+
+		if i == len(s) {
+			return segments, nil
+		}
+
+		if s[i] != '.' {
+			return nil, ErrInvalidKeyPath
+		}
+
+		i++
+	}
+}
+
+// Validate returns ErrInvalidKeyPath if the Key is not a valid path.
+func (k Key) Validate() error {
+	_, err := k.path()
+
+	return err
+}
+
+// getPath returns the value found following the path, false when it was not found.
+func getPath(v interface{}, path []pathSegment) (interface{}, bool) {
+	for _, seg := range path {
+		if seg.isIndex {
+			a, ok := v.([]interface{})
+			if !ok || seg.index >= len(a) {
+				return nil, false
+			}
+
+			v = a[seg.index]
+
+			continue
+		}
+
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		v, ok = m[seg.name]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// setPath sets the value following the path, creating the maps that are missing. Returns the container updated.
+//
+// ErrTypeMismatch is returned if a value in the path is not a map or an array as the path expects and
+// ErrInvalidKeyPath if an array index is out of range.
+func setPath(v interface{}, path []pathSegment, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	seg := path[0]
+
+	if seg.isIndex {
+		a, ok := v.([]interface{})
+		if !ok {
+			return nil, ErrTypeMismatch
+		}
+
+		if seg.index >= len(a) {
+			return nil, ErrInvalidKeyPath
+		}
+
+		r, err := setPath(a[seg.index], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+
+		a[seg.index] = r
+
+		return a, nil
+	}
+
+	if v == nil {
+		v = make(map[string]interface{})
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, ErrTypeMismatch
+	}
+
+	r, err := setPath(m[seg.name], path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+
+	m[seg.name] = r
+
+	return m, nil
+}
+
+// checkSetPath returns the error setPath would return setting a value following the path, without setting it.
+func checkSetPath(v interface{}, path []pathSegment) error {
+	for _, seg := range path {
+		if seg.isIndex {
+			a, ok := v.([]interface{})
+			if !ok {
+				return ErrTypeMismatch
+			}
+
+			if seg.index >= len(a) {
+				return ErrInvalidKeyPath
+			}
+
+			v = a[seg.index]
+
+			continue
+		}
+
+		// the missing maps are created.
+		if v == nil {
+			continue
+		}
+
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return ErrTypeMismatch
+		}
+
+		v = m[seg.name]
+	}
+
+	return nil
+}
+
+// isPathPrefix returns whether the path is a prefix of the other path, not being the same path.
+func isPathPrefix(path, other []pathSegment) bool {
+	if len(path) >= len(other) {
+		return false
+	}
+
+	for i, seg := range path {
+		if seg != other[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// deletePath removes the value found following the path, array elements are removed shifting the following ones.
+// Returns the container updated and false when the value was not found.
+func deletePath(v interface{}, path []pathSegment) (interface{}, bool) {
+	seg := path[0]
+
+	if seg.isIndex {
+		a, ok := v.([]interface{})
+		if !ok || seg.index >= len(a) {
+			return v, false
+		}
+
+		if len(path) == 1 {
+			return append(a[:seg.index], a[seg.index+1:]...), true
+		}
+
+		r, ok := deletePath(a[seg.index], path[1:])
+		a[seg.index] = r
+
+		return a, ok
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v, false
+	}
+
+	if len(path) == 1 {
+		_, ok := m[seg.name]
+		delete(m, seg.name)
+
+		return m, ok
+	}
+
+	nested, ok := m[seg.name]
+	if !ok {
+		return m, false
+	}
+
+	r, ok := deletePath(nested, path[1:])
+	m[seg.name] = r
+
+	return m, ok
+}
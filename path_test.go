@@ -0,0 +1,46 @@
+package swissarmyknife_test
+
+import (
+	"testing"
+
+	swiss_army_knife "github.com/dohernandez/swiss-army-knife"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyValidate(t *testing.T) {
+	testCases := []struct {
+		key   swiss_army_knife.Key
+		valid bool
+	}{
+		{key: "id", valid: true},
+		{key: "driver.location.lat", valid: true},
+		{key: "stops[0].id", valid: true},
+		{key: "stops[0][1]", valid: true},
+		{key: `created\.at`, valid: true},
+		{key: `driver\[0\]`, valid: true},
+		{key: `back\\slash`, valid: true},
+		{key: ""},
+		{key: "driver."},
+		{key: ".driver"},
+		{key: "driver..id"},
+		{key: "[0]"},
+		{key: "stops[0"},
+		{key: "stops[a]"},
+		{key: "stops[-1]"},
+		{key: "stops]"},
+		{key: "stops[0]id"},
+		{key: `trailing\`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.key.String(), func(t *testing.T) {
+			err := tc.key.Validate()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, swiss_army_knife.ErrInvalidKeyPath.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,701 @@
+// Package pipeline defines the pipeline definition file, declaring the input, the ordered list of operations with
+// their parameters and the output of a process, in YAML or JSON.
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	swissarmyknife "github.com/dohernandez/swiss-army-knife"
+	sakio "github.com/dohernandez/swiss-army-knife/io"
+	"gopkg.in/yaml.v3"
+)
+
+// Error is a pipeline definition error, reporting the line where it was found.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Errors are all the errors found in a pipeline definition.
+type Errors []*Error
+
+// Error returns the error messages, one per line.
+func (ee Errors) Error() string {
+	msgs := make([]string, len(ee))
+	for i, e := range ee {
+		msgs[i] = e.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Definition is a pipeline definition.
+//
+// Common definition example:
+//
+//      input:
+//        type: file
+//        paths: [resources/dump/*.json_dump.gz]
+//        annotate_source: true
+//      processor:
+//        workers: 4
+//        dead_letter: failed.ndjson
+//      operations:
+//        - type: filter
+//          criteria: "speed > 100 | status in [stopped,parked]"
+//        - type: set
+//          expression: "distance_km = dist_m / 1000"
+//        - type: remove
+//          keys: [dist_m]
+//      output:
+//        type: file
+//        path: out/%Y-%m-%d/part-{n}.ndjson
+//        max_size: 67108864
+//        rotation_interval: 1h
+//        compression: gzip
+//
+type Definition struct {
+	Input      InputDefinition       `yaml:"input"`
+	Processor  ProcessorDefinition   `yaml:"processor"`
+	Operations []OperationDefinition `yaml:"operations"`
+	Output     OutputDefinition      `yaml:"output"`
+}
+
+// InputDefinition declares the input of the pipeline.
+type InputDefinition struct {
+	// Type of the input, stdin (the default), file, socket or http.
+	Type string `yaml:"type"`
+	// Paths are the files, globs or directories read by the file input, in order.
+	Paths []string `yaml:"paths"`
+	// AnnotateSource adds the source file and line number to the records read by the file input.
+	AnnotateSource bool `yaml:"annotate_source"`
+	// Follow reads the single path of the file input as it grows, see sakio.FileInput.WithFollow.
+	Follow bool `yaml:"follow"`
+	// OffsetFile is the file the offset reached in the followed file is stored into, to resume from it.
+	OffsetFile string `yaml:"offset_file"`
+	// MaxRecordSize is the max size in bytes of the records read, the records exceeding it are skipped.
+	// Zero means sakio.DefaultMaxRecordSize.
+	MaxRecordSize int `yaml:"max_record_size"`
+	// Format of the records, ndjson (the default), json (see sakio.JSONRecordReader), csv or tsv (see
+	// sakio.CSVInput), only for stdin, msgpack, cbor or protobuf (see sakio.FrameReader) or avro (see
+	// sakio.AvroRecordReader).
+	Format string `yaml:"format"`
+	// JSONPointer points to the nested array whose elements are the records, with the json format.
+	JSONPointer string `yaml:"json_pointer"`
+	// Columns are the column names of the csv or tsv rows, taken from the header row when empty.
+	Columns []string `yaml:"columns"`
+	// InferTypes infers the type of the csv or tsv fields, otherwise they are strings.
+	InferTypes bool `yaml:"infer_types"`
+	// ProtoDescriptor is the FileDescriptorSet file declaring the ProtoMessage type, with the protobuf format.
+	ProtoDescriptor string `yaml:"proto_descriptor"`
+	// ProtoMessage is the full name of the message type of the records, with the protobuf format.
+	ProtoMessage string `yaml:"proto_message"`
+	// Listen is the address the socket input listens on, tcp://host:port or unix:///path (see
+	// sakio.ParseSocketAddress), or the endpoint the http input listens on, http://host:port/path.
+	Listen string `yaml:"listen"`
+	// AnnotateConnection adds the remote address and the connection ID to the records read by the socket input.
+	AnnotateConnection bool `yaml:"annotate_connection"`
+	// MaxConnections is the max amount of connections read at the same time by the socket input, zero means no limit.
+	MaxConnections int `yaml:"max_connections"`
+	Line           int `yaml:"-"`
+}
+
+// UnmarshalYAML decodes the input definition keeping its line.
+func (d *InputDefinition) UnmarshalYAML(node *yaml.Node) error {
+	type plain InputDefinition
+
+	d.Line = node.Line
+
+	return decodeStrict(node, (*plain)(d), "type", "paths", "annotate_source", "follow", "offset_file", "max_record_size", "format", "json_pointer", "columns", "infer_types",
+		"proto_descriptor", "proto_message", "listen", "annotate_connection", "max_connections")
+}
+
+// OutputDefinition declares the output of the pipeline.
+type OutputDefinition struct {
+	// Type of the output, stdout (the default), file or http.
+	Type string `yaml:"type"`
+	// FlushInterval is the interval to flush the records written to stdout, zero flushes after each record, or to
+	// post the batch of the http output even if not full, zero posts only full batches.
+	FlushInterval *time.Duration `yaml:"flush_interval"`
+	// Path is the path template of the parts written by the file output, see sakio.FileOutput.
+	Path string `yaml:"path"`
+	// MaxSize is the size in bytes the parts are rotated at, zero means no limit.
+	MaxSize int64 `yaml:"max_size"`
+	// MaxRecords is the amount of records the parts are rotated at, zero means no limit.
+	MaxRecords int64 `yaml:"max_records"`
+	// RotationInterval is the interval the parts are rotated after being opened, zero means no limit.
+	RotationInterval time.Duration `yaml:"rotation_interval"`
+	// Compression of the parts, none (the default), gzip or zstd.
+	Compression string `yaml:"compression"`
+	// Format of the records, ndjson (the default), csv or tsv (see sakio.CSVOutput), only for stdout, msgpack, cbor
+	// or protobuf (see sakio.FrameReader) or avro (see sakio.AvroOutput), only for stdout.
+	Format string `yaml:"format"`
+	// Columns are the columns, in order, of the csv or tsv rows, the keys of the first record sorted when empty.
+	Columns []string `yaml:"columns"`
+	// ProtoDescriptor is the FileDescriptorSet file declaring the ProtoMessage type, with the protobuf format.
+	ProtoDescriptor string `yaml:"proto_descriptor"`
+	// ProtoMessage is the full name of the message type of the records, with the protobuf format.
+	ProtoMessage string `yaml:"proto_message"`
+	// AvroSchema is the Avro schema file of the records, with the avro format. Inferred from the first record when
+	// empty.
+	AvroSchema string `yaml:"avro_schema"`
+	// AvroCodec is the codec compressing the blocks, null (the default), deflate or snappy, with the avro format.
+	AvroCodec string `yaml:"avro_codec"`
+	// URL is the endpoint the http output posts the records to, see sakio.HTTPOutput.
+	URL string `yaml:"url"`
+	// BatchSize is the amount of records posted at once by the http output, zero means sakio.DefaultHTTPBatchSize.
+	BatchSize int `yaml:"batch_size"`
+	// Timeout is the timeout of each request of the http output, zero means sakio.DefaultHTTPTimeout.
+	Timeout time.Duration `yaml:"timeout"`
+	// Retries is the amount of times the http output retries a failed request, sakio.DefaultHTTPRetries when nil.
+	Retries *int `yaml:"retries"`
+	Line    int  `yaml:"-"`
+}
+
+// UnmarshalYAML decodes the output definition keeping its line.
+func (d *OutputDefinition) UnmarshalYAML(node *yaml.Node) error {
+	type plain OutputDefinition
+
+	d.Line = node.Line
+
+	return decodeStrict(node, (*plain)(d),
+		"type", "flush_interval", "path", "max_size", "max_records", "rotation_interval", "compression", "format", "columns",
+		"proto_descriptor", "proto_message", "avro_schema", "avro_codec", "url", "batch_size", "timeout", "retries")
+}
+
+// ProcessorDefinition declares how the records are processed, see swissarmyknife.ChannelConveyorProcessor.
+type ProcessorDefinition struct {
+	Workers      int     `yaml:"workers"`
+	Ordered      bool    `yaml:"ordered"`
+	DeadLetter   string  `yaml:"dead_letter"`
+	FailFast     bool    `yaml:"fail_fast"`
+	MaxErrors    int64   `yaml:"max_errors"`
+	MaxErrorRate float64 `yaml:"max_error_rate"`
+	MinRecords   int64   `yaml:"min_records"`
+	// Checkpoint is the file the checkpoint is stored into every checkpoint interval, to resume the process from it.
+	Checkpoint         string        `yaml:"checkpoint"`
+	CheckpointInterval time.Duration `yaml:"checkpoint_interval"`
+	Line               int           `yaml:"-"`
+}
+
+// UnmarshalYAML decodes the processor definition keeping its line.
+func (d *ProcessorDefinition) UnmarshalYAML(node *yaml.Node) error {
+	type plain ProcessorDefinition
+
+	d.Line = node.Line
+
+	return decodeStrict(node, (*plain)(d),
+		"workers", "ordered", "dead_letter", "fail_fast", "max_errors", "max_error_rate", "min_records", "checkpoint",
+		"checkpoint_interval")
+}
+
+// ErrorPolicy returns the error policy declared.
+func (d ProcessorDefinition) ErrorPolicy() swissarmyknife.ErrorPolicy {
+	if d.FailFast {
+		return swissarmyknife.FailFast
+	}
+
+	return swissarmyknife.ErrorPolicy{
+		MaxErrors:    d.MaxErrors,
+		MaxErrorRate: d.MaxErrorRate,
+		MinRecords:   d.MinRecords,
+	}
+}
+
+// OperationDefinition declares an operation of the pipeline by the name it is registered under in
+// swissarmyknife.DefaultOperationRegistry, the other fields of the definition being its parameters:
+//
+//      - type: filter
+//        criteria: "speed > 100"
+//
+type OperationDefinition struct {
+	Type string
+	Line int
+
+	node *yaml.Node
+}
+
+// UnmarshalYAML decodes the operation type, its parameters are decoded once validated.
+func (d *OperationDefinition) UnmarshalYAML(node *yaml.Node) error {
+	d.Line = node.Line
+	d.node = node
+
+	if node.Kind != yaml.MappingNode {
+		return typeError(node, "operation must be a mapping")
+	}
+
+	t := d.param("type")
+	if t == nil {
+		return typeError(node, "operation type is missing")
+	}
+
+	return t.Decode(&d.Type)
+}
+
+// Parse parses and validates the pipeline definition, in YAML or JSON.
+//
+// Errors is returned with all the errors found in the definition.
+func Parse(data []byte) (*Definition, error) {
+	var d Definition
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(&d); err != nil {
+		if err == io.EOF {
+			return nil, Errors{{Line: 1, Msg: "pipeline is empty"}}
+		}
+
+		return nil, yamlErrors(err)
+	}
+
+	if errs := d.validate(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	return &d, nil
+}
+
+// validate returns all the errors found in the definition.
+func (d *Definition) validate() Errors {
+	var errs Errors
+
+	switch d.Input.Type {
+	case "", "stdin":
+		if len(d.Input.Paths) > 0 || d.Input.AnnotateSource {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: "paths and annotate_source are only allowed for file input"})
+		}
+	case "file":
+		if len(d.Input.Paths) == 0 {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: "paths is required for file input"})
+		}
+
+		if d.Input.Follow && len(d.Input.Paths) > 1 {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: "follow reads a single path"})
+		}
+
+		if d.Input.Follow && d.Input.Format != "" && d.Input.Format != "ndjson" {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: fmt.Sprintf("%s format is not available with follow", d.Input.Format)})
+		}
+
+		if !d.Input.Follow && d.Input.OffsetFile != "" {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: "offset_file is only allowed with follow"})
+		}
+	case "socket":
+		if d.Input.Listen == "" {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: "listen is required for socket input"})
+		} else if _, _, err := sakio.ParseSocketAddress(d.Input.Listen); err != nil {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: err.Error()})
+		}
+
+		if len(d.Input.Paths) > 0 || d.Input.AnnotateSource {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: "paths and annotate_source are only allowed for file input"})
+		}
+
+		if d.Input.MaxConnections < 0 {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: "max_connections must not be negative"})
+		}
+
+		switch d.Input.Format {
+		case "json", "csv", "tsv", "avro":
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: fmt.Sprintf("%s format is not available for socket input", d.Input.Format)})
+		}
+	case "http":
+		if d.Input.Listen == "" {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: "listen is required for http input"})
+		} else if _, _, err := sakio.ParseHTTPAddress(d.Input.Listen); err != nil {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: err.Error()})
+		}
+
+		if len(d.Input.Paths) > 0 || d.Input.AnnotateSource {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: "paths and annotate_source are only allowed for file input"})
+		}
+
+		switch d.Input.Format {
+		case "", "ndjson", "json":
+			if d.Input.JSONPointer != "" {
+				errs = append(errs, &Error{Line: d.Input.Line, Msg: "json_pointer is not available for http input"})
+			}
+		default:
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: fmt.Sprintf("%s format is not available for http input", d.Input.Format)})
+		}
+	default:
+		errs = append(errs, &Error{Line: d.Input.Line, Msg: fmt.Sprintf("unknown input type %q", d.Input.Type)})
+	}
+
+	if d.Input.Type != "file" && (d.Input.Follow || d.Input.OffsetFile != "") {
+		errs = append(errs, &Error{Line: d.Input.Line, Msg: "follow and offset_file are only allowed for file input"})
+	}
+
+	if d.Input.Type != "socket" && d.Input.Type != "http" && d.Input.Listen != "" {
+		errs = append(errs, &Error{Line: d.Input.Line, Msg: "listen is only allowed for socket and http inputs"})
+	}
+
+	if d.Input.Type != "socket" && (d.Input.AnnotateConnection || d.Input.MaxConnections != 0) {
+		errs = append(errs, &Error{Line: d.Input.Line, Msg: "annotate_connection and max_connections are only allowed for socket input"})
+	}
+
+	if d.Input.MaxRecordSize < 0 {
+		errs = append(errs, &Error{Line: d.Input.Line, Msg: "max_record_size must not be negative"})
+	}
+
+	errs = append(errs, validateProto(d.Input.Line, d.Input.Format, d.Input.ProtoDescriptor, d.Input.ProtoMessage)...)
+
+	switch d.Input.Format {
+	case "", "ndjson", "json", "msgpack", "cbor", "protobuf", "avro":
+		if d.Input.Format != "json" && d.Input.JSONPointer != "" {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: "json_pointer is only allowed for json format"})
+		}
+
+		if d.Input.JSONPointer != "" && !strings.HasPrefix(d.Input.JSONPointer, "/") {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: fmt.Sprintf("invalid json_pointer %q, must start with /", d.Input.JSONPointer)})
+		}
+
+		if len(d.Input.Columns) > 0 || d.Input.InferTypes {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: "columns and infer_types are only allowed for csv and tsv formats"})
+		}
+	case "csv", "tsv":
+		if d.Input.Type == "file" {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: fmt.Sprintf("%s format is only available for stdin input", d.Input.Format)})
+		}
+
+		if d.Input.JSONPointer != "" {
+			errs = append(errs, &Error{Line: d.Input.Line, Msg: "json_pointer is only allowed for json format"})
+		}
+	default:
+		errs = append(errs, &Error{Line: d.Input.Line, Msg: fmt.Sprintf("unknown input format %q", d.Input.Format)})
+	}
+
+	errs = append(errs, d.Output.validate()...)
+
+	if d.Processor.Workers < 0 {
+		errs = append(errs, &Error{Line: d.Processor.Line, Msg: "workers must not be negative"})
+	}
+
+	if d.Processor.MaxErrorRate < 0 || d.Processor.MaxErrorRate > 1 {
+		errs = append(errs, &Error{Line: d.Processor.Line, Msg: "max_error_rate must be between 0 and 1"})
+	}
+
+	errs = append(errs, d.validateCheckpoint()...)
+
+	for _, od := range d.Operations {
+		if _, err := od.operation(context.Background()); err != nil {
+			errs = append(errs, err.(*Error))
+		}
+	}
+
+	return errs
+}
+
+// validateCheckpoint returns the errors found in the checkpoint of the processor, only available when reading files
+// and writing the output files in parts.
+func (d *Definition) validateCheckpoint() Errors {
+	if d.Processor.Checkpoint == "" {
+		if d.Processor.CheckpointInterval != 0 {
+			return Errors{&Error{Line: d.Processor.Line, Msg: "checkpoint_interval is only allowed with checkpoint"}}
+		}
+
+		return nil
+	}
+
+	var errs Errors
+
+	if d.Processor.CheckpointInterval < 0 {
+		errs = append(errs, &Error{Line: d.Processor.Line, Msg: "checkpoint_interval must not be negative"})
+	}
+
+	if d.Input.Type != "file" || d.Input.Follow {
+		errs = append(errs, &Error{Line: d.Processor.Line, Msg: "checkpoint is only available for file input without follow"})
+	}
+
+	if d.Output.Type == "file" && !strings.Contains(d.Output.Path, "{n}") {
+		errs = append(errs, &Error{Line: d.Processor.Line, Msg: "checkpoint requires {n} in the path of the file output"})
+	}
+
+	return errs
+}
+
+// validate returns all the errors found in the output definition.
+func (d *OutputDefinition) validate() Errors {
+	var errs Errors
+
+	switch d.Type {
+	case "", "stdout", "http":
+		if d.Path != "" || d.MaxSize != 0 || d.MaxRecords != 0 || d.RotationInterval != 0 || d.Compression != "" {
+			errs = append(errs, &Error{
+				Line: d.Line,
+				Msg:  "path, max_size, max_records, rotation_interval and compression are only allowed for file output",
+			})
+		}
+
+		if d.FlushInterval != nil && *d.FlushInterval < 0 {
+			errs = append(errs, &Error{Line: d.Line, Msg: "flush_interval must not be negative"})
+		}
+
+		if d.Type != "http" {
+			break
+		}
+
+		if u, err := url.Parse(d.URL); d.URL == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, &Error{Line: d.Line, Msg: fmt.Sprintf("invalid url %q for http output, must be http:// or https://", d.URL)})
+		}
+
+		if d.BatchSize < 0 || d.Timeout < 0 || (d.Retries != nil && *d.Retries < 0) {
+			errs = append(errs, &Error{Line: d.Line, Msg: "batch_size, timeout and retries must not be negative"})
+		}
+
+		if d.Format != "" && d.Format != "ndjson" {
+			errs = append(errs, &Error{Line: d.Line, Msg: fmt.Sprintf("%s format is not available for http output", d.Format)})
+		}
+	case "file":
+		if d.Path == "" {
+			errs = append(errs, &Error{Line: d.Line, Msg: "path is required for file output"})
+		}
+
+		if d.FlushInterval != nil {
+			errs = append(errs, &Error{Line: d.Line, Msg: "flush_interval is only allowed for stdout output"})
+		}
+
+		if d.MaxSize < 0 || d.MaxRecords < 0 || d.RotationInterval < 0 {
+			errs = append(errs, &Error{Line: d.Line, Msg: "max_size, max_records and rotation_interval must not be negative"})
+		}
+
+		if err := sakio.ValidFileRotation(d.Path, d.MaxSize, d.MaxRecords, d.RotationInterval); d.Path != "" && err != nil {
+			errs = append(errs, &Error{Line: d.Line, Msg: err.Error()})
+		}
+
+		if _, err := sakio.ParseCompression(d.Compression); err != nil {
+			errs = append(errs, &Error{Line: d.Line, Msg: err.Error()})
+		}
+	default:
+		errs = append(errs, &Error{Line: d.Line, Msg: fmt.Sprintf("unknown output type %q", d.Type)})
+	}
+
+	if d.Type != "http" && (d.URL != "" || d.BatchSize != 0 || d.Timeout != 0 || d.Retries != nil) {
+		errs = append(errs, &Error{Line: d.Line, Msg: "url, batch_size, timeout and retries are only allowed for http output"})
+	}
+
+	errs = append(errs, validateProto(d.Line, d.Format, d.ProtoDescriptor, d.ProtoMessage)...)
+
+	if d.Format != "avro" && (d.AvroSchema != "" || d.AvroCodec != "") {
+		errs = append(errs, &Error{Line: d.Line, Msg: "avro_schema and avro_codec are only allowed for avro format"})
+	}
+
+	switch d.Format {
+	case "", "ndjson", "msgpack", "cbor", "protobuf":
+		if len(d.Columns) > 0 {
+			errs = append(errs, &Error{Line: d.Line, Msg: "columns are only allowed for csv and tsv formats"})
+		}
+	case "avro":
+		if d.Type == "file" {
+			errs = append(errs, &Error{Line: d.Line, Msg: "avro format is only available for stdout output"})
+		}
+
+		if len(d.Columns) > 0 {
+			errs = append(errs, &Error{Line: d.Line, Msg: "columns are only allowed for csv and tsv formats"})
+		}
+
+		if err := sakio.ValidAvroCompression(d.AvroCodec); err != nil {
+			errs = append(errs, &Error{Line: d.Line, Msg: err.Error()})
+		}
+	case "csv", "tsv":
+		if d.Type == "file" {
+			errs = append(errs, &Error{Line: d.Line, Msg: fmt.Sprintf("%s format is only available for stdout output", d.Format)})
+		}
+	default:
+		errs = append(errs, &Error{Line: d.Line, Msg: fmt.Sprintf("unknown output format %q", d.Format)})
+	}
+
+	return errs
+}
+
+// BuildOperations creates the operations of the pipeline, in order.
+func (d *Definition) BuildOperations(ctx context.Context) ([]swissarmyknife.Operation, error) {
+	operations := make([]swissarmyknife.Operation, len(d.Operations))
+
+	for i, od := range d.Operations {
+		op, err := od.operation(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		operations[i] = op
+	}
+
+	return operations, nil
+}
+
+// operation creates the operation declared, from the swissarmyknife.DefaultOperationRegistry.
+//
+// Error is returned if the operation definition is not valid.
+func (d OperationDefinition) operation(ctx context.Context) (swissarmyknife.Operation, error) {
+	params := make(map[string]interface{})
+
+	for i := 0; i < len(d.node.Content); i += 2 {
+		key, value := d.node.Content[i], d.node.Content[i+1]
+		if key.Value == "type" {
+			continue
+		}
+
+		v, err := decodeParam(value)
+		if err != nil {
+			return nil, &Error{Line: value.Line, Msg: err.Error()}
+		}
+
+		params[key.Value] = v
+	}
+
+	op, err := swissarmyknife.NewRegisteredOperation(ctx, d.Type, params)
+	if err == swissarmyknife.ErrUnknownOperation {
+		return nil, &Error{Line: d.Line, Msg: fmt.Sprintf("unknown operation type %q", d.Type)}
+	}
+
+	if err != nil {
+		line := d.Line
+
+		if cerr, ok := err.(*swissarmyknife.OperationConfigError); ok {
+			for i := 0; i < len(d.node.Content); i += 2 {
+				if d.node.Content[i].Value == cerr.Param {
+					line = d.node.Content[i].Line
+				}
+			}
+		}
+
+		return nil, &Error{Line: line, Msg: err.Error()}
+	}
+
+	return op, nil
+}
+
+// decodeParam decodes the value of an operation parameter, a mapping into []swissarmyknife.KeyValue to keep the
+// order of its keys.
+func decodeParam(node *yaml.Node) (interface{}, error) {
+	if node.Kind != yaml.MappingNode {
+		var v interface{}
+		err := node.Decode(&v)
+
+		return v, err
+	}
+
+	kvs := make([]swissarmyknife.KeyValue, 0, len(node.Content)/2)
+
+	for i := 0; i < len(node.Content); i += 2 {
+		var kv swissarmyknife.KeyValue
+
+		if err := node.Content[i].Decode(&kv.Key); err != nil {
+			return nil, err
+		}
+
+		if err := node.Content[i+1].Decode(&kv.Value); err != nil {
+			return nil, err
+		}
+
+		kvs = append(kvs, kv)
+	}
+
+	return kvs, nil
+}
+
+// param returns the value node of the operation parameter, nil if it is missing.
+func (d OperationDefinition) param(name string) *yaml.Node {
+	for i := 0; i+1 < len(d.node.Content); i += 2 {
+		if d.node.Content[i].Value == name {
+			return d.node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// decodeStrict decodes the mapping node into v, failing on the keys that are not known.
+func decodeStrict(node *yaml.Node, v interface{}, known ...string) error {
+	if node.Tag == "!!null" {
+		return nil
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return typeError(node, "must be a mapping")
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		if key := node.Content[i]; !contains(known, key.Value) {
+			return typeError(key, fmt.Sprintf("unknown field %q", key.Value))
+		}
+	}
+
+	return node.Decode(v)
+}
+
+// typeError returns a yaml.TypeError at the node line, the decoder keeps decoding to report all the errors.
+func typeError(node *yaml.Node, msg string) error {
+	return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: %s", node.Line, msg)}}
+}
+
+// yamlLineError matches the line of the YAML errors.
+// nolint:gochecknoglobals
+var yamlLineError = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlErrors converts the YAML decoding errors into Errors.
+func yamlErrors(err error) Errors {
+	var msgs []string
+
+	if terr, ok := err.(*yaml.TypeError); ok {
+		msgs = terr.Errors
+	} else {
+		msgs = []string{err.Error()}
+	}
+
+	errs := make(Errors, len(msgs))
+
+	for i, msg := range msgs {
+		errs[i] = &Error{Msg: msg}
+
+		if m := yamlLineError.FindStringSubmatch(msg); m != nil {
+			errs[i].Line, _ = strconv.Atoi(m[1]) // nolint:errcheck
+			errs[i].Msg = m[2]
+		}
+	}
+
+	return errs
+}
+
+// contains returns whether s is in the list.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateProto returns the errors found in the protobuf options of the input or output definition at the line.
+func validateProto(line int, format, descriptor, message string) Errors {
+	if format != "protobuf" {
+		if descriptor != "" || message != "" {
+			return Errors{&Error{Line: line, Msg: "proto_descriptor and proto_message are only allowed for protobuf format"}}
+		}
+
+		return nil
+	}
+
+	if descriptor == "" || message == "" {
+		return Errors{&Error{Line: line, Msg: "proto_descriptor and proto_message are required for protobuf format"}}
+	}
+
+	return nil
+}
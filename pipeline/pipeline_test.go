@@ -0,0 +1,526 @@
+package pipeline_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	swiss_army_knife "github.com/dohernandez/swiss-army-knife"
+	"github.com/dohernandez/swiss-army-knife/pipeline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		data     string
+	}{
+		{
+			scenario: "YAML definition",
+			data: `
+input:
+  type: stdin
+processor:
+  workers: 4
+  ordered: true
+  dead_letter: failed.ndjson
+  max_errors: 10
+operations:
+  - type: select
+    criteria: "speed > 10"
+  - type: set
+    expression: "distance_km = dist_m / 1000"
+  - type: append
+    pairs:
+      source: gps
+      driver.city: paris
+  - type: remove
+    keys: [dist_m]
+  - type: prefix
+    pairs: {id: _}
+  - type: where
+    expression: "distance_km > 1"
+  - type: filter
+    criteria: "source:other"
+output:
+  type: stdout
+  flush_interval: 500ms
+`,
+		},
+		{
+			scenario: "JSON definition",
+			data: `{
+  "input": {"type": "stdin"},
+  "processor": {"workers": 4, "ordered": true, "dead_letter": "failed.ndjson", "max_errors": 10},
+  "operations": [
+    {"type": "select", "criteria": "speed > 10"},
+    {"type": "set", "expression": "distance_km = dist_m / 1000"},
+    {"type": "append", "pairs": {"source": "gps", "driver.city": "paris"}},
+    {"type": "remove", "keys": ["dist_m"]},
+    {"type": "prefix", "pairs": {"id": "_"}},
+    {"type": "where", "expression": "distance_km > 1"},
+    {"type": "filter", "criteria": "source:other"}
+  ],
+  "output": {"type": "stdout", "flush_interval": "500ms"}
+}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			d, err := pipeline.Parse([]byte(tc.data))
+			assert.NoError(t, err)
+
+			assert.Equal(t, "stdin", d.Input.Type)
+			assert.Equal(t, "stdout", d.Output.Type)
+			assert.Equal(t, 500*time.Millisecond, *d.Output.FlushInterval)
+			assert.Equal(t, 4, d.Processor.Workers)
+			assert.True(t, d.Processor.Ordered)
+			assert.Equal(t, "failed.ndjson", d.Processor.DeadLetter)
+			assert.Equal(t, swiss_army_knife.ErrorPolicy{MaxErrors: 10}, d.Processor.ErrorPolicy())
+			assert.Len(t, d.Operations, 7)
+
+			ctx := context.TODO()
+
+			operations, err := d.BuildOperations(ctx)
+			assert.NoError(t, err)
+
+			var value interface{}
+			assert.NoError(t, json.Unmarshal([]byte(`{"id":347,"speed":92,"dist_m":2500}`), &value))
+
+			for _, op := range operations {
+				value, err = op(ctx, value)
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, map[string]interface{}{
+				"_id":         float64(347),
+				"speed":       float64(92),
+				"distance_km": 2.5,
+				"source":      "gps",
+				"driver":      map[string]interface{}{"city": "paris"},
+			}, value)
+		})
+	}
+}
+
+func TestParseFileInput(t *testing.T) {
+	d, err := pipeline.Parse([]byte(`input:
+  type: file
+  paths: [a.json, logs/]
+  annotate_source: true
+  max_record_size: 1048576
+  format: json
+  json_pointer: /data/items
+`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "file", d.Input.Type)
+	assert.Equal(t, []string{"a.json", "logs/"}, d.Input.Paths)
+	assert.True(t, d.Input.AnnotateSource)
+	assert.Equal(t, 1048576, d.Input.MaxRecordSize)
+	assert.Equal(t, "json", d.Input.Format)
+	assert.Equal(t, "/data/items", d.Input.JSONPointer)
+}
+
+func TestParseFollow(t *testing.T) {
+	d, err := pipeline.Parse([]byte(`input:
+  type: file
+  paths: [/var/log/rides.ndjson]
+  follow: true
+  offset_file: rides.offset
+`))
+	assert.NoError(t, err)
+
+	assert.True(t, d.Input.Follow)
+	assert.Equal(t, "rides.offset", d.Input.OffsetFile)
+
+	_, err = pipeline.Parse([]byte("input:\n  type: file\n  paths: [a.json, b.json]\n  follow: true\n  format: json\n"))
+	assert.EqualError(t, err, "line 2: follow reads a single path\nline 2: json format is not available with follow")
+
+	_, err = pipeline.Parse([]byte("input:\n  type: file\n  paths: [a.json]\n  offset_file: a.offset\n"))
+	assert.EqualError(t, err, "line 2: offset_file is only allowed with follow")
+
+	_, err = pipeline.Parse([]byte("input:\n  follow: true\n"))
+	assert.EqualError(t, err, "line 2: follow and offset_file are only allowed for file input")
+}
+
+func TestParseSocketInput(t *testing.T) {
+	d, err := pipeline.Parse([]byte(`input:
+  type: socket
+  listen: unix:///run/sak.sock
+  annotate_connection: true
+  max_connections: 16
+  format: msgpack
+`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "socket", d.Input.Type)
+	assert.Equal(t, "unix:///run/sak.sock", d.Input.Listen)
+	assert.True(t, d.Input.AnnotateConnection)
+	assert.Equal(t, 16, d.Input.MaxConnections)
+	assert.Equal(t, "msgpack", d.Input.Format)
+
+	_, err = pipeline.Parse([]byte("input:\n  type: socket\n  paths: [a.json]\n  max_connections: -1\n  format: csv\n"))
+	assert.EqualError(t, err, "line 2: listen is required for socket input\n"+
+		"line 2: paths and annotate_source are only allowed for file input\n"+
+		"line 2: max_connections must not be negative\n"+
+		"line 2: csv format is not available for socket input")
+
+	_, err = pipeline.Parse([]byte("input:\n  type: socket\n  listen: udp://:7070\n"))
+	assert.EqualError(t, err, `line 2: invalid listen address "udp://:7070", valid schemes are tcp, tcp4, tcp6 and unix`)
+
+	_, err = pipeline.Parse([]byte("input:\n  listen: :7070\n  max_connections: 1\n"))
+	assert.EqualError(t, err, "line 2: listen is only allowed for socket and http inputs\n"+
+		"line 2: annotate_connection and max_connections are only allowed for socket input")
+}
+
+func TestParseHTTP(t *testing.T) {
+	d, err := pipeline.Parse([]byte(`input:
+  type: http
+  listen: http://:8080/ingest
+output:
+  type: http
+  url: https://example.com/webhook
+  flush_interval: 5s
+  batch_size: 500
+  timeout: 30s
+  retries: 0
+`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "http", d.Input.Type)
+	assert.Equal(t, "http://:8080/ingest", d.Input.Listen)
+	assert.Equal(t, "http", d.Output.Type)
+	assert.Equal(t, "https://example.com/webhook", d.Output.URL)
+	assert.Equal(t, 5*time.Second, *d.Output.FlushInterval)
+	assert.Equal(t, 500, d.Output.BatchSize)
+	assert.Equal(t, 30*time.Second, d.Output.Timeout)
+	assert.Equal(t, 0, *d.Output.Retries)
+
+	_, err = pipeline.Parse([]byte("input:\n  type: http\n  listen: tcp://:8080\n  format: msgpack\n" +
+		"output:\n  type: http\n  url: example.com\n  retries: -1\n  path: out.ndjson\n  format: cbor\n"))
+	assert.EqualError(t, err, `line 2: invalid listen address "tcp://:8080", must be http://host:port/path`+"\n"+
+		"line 2: msgpack format is not available for http input\n"+
+		"line 6: path, max_size, max_records, rotation_interval and compression are only allowed for file output\n"+
+		`line 6: invalid url "example.com" for http output, must be http:// or https://`+"\n"+
+		"line 6: batch_size, timeout and retries must not be negative\n"+
+		"line 6: cbor format is not available for http output")
+
+	_, err = pipeline.Parse([]byte("input:\n  type: http\noutput:\n  url: https://example.com/webhook\n"))
+	assert.EqualError(t, err, "line 2: listen is required for http input\n"+
+		"line 4: url, batch_size, timeout and retries are only allowed for http output")
+}
+
+func TestParseFileOutput(t *testing.T) {
+	d, err := pipeline.Parse([]byte(`output:
+  type: file
+  path: out/%Y-%m-%d/part-{n}.ndjson
+  max_size: 1024
+  max_records: 100
+  rotation_interval: 1h
+  compression: zstd
+`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, pipeline.OutputDefinition{
+		Type:             "file",
+		Path:             "out/%Y-%m-%d/part-{n}.ndjson",
+		MaxSize:          1024,
+		MaxRecords:       100,
+		RotationInterval: time.Hour,
+		Compression:      "zstd",
+		Line:             2,
+	}, d.Output)
+}
+
+func TestParseCSV(t *testing.T) {
+	d, err := pipeline.Parse([]byte(`input:
+  format: tsv
+  columns: [id, lat]
+  infer_types: true
+output:
+  format: csv
+  columns: [id, driver.city]
+`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "tsv", d.Input.Format)
+	assert.Equal(t, []string{"id", "lat"}, d.Input.Columns)
+	assert.True(t, d.Input.InferTypes)
+	assert.Equal(t, "csv", d.Output.Format)
+	assert.Equal(t, []string{"id", "driver.city"}, d.Output.Columns)
+}
+
+func TestParseBinary(t *testing.T) {
+	d, err := pipeline.Parse([]byte(`input:
+  type: file
+  paths: [dump/*.msgpack]
+  format: msgpack
+output:
+  format: cbor
+`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "msgpack", d.Input.Format)
+	assert.Equal(t, "cbor", d.Output.Format)
+
+	_, err = pipeline.Parse([]byte("input:\n  format: cbor\n  json_pointer: /items\noutput:\n  format: msgpack\n  columns: [id]\n"))
+	assert.EqualError(t, err, "line 2: json_pointer is only allowed for json format\n"+
+		"line 5: columns are only allowed for csv and tsv formats")
+}
+
+func TestParseProtobuf(t *testing.T) {
+	d, err := pipeline.Parse([]byte(`input:
+  format: protobuf
+  proto_descriptor: rides.pb
+  proto_message: rides.v1.Location
+output:
+  format: protobuf
+  proto_descriptor: rides.pb
+  proto_message: rides.v1.PrefixedLocation
+`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "rides.pb", d.Input.ProtoDescriptor)
+	assert.Equal(t, "rides.v1.Location", d.Input.ProtoMessage)
+	assert.Equal(t, "rides.pb", d.Output.ProtoDescriptor)
+	assert.Equal(t, "rides.v1.PrefixedLocation", d.Output.ProtoMessage)
+
+	_, err = pipeline.Parse([]byte("input:\n  format: protobuf\n  proto_message: rides.v1.Location\noutput:\n  proto_descriptor: rides.pb\n"))
+	assert.EqualError(t, err, "line 2: proto_descriptor and proto_message are required for protobuf format\n"+
+		"line 5: proto_descriptor and proto_message are only allowed for protobuf format")
+}
+
+func TestParseAvro(t *testing.T) {
+	d, err := pipeline.Parse([]byte(`input:
+  type: file
+  paths: [dump/*.avro]
+  format: avro
+output:
+  format: avro
+  avro_schema: ride.avsc
+  avro_codec: snappy
+`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "avro", d.Input.Format)
+	assert.Equal(t, "avro", d.Output.Format)
+	assert.Equal(t, "ride.avsc", d.Output.AvroSchema)
+	assert.Equal(t, "snappy", d.Output.AvroCodec)
+
+	_, err = pipeline.Parse([]byte("output:\n  type: file\n  path: out.avro\n  format: avro\n  avro_codec: gzip\n"))
+	assert.EqualError(t, err, "line 2: avro format is only available for stdout output\n"+
+		`line 2: unknown avro compression "gzip", valid are null, deflate and snappy`)
+
+	_, err = pipeline.Parse([]byte("output:\n  avro_schema: ride.avsc\n"))
+	assert.EqualError(t, err, "line 2: avro_schema and avro_codec are only allowed for avro format")
+}
+
+func TestParseDefaults(t *testing.T) {
+	d, err := pipeline.Parse([]byte("operations: []\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "", d.Input.Type)
+	assert.Nil(t, d.Output.FlushInterval)
+	assert.Equal(t, swiss_army_knife.ContinueOnError, d.Processor.ErrorPolicy())
+	assert.Empty(t, d.Operations)
+}
+
+func TestParseInvalid(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		data     string
+		err      string
+	}{
+		{
+			scenario: "Empty",
+			data:     "",
+			err:      "line 1: pipeline is empty",
+		},
+		{
+			scenario: "Syntax error",
+			data:     "input:\n  type: stdin\noutput:\n\ttype: stdout\n",
+			err:      "line 4: found character that cannot start any token",
+		},
+		{
+			scenario: "Unknown fields",
+			data:     "inputs: {}\noutput:\n  kind: file\n",
+			err:      "line 1: field inputs not found in type pipeline.Definition\nline 3: unknown field \"kind\"",
+		},
+		{
+			scenario: "Invalid types",
+			data:     "input:\n  type: kafka\noutput:\n  type: kafka\nprocessor:\n  workers: -1\n  max_error_rate: 2\n",
+			err: "line 2: unknown input type \"kafka\"\n" +
+				"line 4: unknown output type \"kafka\"\n" +
+				"line 6: workers must not be negative\n" +
+				"line 6: max_error_rate must be between 0 and 1",
+		},
+		{
+			scenario: "File input without paths",
+			data:     "input:\n  type: file\n  max_record_size: -1\n",
+			err:      "line 2: paths is required for file input\nline 2: max_record_size must not be negative",
+		},
+		{
+			scenario: "Invalid input formats",
+			data:     "input:\n  format: xml\n",
+			err:      "line 2: unknown input format \"xml\"",
+		},
+		{
+			scenario: "JSON pointer without json format",
+			data:     "input:\n  json_pointer: /data\n",
+			err:      "line 2: json_pointer is only allowed for json format",
+		},
+		{
+			scenario: "Invalid JSON pointer",
+			data:     "input:\n  format: json\n  json_pointer: data\n",
+			err:      "line 2: invalid json_pointer \"data\", must start with /",
+		},
+		{
+			scenario: "Invalid csv formats",
+			data:     "input:\n  type: file\n  paths: [a.csv]\n  format: tsv\n  json_pointer: /data\noutput:\n  type: file\n  path: out.csv\n  format: csv\n",
+			err: "line 2: tsv format is only available for stdin input\n" +
+				"line 2: json_pointer is only allowed for json format\n" +
+				"line 7: csv format is only available for stdout output",
+		},
+		{
+			scenario: "Columns without csv formats",
+			data:     "input:\n  columns: [id]\n  infer_types: true\noutput:\n  format: xml\n  columns: [id]\n",
+			err: "line 2: columns and infer_types are only allowed for csv and tsv formats\n" +
+				"line 5: unknown output format \"xml\"",
+		},
+		{
+			scenario: "Stdin input with paths",
+			data:     "input:\n  paths: [a.json]\n  annotate_source: true\n",
+			err:      "line 2: paths and annotate_source are only allowed for file input",
+		},
+		{
+			scenario: "Invalid file output",
+			data:     "output:\n  type: file\n  flush_interval: 1s\n  max_records: -1\n  compression: bzip2\n",
+			err: "line 2: path is required for file output\n" +
+				"line 2: flush_interval is only allowed for stdout output\n" +
+				"line 2: max_size, max_records and rotation_interval must not be negative\n" +
+				"line 2: unknown compression \"bzip2\", valid are none, gzip and zstd",
+		},
+		{
+			scenario: "File output rotated without part number",
+			data:     "output:\n  type: file\n  path: out.ndjson\n  max_records: 100\n",
+			err:      `line 2: rotating the parts requires {n} in the path template "out.ndjson"`,
+		},
+		{
+			scenario: "Stdout output with path",
+			data:     "output:\n  path: out.ndjson\n",
+			err:      "line 2: path, max_size, max_records, rotation_interval and compression are only allowed for file output",
+		},
+		{
+			scenario: "Invalid operation definitions",
+			data: `operations:
+  - type: filter
+  - criteria: "id:1"
+  - just a string
+`,
+			err: `line 3: operation type is missing
+line 4: operation must be a mapping`,
+		},
+		{
+			scenario: "Invalid operation parameters",
+			data: `operations:
+  - type: unknown
+  - type: filter
+  - type: filter
+    criteria: "id in 1"
+  - type: select
+    criteria: "id:1"
+    expression: "id > 1"
+  - type: where
+    expression: "id = 1"
+  - type: set
+    expression: "id > 1"
+  - type: set
+    expression: "id +"
+  - type: append
+    pairs: [id]
+  - type: remove
+    keys:
+      - id
+      - driver..id
+  - type: prefix
+    pairs:
+      id: [_]
+  - type: append
+    pairs:
+      id: 1
+      id: 2
+`,
+			err: `line 2: unknown operation type "unknown"
+line 3: filter: parameter "criteria": missing required parameter
+line 5: filter: parameter "criteria": invalid criteria: missing '[' at offset 6
+line 8: select: parameter "expression": unknown parameter
+line 10: where: parameter "expression": assignments are not allowed in where, use set
+line 12: set: parameter "expression": only assignments are allowed in set, use where
+line 14: set: parameter "expression": invalid expression: unexpected end of expression at offset 4
+line 16: append: parameter "pairs": []interface {} does not fit [][2]string
+line 18: remove: parameter "keys": invalid key path "driver..id"
+line 22: prefix: parameter "pairs": value of "id" must be a string, number or bool, got []interface {}
+line 25: append: parameter "pairs": duplicate key "id"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			_, err := pipeline.Parse([]byte(tc.data))
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestParsePairsOrder(t *testing.T) {
+	d, err := pipeline.Parse([]byte(`
+operations:
+  - type: append
+    pairs:
+      driver.id: 347
+      driver: unknown
+`))
+	assert.NoError(t, err)
+
+	ctx := context.TODO()
+
+	operations, err := d.BuildOperations(ctx)
+	assert.NoError(t, err)
+
+	// the pairs are applied in the order they are declared.
+	value, err := operations[0](ctx, map[string]interface{}{"id": float64(1629)})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": float64(1629), "driver": "unknown"}, value)
+}
+
+func TestParseCheckpoint(t *testing.T) {
+	d, err := pipeline.Parse([]byte(`input:
+  type: file
+  paths: [rides/]
+output:
+  type: file
+  path: out/part-{n}.ndjson
+processor:
+  checkpoint: rides.checkpoint
+  checkpoint_interval: 30s
+`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "rides.checkpoint", d.Processor.Checkpoint)
+	assert.Equal(t, 30*time.Second, d.Processor.CheckpointInterval)
+
+	_, err = pipeline.Parse([]byte("input:\n  type: file\n  paths: [rides.ndjson]\n  follow: true\n" +
+		"output:\n  type: file\n  path: out.ndjson\n" +
+		"processor:\n  checkpoint: rides.checkpoint\n  checkpoint_interval: -1s\n"))
+	assert.EqualError(t, err, "line 9: checkpoint_interval must not be negative\n"+
+		"line 9: checkpoint is only available for file input without follow\n"+
+		"line 9: checkpoint requires {n} in the path of the file output")
+
+	_, err = pipeline.Parse([]byte("processor:\n  checkpoint_interval: 1s\n"))
+	assert.EqualError(t, err, "line 2: checkpoint_interval is only allowed with checkpoint")
+}
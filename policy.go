@@ -0,0 +1,54 @@
+package swissarmyknife
+
+// ErrorPolicy defines when the processor aborts the process because of the errors processing the records.
+// The zero value continues processing regardless of the errors.
+//
+// Common initialization example:
+//
+//      // abort after 100 errors or when more than 10% of the records failed, once 1000 records were read.
+//      policy := ErrorPolicy{
+//			MaxErrors:    100,
+//			MaxErrorRate: 0.1,
+//			MinRecords:   1000,
+//		}
+//
+type ErrorPolicy struct {
+	// MaxErrors is the amount of errors that aborts the process, zero means no limit.
+	MaxErrors int64
+	// MaxErrorRate is the rate of errors over records read (from 0 to 1) that once exceeded aborts the process,
+	// zero means no limit.
+	MaxErrorRate float64
+	// MinRecords is the amount of records read before the MaxErrorRate is taken into account,
+	// DefaultMinRecords when zero, so a single early failure does not abort the process.
+	MinRecords int64
+}
+
+// DefaultMinRecords is the amount of records read before the MaxErrorRate is taken into account when the
+// ErrorPolicy does not set MinRecords.
+const DefaultMinRecords = 100
+
+var (
+	// ContinueOnError is the ErrorPolicy that keeps processing regardless of the errors.
+	ContinueOnError = ErrorPolicy{}
+
+	// FailFast is the ErrorPolicy that aborts the process on the first error.
+	FailFast = ErrorPolicy{MaxErrors: 1}
+)
+
+// Exceeded returns whether the errors occurred exceed the policy, based on the amount of errors and records read.
+func (ep ErrorPolicy) Exceeded(errors, records int64) bool {
+	if ep.MaxErrors > 0 && errors >= ep.MaxErrors {
+		return true
+	}
+
+	minRecords := ep.MinRecords
+	if minRecords <= 0 {
+		minRecords = DefaultMinRecords
+	}
+
+	if ep.MaxErrorRate > 0 && records >= minRecords {
+		return float64(errors)/float64(records) > ep.MaxErrorRate
+	}
+
+	return false
+}
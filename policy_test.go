@@ -0,0 +1,91 @@
+package swissarmyknife_test
+
+import (
+	"testing"
+
+	swiss_army_knife "github.com/dohernandez/swiss-army-knife"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorPolicyExceeded(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		policy   swiss_army_knife.ErrorPolicy
+		errors   int64
+		records  int64
+		exceeded bool
+	}{
+		{
+			scenario: "Continue on error is never exceeded",
+			policy:   swiss_army_knife.ContinueOnError,
+			errors:   100,
+			records:  100,
+		},
+		{
+			scenario: "Fail fast is exceeded on first error",
+			policy:   swiss_army_knife.FailFast,
+			errors:   1,
+			records:  100,
+			exceeded: true,
+		},
+		{
+			scenario: "Max errors is not exceeded",
+			policy:   swiss_army_knife.ErrorPolicy{MaxErrors: 10},
+			errors:   9,
+			records:  100,
+		},
+		{
+			scenario: "Max errors is exceeded",
+			policy:   swiss_army_knife.ErrorPolicy{MaxErrors: 10},
+			errors:   10,
+			records:  100,
+			exceeded: true,
+		},
+		{
+			scenario: "Max error rate is not exceeded",
+			policy:   swiss_army_knife.ErrorPolicy{MaxErrorRate: 0.1},
+			errors:   10,
+			records:  100,
+		},
+		{
+			scenario: "Max error rate is exceeded",
+			policy:   swiss_army_knife.ErrorPolicy{MaxErrorRate: 0.1},
+			errors:   11,
+			records:  100,
+			exceeded: true,
+		},
+		{
+			scenario: "Max error rate is not taken into account before min records",
+			policy:   swiss_army_knife.ErrorPolicy{MaxErrorRate: 0.1, MinRecords: 100},
+			errors:   2,
+			records:  10,
+		},
+		{
+			scenario: "Max error rate is not taken into account before default min records",
+			policy:   swiss_army_knife.ErrorPolicy{MaxErrorRate: 0.1},
+			errors:   1,
+			records:  1,
+		},
+		{
+			scenario: "Max error rate is exceeded after default min records",
+			policy:   swiss_army_knife.ErrorPolicy{MaxErrorRate: 0.1},
+			errors:   11,
+			records:  swiss_army_knife.DefaultMinRecords,
+			exceeded: true,
+		},
+		{
+			scenario: "Max error rate is taken into account after min records lower than the default",
+			policy:   swiss_army_knife.ErrorPolicy{MaxErrorRate: 0.1, MinRecords: 5},
+			errors:   1,
+			records:  5,
+			exceeded: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			assert.Equal(t, tc.exceeded, tc.policy.Exceeded(tc.errors, tc.records))
+		})
+	}
+}
@@ -3,7 +3,12 @@ package swissarmyknife
 import (
 	"context"
 	"io"
+	"reflect"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	sakio "github.com/dohernandez/swiss-army-knife/io"
 )
@@ -15,33 +20,123 @@ type Processor interface {
 
 // ChannelConveyorProcessor a processor that uses ChannelConveyor to share data between operations.
 type ChannelConveyorProcessor struct {
+	// kept first to guarantee the 64-bit alignment required by the atomic operations.
+	stats Stats
+
+	codec Codec
+
+	workers        int
+	orderPreserved bool
+
+	deadLetter sakio.Output
+
+	errorPolicy ErrorPolicy
+
+	checkpointFile     string
+	checkpointInterval time.Duration
+	resume             *Checkpoint
+	tracker            *checkpointTracker
+	// stored is the last checkpoint stored, nil until one is.
+	stored *Checkpoint
+
+	// ackable is the input the records are acknowledged to, nil when the input is not a sakio.AckableInput.
+	ackable sakio.AckableInput
+	// acks are the records settled to be acknowledged once the output is committed.
+	acksMu sync.Mutex
+	acks   []pendingAck
+
 	conveyorErrors []error
 }
 
 var _ Processor = new(ChannelConveyorProcessor)
 
+// Stats holds the counters of the records processed.
+type Stats struct {
+	// Read is the amount of records read from the input, including the invalid ones.
+	Read int64 `json:"read"`
+	// Written is the amount of records appended to the output.
+	Written int64 `json:"written"`
+	// Dropped is the amount of records the operations did not emit (see ErrDoNotEmit).
+	Dropped int64 `json:"dropped"`
+	// Failed is the amount of errors that occurred processing the records.
+	Failed int64 `json:"failed"`
+}
+
+// record is the envelope conveyed between operations. It keeps the record as it was read from the input along
+// with the value operated.
+type record struct {
+	raw   string
+	value interface{}
+
+	tracking
+}
+
+// tracking identifies a record read, to track it until it is settled (see checkpointTracker) and to acknowledge it
+// to the input (see sakio.AckableInput).
+type tracking struct {
+	// seq is the number of the record in the order it was read.
+	seq     int64
+	receipt interface{}
+}
+
+const (
+	inputStageName  = "input"
+	outputStageName = "output"
+)
+
 // Process processes the data input thro the operations defines and outputted the result.
 // Returns error if outputting the result fails.
+//
+// Errors processing a record are collected as *RecordError (see Errors) and, when a dead letter output is set,
+// appended to it. Once the errors exceed the error policy, the process is aborted the same way as when
+// the context is done, returning ErrAborted.
+//
+// When the context is done, Process stops reading from the input, drains the items already read thro
+// the operations, writes them into the output and returns the context error.
+//
+// When a checkpoint file is set (see WithCheckpoint), the checkpoint is stored periodically and once the output is
+// written, the input being resumed from the checkpoint set by WithResume, if any.
+//
+// When the input is a sakio.AckableInput, each record appended to the output or dropped is acknowledged, and each
+// record that failed negatively acknowledged, once the output and the dead letter output, if any, are committed
+// (see WithCheckpoint) or written, so the records acknowledged are durable. The records read but not processed
+// because the context is done, or not committed because writing the output fails, are not acknowledged.
 func (p *ChannelConveyorProcessor) Process(ctx context.Context, input sakio.Input, output sakio.Output, operations ...Operation) error {
-	var wg sync.WaitGroup
+	parentCtx := ctx
+
+	if err := p.startCheckpoint(input); err != nil {
+		return err
+	}
+
+	p.ackable, _ = input.(sakio.AckableInput)
+
+	ctx, abort := context.WithCancel(ctx)
+	defer abort()
+
+	var (
+		wg      sync.WaitGroup
+		aborted bool
+	)
+
 	inputs := make(chan interface{})
-	operationResults := make(chan error)
+	operationResults := make(chan operationError)
 
-	// create a ChannelConveyor.
-	cc := NewChannelConveyor(inputs)
+	// create a ChannelConveyor. The records are conveyed as they are, the codec is applied to the value
+	// accepted by each operation.
+	cc := NewChannelConveyor(inputs, PassThroughCodec{})
 
 	// starts the conveyor.
 	p.inputConveyor(ctx, &wg, input, cc, operationResults)
 	cc = cc.ChainNext()
 
 	// operate the conveyor.
-	for _, op := range operations {
-		p.operateConveyor(ctx, &wg, op, cc, operationResults)
+	for i, op := range operations {
+		p.operateConveyor(ctx, &wg, i, op, cc, operationResults)
 		cc = cc.ChainNext()
 	}
 
 	// ends the conveyor.
-	p.outputConveyor(ctx, &wg, output, cc, operationResults)
+	p.outputConveyor(ctx, &wg, len(operations), output, cc, operationResults)
 
 	// this along with wg.Wait() are why the error handling works and doesn't deadlock.
 	finished := make(chan bool, 1)
@@ -53,6 +148,25 @@ func (p *ChannelConveyorProcessor) Process(ctx context.Context, input sakio.Inpu
 		close(finished)
 	}()
 
+	// the checkpoint is stored, and the records acknowledged, from this routine, after the dead letter output
+	// appended the errors received.
+	var (
+		checkpoints   <-chan time.Time
+		checkpointErr error
+	)
+
+	if (p.tracker != nil || p.ackable != nil) && streamed(output) && (p.deadLetter == nil || streamed(p.deadLetter)) {
+		interval := p.checkpointInterval
+		if interval <= 0 {
+			interval = DefaultCheckpointInterval
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		checkpoints = ticker.C
+	}
+
 	// handler operation errors.
 	for {
 		var fin bool
@@ -60,9 +174,28 @@ func (p *ChannelConveyorProcessor) Process(ctx context.Context, input sakio.Inpu
 		select {
 		case <-finished:
 			fin = true
-		case err := <-operationResults:
-			if err != nil {
-				p.conveyorErrors = append(p.conveyorErrors, err)
+		case <-checkpoints:
+			if err := p.checkpoint(ctx, output); err != nil && checkpointErr == nil {
+				checkpointErr = err
+
+				abort()
+			}
+		case oerr := <-operationResults:
+			p.conveyorErrors = append(p.conveyorErrors, oerr.err)
+
+			p.deadLetterRecord(ctx, oerr.err)
+
+			// settled once appended to the dead letter output, so the checkpoint does not move past it before.
+			if oerr.seq > 0 {
+				p.settle(oerr.tracking, failed, oerr.err)
+			}
+
+			failed := atomic.AddInt64(&p.stats.Failed, 1)
+
+			if !aborted && p.errorPolicy.Exceeded(failed, atomic.LoadInt64(&p.stats.Read)) {
+				aborted = true
+
+				abort()
 			}
 		}
 
@@ -71,14 +204,179 @@ func (p *ChannelConveyorProcessor) Process(ctx context.Context, input sakio.Inpu
 		}
 	}
 
-	return output.Write(ctx)
+	if ctx.Err() != nil {
+		// the output is written regardless of the context being done, the records already processed
+		// must be flushed.
+		if err := p.write(withoutCancel(ctx), output); err != nil {
+			return err
+		}
+
+		if checkpointErr != nil {
+			return checkpointErr
+		}
+
+		if err := p.checkpoint(withoutCancel(ctx), output); err != nil {
+			return err
+		}
+
+		if aborted && parentCtx.Err() == nil {
+			return ErrAborted
+		}
+
+		return parentCtx.Err()
+	}
+
+	if err := p.write(ctx, output); err != nil {
+		return err
+	}
+
+	return p.checkpoint(ctx, output)
+}
+
+// startCheckpoint starts tracking the records for the checkpoint, if a checkpoint file is set, resuming the input
+// and the counters from the checkpoint to resume from, if any.
+//
+// Returns ErrNotResumable if the input is not a sakio.ResumableInput.
+func (p *ChannelConveyorProcessor) startCheckpoint(input sakio.Input) error {
+	if p.checkpointFile == "" && p.resume == nil {
+		return nil
+	}
+
+	resumable, ok := input.(sakio.ResumableInput)
+	if !ok {
+		return ErrNotResumable
+	}
+
+	var checkpoint Checkpoint
+
+	if p.resume != nil {
+		checkpoint = *p.resume
+
+		if err := resumable.Resume(checkpoint.Position); err != nil {
+			return err
+		}
+
+		atomic.StoreInt64(&p.stats.Read, checkpoint.Stats.Read)
+		atomic.StoreInt64(&p.stats.Written, checkpoint.Stats.Written)
+		atomic.StoreInt64(&p.stats.Dropped, checkpoint.Stats.Dropped)
+		atomic.StoreInt64(&p.stats.Failed, checkpoint.Stats.Failed)
+	}
+
+	if p.checkpointFile != "" {
+		p.tracker = newCheckpointTracker(checkpoint)
+	}
+
+	return nil
+}
+
+// streamed reports whether the output writes the output data as soon as it is appended, so a checkpoint can be
+// stored before the output is written.
+func streamed(output sakio.Output) bool {
+	_, ok := output.(sakio.StreamOutput)
+
+	return ok
+}
+
+// checkpoint makes the output data appended so far durable, committing the output and the dead letter output, then
+// stores the checkpoint reached, if it moved forward since the last one stored, and acknowledges the records settled
+// to the input, if ackable.
+func (p *ChannelConveyorProcessor) checkpoint(ctx context.Context, output sakio.Output) error {
+	// taken before committing, the records settled meanwhile are committed with the next checkpoint.
+	var checkpoint Checkpoint
+	if p.tracker != nil {
+		checkpoint = p.tracker.current()
+	}
+
+	store := p.tracker != nil && (p.stored == nil || *p.stored != checkpoint)
+
+	acks := p.pendingAcks()
+
+	if !store && len(acks) == 0 {
+		return nil
+	}
+
+	if err := commit(ctx, output); err != nil {
+		return err
+	}
+
+	if p.deadLetter != nil {
+		if err := commit(ctx, p.deadLetter); err != nil {
+			return err
+		}
+	}
+
+	if store {
+		if err := storeCheckpoint(p.checkpointFile, checkpoint); err != nil {
+			return err
+		}
+
+		p.stored = &checkpoint
+	}
+
+	// the records drained once the context is done are acknowledged as well.
+	ctx = withoutCancel(ctx)
+
+	for _, a := range acks {
+		if a.err != nil {
+			p.ackable.Nack(ctx, a.receipt, a.err)
+
+			continue
+		}
+
+		p.ackable.Ack(ctx, a.receipt)
+	}
+
+	return nil
+}
+
+// commit makes the output data appended so far durable, see sakio.CommitOutput. Other outputs are flushed, if
+// streamed, the output data being written by Write otherwise.
+func commit(ctx context.Context, output sakio.Output) error {
+	switch o := output.(type) {
+	case sakio.CommitOutput:
+		return o.Commit(ctx)
+	case sakio.StreamOutput:
+		return o.Flush(ctx)
+	}
+
+	return nil
+}
+
+// write writes the output and the dead letter output, if any.
+func (p *ChannelConveyorProcessor) write(ctx context.Context, output sakio.Output) error {
+	if err := output.Write(ctx); err != nil {
+		return err
+	}
+
+	if p.deadLetter != nil {
+		return p.deadLetter.Write(ctx)
+	}
+
+	return nil
+}
+
+// deadLetterRecord appends the record that failed into the dead letter output, if any.
+func (p *ChannelConveyorProcessor) deadLetterRecord(ctx context.Context, err error) {
+	if p.deadLetter == nil {
+		return
+	}
+
+	rerr, ok := err.(*RecordError)
+	if !ok || (rerr.Input == "" && rerr.Record == nil) {
+		return
+	}
+
+	p.deadLetter.Append(ctx, rerr)
 }
 
 // inputConveyor takes the input one by one and start the conveyor sending the data input to the first
 // operation in the list.
 // As it is a function that runs in the background - using go routines - error will be sent to the main routine
 // thro the channel `operationResults`.
-func (p *ChannelConveyorProcessor) inputConveyor(ctx context.Context, wg *sync.WaitGroup, input sakio.Input, cc ChannelConveyor, operationResults chan error) {
+//
+// When the context is done, it stops reading from the input and closes the conveyor, letting the items
+// already in the conveyor to drain thro the operations to the output.
+func (p *ChannelConveyorProcessor) inputConveyor(ctx context.Context, wg *sync.WaitGroup, input sakio.Input, cc ChannelConveyor, operationResults chan operationError) {
 	wg.Add(1)
 
 	go func(ctx context.Context, c ChannelConveyor) {
@@ -87,69 +385,408 @@ func (p *ChannelConveyorProcessor) inputConveyor(ctx context.Context, wg *sync.W
 			wg.Done()
 		}()
 
-		r, err := input.Next(ctx)
-		for err == nil {
-			err = c.Emit(r)
-			if err != nil {
-				operationResults <- err
+		records := readInput(ctx, input, &p.stats.Read, p.tracker)
+
+		for {
+			var (
+				rec nextRecord
+				ok  bool
+			)
+
+			select {
+			case <-ctx.Done():
+				return
+			case rec, ok = <-records:
 			}
 
-			r, err = input.Next(ctx)
-		}
-		if err != io.EOF {
-			operationResults <- err
+			if !ok {
+				return
+			}
+
+			if invalid, ok := rec.err.(*sakio.InvalidRecordError); ok {
+				// the record is skipped, the input is still readable.
+				operationResults <- operationError{
+					err: &RecordError{
+						OperationIndex: InputStage,
+						OperationName:  inputStageName,
+						Input:          rec.raw,
+						Err:            invalid.Err,
+					},
+					tracking: rec.tracking,
+				}
+
+				continue
+			}
+
+			if rec.err != nil {
+				if rec.err != io.EOF && rec.err != ctx.Err() {
+					operationResults <- operationError{
+						err: &RecordError{
+							OperationIndex: InputStage,
+							OperationName:  inputStageName,
+							Input:          rec.raw,
+							Err:            rec.err,
+						},
+					}
+				}
+
+				return
+			}
+
+			if err := c.Emit(&record{raw: rec.raw, value: rec.value, tracking: rec.tracking}); err != nil {
+				operationResults <- operationError{
+					err: &RecordError{
+						OperationIndex: InputStage,
+						OperationName:  inputStageName,
+						Input:          rec.raw,
+						Record:         rec.value,
+						Err:            err,
+					},
+					tracking: rec.tracking,
+				}
+			}
 		}
 	}(ctx, cc)
 }
 
+// operationError is an error sent to the main routine thro the channel `operationResults`, along with the tracking of
+// the record that failed, to settle it once appended to the dead letter output. The tracking is zero when the error
+// is not about a record read (i.e. reading the input failed).
+type operationError struct {
+	err error
+
+	tracking
+}
+
+// nextRecord holds the result of calling sakio.Input.Next.
+type nextRecord struct {
+	raw   string
+	value interface{}
+	err   error
+
+	tracking
+}
+
+// readInput reads the input in the background until an error occurs (io.EOF included) or the context is done.
+// Invalid records (see sakio.InvalidRecordError) do not stop the reading.
+// Reading is done in its own go routine since sakio.Input.Next may block regardless of the context
+// (i.e. reading from os.Stdin), so the conveyor can be closed as soon as the context is done.
+//
+// The records read are numbered, along with their receipt when the input is a sakio.AckableInput, and tracked along
+// with the position in the input after them, when the tracker is not nil.
+func readInput(ctx context.Context, input sakio.Input, read *int64, tracker *checkpointTracker) <-chan nextRecord {
+	records := make(chan nextRecord)
+
+	rawInput, _ := input.(sakio.RawInput)
+	resumable, _ := input.(sakio.ResumableInput)
+	ackable, _ := input.(sakio.AckableInput)
+
+	go func() {
+		defer close(records)
+
+		var seq int64
+
+		for {
+			r, err := input.Next(ctx)
+
+			rec := nextRecord{value: r, err: err}
+
+			if _, ok := err.(*sakio.InvalidRecordError); err == nil || ok {
+				atomic.AddInt64(read, 1)
+
+				seq++
+				rec.seq = seq
+
+				if ackable != nil {
+					rec.receipt = ackable.Receipt()
+				}
+
+				if tracker != nil {
+					tracker.read(seq, resumable.Position())
+				}
+			}
+
+			if rawInput != nil && err != io.EOF {
+				rec.raw = rawInput.Raw()
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case records <- rec:
+			}
+
+			if _, ok := err.(*sakio.InvalidRecordError); err != nil && !ok {
+				return
+			}
+		}
+	}()
+
+	return records
+}
+
 // operateConveyor takes the input an apply the operation. The resulting output is sent either to the next
 // operation in the list.
 // As it is a function that runs in the background - using go routines - error will be sent to the main routine
 // thro the channel `operationResults`.
-func (p *ChannelConveyorProcessor) operateConveyor(ctx context.Context, wg *sync.WaitGroup, op Operation, cc ChannelConveyor, operationResults chan error) {
+//
+// The operation is applied by as many workers as configured, all of them accepting from the same conveyor.
+// When the order is preserved, the results are emitted in the same order the inputs were accepted.
+func (p *ChannelConveyorProcessor) operateConveyor(ctx context.Context, wg *sync.WaitGroup, index int, op Operation, cc ChannelConveyor, operationResults chan operationError) {
+	workers := p.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	stage := stage{index: index, name: OperationName(op)}
+
+	if p.orderPreserved && workers > 1 {
+		p.operateConveyorInOrder(ctx, wg, workers, stage, op, cc, operationResults)
+
+		return
+	}
+
 	wg.Add(1)
 
-	go func(ctx context.Context, op Operation, c ChannelConveyor) {
+	var workersWg sync.WaitGroup
+
+	workersWg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func(ctx context.Context, op Operation, c ChannelConveyor) {
+			defer workersWg.Done()
+
+			for {
+				rec, input, err := p.accept(c, stage)
+				if err != nil {
+					if err == io.EOF {
+						break
+					}
+
+					operationResults <- acceptError(rec, err)
+
+					continue
+				}
+
+				output, err := op(ctx, input)
+
+				p.emit(c, stage, rec, input, output, err, operationResults)
+			}
+		}(ctx, op, cc)
+	}
+
+	// the conveyor is closed once all the workers are done.
+	go func(c ChannelConveyor) {
+		workersWg.Wait()
+
+		c.Close()
+		wg.Done()
+	}(cc)
+}
+
+// stage identifies the operation of the process.
+type stage struct {
+	index int
+	name  string
+}
+
+// operationJob holds the input to apply the operation to and where to send the result.
+type operationJob struct {
+	rec    *record
+	input  interface{}
+	result chan operationResult
+}
+
+// operationResult holds the result of applying the operation.
+type operationResult struct {
+	rec    *record
+	input  interface{}
+	output interface{}
+	err    error
+}
+
+// operateConveyorInOrder takes the input an apply the operation using the workers, emitting the results in the
+// same order the inputs were accepted.
+// As it is a function that runs in the background - using go routines - error will be sent to the main routine
+// thro the channel `operationResults`.
+func (p *ChannelConveyorProcessor) operateConveyorInOrder(ctx context.Context, wg *sync.WaitGroup, workers int, stage stage, op Operation, cc ChannelConveyor, operationResults chan operationError) {
+	wg.Add(1)
+
+	jobs := make(chan operationJob, workers)
+	// results pending to be emitted, in the order the inputs were accepted. Its size limits the amount of
+	// results waiting for a slower previous one.
+	pending := make(chan chan operationResult, 2*workers)
+
+	// dispatches the inputs to the workers.
+	go func(c ChannelConveyor) {
 		defer func() {
-			c.Close()
-			wg.Done()
+			close(jobs)
+			close(pending)
 		}()
 
 		for {
-			var input interface{}
-
-			if err := c.Accept(&input); err != nil {
+			rec, input, err := p.accept(c, stage)
+			if err != nil {
 				if err == io.EOF {
 					break
 				}
 
-				operationResults <- err
+				operationResults <- acceptError(rec, err)
+
 				continue
 			}
 
-			output, err := op(ctx, input)
-			if err != nil {
-				if err != ErrDoNotEmit {
-					operationResults <- err
-				}
-				continue
+			job := operationJob{
+				rec:    rec,
+				input:  input,
+				result: make(chan operationResult, 1),
 			}
 
-			err = c.Emit(output)
-			if err != nil {
-				operationResults <- err
+			pending <- job.result
+			jobs <- job
+		}
+	}(cc)
+
+	for i := 0; i < workers; i++ {
+		go func(ctx context.Context, op Operation) {
+			for job := range jobs {
+				output, err := op(ctx, job.input)
+
+				job.result <- operationResult{rec: job.rec, input: job.input, output: output, err: err}
 			}
+		}(ctx, op)
+	}
+
+	// emits the results in order.
+	go func(c ChannelConveyor) {
+		defer func() {
+			c.Close()
+			wg.Done()
+		}()
+
+		for result := range pending {
+			r := <-result
+
+			p.emit(c, stage, r.rec, r.input, r.output, r.err, operationResults)
 		}
-	}(ctx, op, cc)
+	}(cc)
+}
+
+// accept accepts the next record from the conveyor, transferring its value with the codec.
+// Returns io.EOF when the conveyor is closed, *RecordError along with the record if transferring the value fails,
+// the error of the conveyor with a nil record otherwise.
+func (p *ChannelConveyorProcessor) accept(c ChannelConveyor, stage stage) (*record, interface{}, error) {
+	var rec *record
+
+	if err := c.Accept(&rec); err != nil {
+		return nil, nil, err
+	}
+
+	codec := p.codec
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	var value interface{}
+
+	if err := codec.Transfer(rec.value, &value); err != nil {
+		return rec, nil, &RecordError{
+			OperationIndex: stage.index,
+			OperationName:  stage.name,
+			Input:          rec.raw,
+			Record:         rec.value,
+			Err:            err,
+		}
+	}
+
+	return rec, value, nil
+}
+
+// acceptError wraps the error returned by accept, the record being nil when the conveyor itself failed and
+// there is nothing to settle.
+func acceptError(rec *record, err error) operationError {
+	var t tracking
+	if rec != nil {
+		t = rec.tracking
+	}
+
+	return operationError{err: err, tracking: t}
+}
+
+// emit emits the output of the operation to the next operation, unless the operation failed or does not want
+// to emit the value.
+func (p *ChannelConveyorProcessor) emit(c ChannelConveyor, stage stage, rec *record, input, output interface{}, err error, operationResults chan operationError) {
+	if err == nil {
+		rec.value = output
+
+		err = c.Emit(rec)
+	}
+
+	if err == ErrDoNotEmit {
+		atomic.AddInt64(&p.stats.Dropped, 1)
+
+		p.settle(rec.tracking, dropped, nil)
+
+		return
+	}
+
+	if err != nil {
+		operationResults <- operationError{
+			err: &RecordError{
+				OperationIndex: stage.index,
+				OperationName:  stage.name,
+				Input:          rec.raw,
+				Record:         input,
+				Err:            err,
+			},
+			tracking: rec.tracking,
+		}
+	}
+}
+
+// pendingAck is a record settled, to be acknowledged to the input once the output is committed, negatively when
+// err is not nil.
+type pendingAck struct {
+	receipt interface{}
+	err     error
+}
+
+// settle settles the record, written into the output, dropped or failed with the error, tracking it for the
+// checkpoint and, if the input is ackable, keeping it to be acknowledged once the output is committed (see
+// checkpoint). The records that failed are settled by the main routine, once appended to the dead letter output.
+func (p *ChannelConveyorProcessor) settle(t tracking, s settlement, err error) {
+	p.tracker.settle(t.seq, s)
+
+	if p.ackable == nil {
+		return
+	}
+
+	p.acksMu.Lock()
+	defer p.acksMu.Unlock()
+
+	p.acks = append(p.acks, pendingAck{receipt: t.receipt, err: err})
+}
+
+// pendingAcks returns the records settled to be acknowledged, removing them.
+func (p *ChannelConveyorProcessor) pendingAcks() []pendingAck {
+	p.acksMu.Lock()
+	defer p.acksMu.Unlock()
+
+	acks := p.acks
+	p.acks = nil
+
+	return acks
 }
 
 // outputConveyor takes the result normally after being processed by the operation (In case there is no operation
 // it will take the exact input) and add it to the output.
 // As it is a function that runs in the background - using go routines - error will be sent to the main routine
 // thro the channel `operationResults`.
-func (p *ChannelConveyorProcessor) outputConveyor(ctx context.Context, wg *sync.WaitGroup, output sakio.Output, cc ChannelConveyor, operationResults chan error) {
+func (p *ChannelConveyorProcessor) outputConveyor(ctx context.Context, wg *sync.WaitGroup, index int, output sakio.Output, cc ChannelConveyor, operationResults chan operationError) {
 	wg.Add(1)
 
+	stage := stage{index: index, name: outputStageName}
+
 	go func(ctx context.Context, c ChannelConveyor) {
 		defer func() {
 			c.Close()
@@ -157,22 +794,138 @@ func (p *ChannelConveyorProcessor) outputConveyor(ctx context.Context, wg *sync.
 		}()
 
 		for {
-			var out interface{}
-
-			if err := c.Accept(&out); err != nil {
+			rec, out, err := p.accept(c, stage)
+			if err != nil {
 				if err == io.EOF {
 					break
 				}
 
-				operationResults <- err
+				operationResults <- acceptError(rec, err)
+
+				continue
+			}
+
+			if err := appendOutput(ctx, output, out); err != nil {
+				operationResults <- operationError{
+					err: &RecordError{
+						OperationIndex: stage.index,
+						OperationName:  stage.name,
+						Input:          rec.raw,
+						Record:         out,
+						Err:            err,
+					},
+					tracking: rec.tracking,
+				}
+
+				continue
 			}
 
-			output.Append(ctx, out)
+			atomic.AddInt64(&p.stats.Written, 1)
+
+			p.settle(rec.tracking, written, nil)
 		}
 	}(ctx, cc)
 }
 
+// appendOutput appends the output data to the output, returning the error that made the output data be discarded when
+// the output is a sakio.RecordOutput.
+func appendOutput(ctx context.Context, output sakio.Output, out interface{}) error {
+	if o, ok := output.(sakio.RecordOutput); ok {
+		return o.AppendRecord(ctx, out)
+	}
+
+	output.Append(ctx, out)
+
+	return nil
+}
+
+// WithCodec set the Codec used to transfer the data between operations.
+// JSONCodec is used by default.
+func (p *ChannelConveyorProcessor) WithCodec(codec Codec) *ChannelConveyorProcessor {
+	p.codec = codec
+
+	return p
+}
+
+// WithWorkers set the amount of workers applying each operation concurrently. One worker is used by default.
+//
+// The order of the records is not preserved when using more than one worker, unless WithOrderPreserved is set.
+func (p *ChannelConveyorProcessor) WithWorkers(workers int) *ChannelConveyorProcessor {
+	p.workers = workers
+
+	return p
+}
+
+// WithOrderPreserved set the processor to output the records in the same order they were inputted, even when
+// the operations are applied by more than one worker.
+func (p *ChannelConveyorProcessor) WithOrderPreserved() *ChannelConveyorProcessor {
+	p.orderPreserved = true
+
+	return p
+}
+
+// WithDeadLetter set the output where the records that failed processing are appended to, as *RecordError,
+// so they can be replayed later.
+func (p *ChannelConveyorProcessor) WithDeadLetter(deadLetter sakio.Output) *ChannelConveyorProcessor {
+	p.deadLetter = deadLetter
+
+	return p
+}
+
+// WithErrorPolicy set the ErrorPolicy that decides when the process is aborted because of the errors.
+// ContinueOnError is used by default.
+func (p *ChannelConveyorProcessor) WithErrorPolicy(errorPolicy ErrorPolicy) *ChannelConveyorProcessor {
+	p.errorPolicy = errorPolicy
+
+	return p
+}
+
+// WithCheckpoint set the file the checkpoint is stored into every interval, DefaultCheckpointInterval when zero, and
+// once the output is written. The checkpoint is the position in the input, which must be a sakio.ResumableInput, up
+// to which all the records read are settled, written into the output, dropped or failed, along with the counters.
+//
+// Before storing the checkpoint the output and the dead letter output are committed (see sakio.CommitOutput), or
+// flushed, so the records up to the checkpoint are durable. The records after it are processed again when resuming
+// (see WithResume), once appended into a stream output they could then be written twice. Outputs other than
+// sakio.StreamOutput only get the checkpoint stored once written.
+//
+// The interval also sets how often the output is committed to acknowledge the records to a sakio.AckableInput, the
+// path being empty to only set it.
+func (p *ChannelConveyorProcessor) WithCheckpoint(path string, interval time.Duration) *ChannelConveyorProcessor {
+	p.checkpointFile = path
+	p.checkpointInterval = interval
+
+	return p
+}
+
+// WithResume set the checkpoint to resume the process from, the input being resumed from its position and the
+// counters starting from the ones of the checkpoint (see LoadCheckpoint).
+func (p *ChannelConveyorProcessor) WithResume(checkpoint Checkpoint) *ChannelConveyorProcessor {
+	p.resume = &checkpoint
+
+	return p
+}
+
+// Stats returns the counters of the records processed.
+func (p *ChannelConveyorProcessor) Stats() Stats {
+	return Stats{
+		Read:    atomic.LoadInt64(&p.stats.Read),
+		Written: atomic.LoadInt64(&p.stats.Written),
+		Dropped: atomic.LoadInt64(&p.stats.Dropped),
+		Failed:  atomic.LoadInt64(&p.stats.Failed),
+	}
+}
+
 // Errors returns errors that happen during the process in case any error occurred.
+// Errors processing a record are *RecordError.
 func (p *ChannelConveyorProcessor) Errors() []error {
 	return p.conveyorErrors
 }
+
+// OperationName returns the name of the operation, the name of the function implementing it without
+// the package path.
+func OperationName(op Operation) string {
+	name := runtime.FuncForPC(reflect.ValueOf(op).Pointer()).Name()
+
+	return name[strings.LastIndex(name, "/")+1:]
+}
@@ -2,10 +2,18 @@ package swissarmyknife_test
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	swiss_army_knife "github.com/dohernandez/swiss-army-knife"
 	sakio "github.com/dohernandez/swiss-army-knife/io"
@@ -17,6 +25,10 @@ const stdinInput = `{"id":7064,"lat":48.88340457471041,"lng":2.3952910238105294,
 {"id":11426,"lat":48.927968740518686,"lng":2.2497446977911437,"created_at":"2016-12-14 18:48:10"}
 {"id":1629,"lat":48.83168740132889,"lng":2.2485795413465577,"created_at":"2016-12-14 18:48:11"}`
 
+func failingOperation(_ context.Context, _ interface{}) (interface{}, error) {
+	return nil, errors.New("operation fails")
+}
+
 func TestChannelConveyorProcessor(t *testing.T) {
 	testCases := []struct {
 		scenario   string
@@ -40,16 +52,24 @@ func TestChannelConveyorProcessor(t *testing.T) {
 		{
 			scenario: "Process data unsuccessful, with operation fails",
 			operations: []swiss_army_knife.Operation{
-				func(_ context.Context, _ interface{}) (interface{}, error) {
-					return nil, errors.New("operation fails")
-				},
+				failingOperation,
 			},
 			output: "",
-			errors: []error{
-				errors.New("operation fails"),
-				errors.New("operation fails"),
-				errors.New("operation fails"),
-			},
+			errors: func() []error {
+				var errs []error
+
+				for _, line := range strings.Split(stdinInput, "\n") {
+					errs = append(errs, &swiss_army_knife.RecordError{
+						OperationIndex: 0,
+						OperationName:  swiss_army_knife.OperationName(failingOperation),
+						Input:          line,
+						Record:         line,
+						Err:            errors.New("operation fails"),
+					})
+				}
+
+				return errs
+			}(),
 		},
 	}
 
@@ -79,3 +99,623 @@ func TestChannelConveyorProcessor(t *testing.T) {
 		})
 	}
 }
+
+// blockingInput returns the records and then blocks forever regardless of the context, like os.Stdin does.
+type blockingInput struct {
+	records []interface{}
+}
+
+func (i *blockingInput) Next(_ context.Context) (interface{}, error) {
+	if len(i.records) == 0 {
+		select {}
+	}
+
+	r := i.records[0]
+	i.records = i.records[1:]
+
+	return r, nil
+}
+
+// notifyingOutput collects the output and notifies every time a record is appended.
+type notifyingOutput struct {
+	appended chan interface{}
+	written  []interface{}
+	output   []interface{}
+}
+
+func (o *notifyingOutput) Append(_ context.Context, output interface{}) {
+	o.output = append(o.output, output)
+	o.appended <- output
+}
+
+func (o *notifyingOutput) Write(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	o.written = append(o.written, o.output...)
+
+	return nil
+}
+
+func TestChannelConveyorProcessorContextCancel(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		cancel   func(ctx context.Context) (context.Context, func())
+		err      error
+	}{
+		{
+			scenario: "Process data cancelled, records processed are written",
+			cancel: func(ctx context.Context) (context.Context, func()) {
+				return context.WithCancel(ctx)
+			},
+			err: context.Canceled,
+		},
+		{
+			scenario: "Process data deadline exceeded, records processed are written",
+			cancel: func(ctx context.Context) (context.Context, func()) {
+				ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+
+				return ctx, func() {
+					<-ctx.Done()
+					cancel()
+				}
+			},
+			err: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint
+		t.Run(tc.scenario, func(t *testing.T) {
+			ctx, cancel := tc.cancel(context.TODO())
+
+			input := &blockingInput{records: []interface{}{"1629", "1874"}}
+			output := &notifyingOutput{appended: make(chan interface{}, 2)}
+
+			p := swiss_army_knife.ChannelConveyorProcessor{}
+			p.WithCodec(swiss_army_knife.PassThroughCodec{})
+
+			go func() {
+				<-output.appended
+				<-output.appended
+
+				cancel()
+			}()
+
+			err := p.Process(ctx, input, output, func(_ context.Context, value interface{}) (interface{}, error) {
+				return value, nil
+			})
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, []interface{}{"1629", "1874"}, output.written)
+			assert.Empty(t, p.Errors())
+		})
+	}
+}
+
+// sliceInput returns the records and then io.EOF.
+type sliceInput struct {
+	records []interface{}
+}
+
+func (i *sliceInput) Next(_ context.Context) (interface{}, error) {
+	if len(i.records) == 0 {
+		return nil, io.EOF
+	}
+
+	r := i.records[0]
+	i.records = i.records[1:]
+
+	return r, nil
+}
+
+// collectingOutput collects the output appended.
+type collectingOutput struct {
+	output []interface{}
+}
+
+func (o *collectingOutput) Append(_ context.Context, output interface{}) {
+	o.output = append(o.output, output)
+}
+
+func (o *collectingOutput) Write(_ context.Context) error {
+	return nil
+}
+
+func TestChannelConveyorProcessorWorkers(t *testing.T) {
+	var records []interface{}
+	for i := 0; i < 100; i++ {
+		records = append(records, i)
+	}
+
+	// slowOperation takes longer for the first records, so workers finish them out of order.
+	slowOperation := func(_ context.Context, value interface{}) (interface{}, error) {
+		time.Sleep(time.Duration(100-value.(int)) * 10 * time.Microsecond)
+
+		if value.(int)%10 == 0 {
+			return nil, swiss_army_knife.ErrDoNotEmit
+		}
+
+		return value, nil
+	}
+
+	var expected []interface{}
+	for _, r := range records {
+		if r.(int)%10 != 0 {
+			expected = append(expected, r)
+		}
+	}
+
+	testCases := []struct {
+		scenario string
+		ordered  bool
+	}{
+		{
+			scenario: "Process data successful, with workers",
+		},
+		{
+			scenario: "Process data successful, with workers preserving order",
+			ordered:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint
+		t.Run(tc.scenario, func(t *testing.T) {
+			ctx := context.TODO()
+
+			input := &sliceInput{records: records}
+			output := &collectingOutput{}
+
+			p := swiss_army_knife.ChannelConveyorProcessor{}
+			p.WithCodec(swiss_army_knife.PassThroughCodec{}).
+				WithWorkers(4)
+
+			if tc.ordered {
+				p.WithOrderPreserved()
+			}
+
+			err := p.Process(ctx, input, output, slowOperation, slowOperation)
+			assert.NoError(t, err)
+			assert.Empty(t, p.Errors())
+
+			if tc.ordered {
+				assert.Equal(t, expected, output.output)
+			} else {
+				assert.ElementsMatch(t, expected, output.output)
+			}
+		})
+	}
+}
+
+func TestChannelConveyorProcessorDeadLetter(t *testing.T) {
+	ctx := context.TODO()
+
+	scanner := bufio.NewScanner(strings.NewReader("{invalid json}\n" + stdinInput))
+	input := sakio.NewStdinInput(scanner).
+		WithUnmarshaling(func(_ context.Context, i string) (interface{}, error) {
+			var a interface{}
+
+			if err := json.Unmarshal([]byte(i), &a); err != nil {
+				return nil, err
+			}
+
+			return a, nil
+		})
+
+	output := &collectingOutput{}
+	deadLetter := &collectingOutput{}
+
+	p := swiss_army_knife.ChannelConveyorProcessor{}
+	p.WithDeadLetter(deadLetter)
+
+	err := p.Process(ctx, input, output, func(_ context.Context, value interface{}) (interface{}, error) {
+		if value.(map[string]interface{})["id"] == float64(11426) {
+			return nil, errors.New("operation fails")
+		}
+
+		return value, nil
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, output.output, 2)
+	assert.Len(t, p.Errors(), 2)
+	assert.Len(t, deadLetter.output, 2)
+
+	failed := make(map[int]*swiss_army_knife.RecordError)
+
+	for _, r := range deadLetter.output {
+		rerr, ok := r.(*swiss_army_knife.RecordError)
+		assert.True(t, ok, "dead letter record must be *RecordError")
+
+		failed[rerr.OperationIndex] = rerr
+	}
+
+	// operation failed.
+	rerr := failed[0]
+	assert.Equal(t, strings.Split(stdinInput, "\n")[1], rerr.Input)
+	assert.Equal(t, float64(11426), rerr.Record.(map[string]interface{})["id"])
+	assert.EqualError(t, rerr.Err, "operation fails")
+
+	// reading the input failed, the record was skipped.
+	rerr = failed[swiss_army_knife.InputStage]
+	assert.Equal(t, "input", rerr.OperationName)
+	assert.Equal(t, "{invalid json}", rerr.Input)
+	assert.Nil(t, rerr.Record)
+}
+
+func TestChannelConveyorProcessorOutputMarshalError(t *testing.T) {
+	ctx := context.TODO()
+
+	var buf bytes.Buffer
+
+	// the record that fails to marshal is the only one not written.
+	output := sakio.NewWriterOutput(&buf).
+		WithMarshaling(func(_ context.Context, i interface{}) (string, error) {
+			if i == "2" {
+				return "", errors.New("marshal fails")
+			}
+
+			return i.(string), nil
+		})
+	deadLetter := &collectingOutput{}
+
+	p := swiss_army_knife.ChannelConveyorProcessor{}
+	p.WithCodec(swiss_army_knife.PassThroughCodec{}).
+		WithDeadLetter(deadLetter)
+
+	err := p.Process(ctx, &sliceInput{records: []interface{}{"1", "2", "3"}}, output)
+	assert.NoError(t, err)
+	assert.NoError(t, output.Close(ctx))
+
+	assert.Equal(t, "1\n3\n", buf.String())
+	assert.Equal(t, swiss_army_knife.Stats{Read: 3, Written: 2, Failed: 1}, p.Stats())
+	assert.Equal(t, []interface{}{&swiss_army_knife.RecordError{
+		OperationIndex: 0,
+		OperationName:  "output",
+		Record:         "2",
+		Err:            errors.New("marshal fails"),
+	}}, deadLetter.output)
+}
+
+func TestChannelConveyorProcessorErrorPolicy(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		policy   swiss_army_knife.ErrorPolicy
+		err      error
+	}{
+		{
+			scenario: "Process data aborted on first error",
+			policy:   swiss_army_knife.FailFast,
+			err:      swiss_army_knife.ErrAborted,
+		},
+		{
+			scenario: "Process data aborted after max errors",
+			policy:   swiss_army_knife.ErrorPolicy{MaxErrors: 3},
+			err:      swiss_army_knife.ErrAborted,
+		},
+		{
+			scenario: "Process data aborted after max error rate",
+			policy:   swiss_army_knife.ErrorPolicy{MaxErrorRate: 0.5, MinRecords: 4},
+			err:      swiss_army_knife.ErrAborted,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint
+		t.Run(tc.scenario, func(t *testing.T) {
+			ctx := context.TODO()
+
+			// the input blocks once the records are read, only aborting the process makes it return.
+			input := &blockingInput{records: []interface{}{1, 2, 3, 4, 5, 6}}
+			output := &collectingOutput{}
+
+			p := swiss_army_knife.ChannelConveyorProcessor{}
+			p.WithCodec(swiss_army_knife.PassThroughCodec{}).
+				WithErrorPolicy(tc.policy)
+
+			err := p.Process(ctx, input, output, failingOperation)
+			assert.Equal(t, tc.err, err)
+
+			stats := p.Stats()
+			assert.Equal(t, int64(len(p.Errors())), stats.Failed)
+			assert.True(t, tc.policy.Exceeded(stats.Failed, stats.Read), "error policy must be exceeded")
+			assert.Empty(t, output.output)
+		})
+	}
+}
+
+func TestChannelConveyorProcessorStats(t *testing.T) {
+	ctx := context.TODO()
+
+	input := &sliceInput{records: []interface{}{1, 2, 3, 4, 5, 6}}
+	output := &collectingOutput{}
+
+	p := swiss_army_knife.ChannelConveyorProcessor{}
+	p.WithCodec(swiss_army_knife.PassThroughCodec{})
+
+	err := p.Process(ctx, input, output, func(_ context.Context, value interface{}) (interface{}, error) {
+		switch value.(int) % 3 {
+		case 0:
+			return nil, swiss_army_knife.ErrDoNotEmit
+		case 1:
+			return nil, errors.New("operation fails")
+		default:
+			return value, nil
+		}
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, swiss_army_knife.Stats{Read: 6, Written: 2, Dropped: 2, Failed: 2}, p.Stats())
+}
+
+func TestChannelConveyorProcessorCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir) // nolint:errcheck
+
+	path := filepath.Join(dir, "rides.ndjson")
+	checkpointFile := filepath.Join(dir, "checkpoint.json")
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("1\n2\n3\n4\n5\n6\n"), 0644))
+
+	// the process is cancelled once the third record is appended.
+	ctx, cancel := context.WithCancel(context.TODO())
+
+	output := &notifyingOutput{appended: make(chan interface{}, 6)}
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			<-output.appended
+		}
+
+		cancel()
+	}()
+
+	hold := make(chan struct{})
+
+	operation := func(ctx context.Context, value interface{}) (interface{}, error) {
+		switch value.(string) {
+		case "2":
+			return nil, swiss_army_knife.ErrDoNotEmit
+		case "5":
+			// holds the first process until it is cancelled.
+			select {
+			case <-ctx.Done():
+			case <-hold:
+			}
+
+			return nil, errors.New("operation fails")
+		}
+
+		return value, nil
+	}
+
+	p := swiss_army_knife.ChannelConveyorProcessor{}
+	p.WithCodec(swiss_army_knife.PassThroughCodec{}).
+		WithCheckpoint(checkpointFile, time.Millisecond)
+
+	err = p.Process(ctx, sakio.NewFileInput(path), output, operation)
+	assert.Equal(t, context.Canceled, err)
+
+	checkpoint, err := swiss_army_knife.LoadCheckpoint(checkpointFile)
+	assert.NoError(t, err)
+
+	// the checkpoint is the position of the last record settled in order, the records after it are processed again.
+	record := checkpoint.Position.Record
+	assert.True(t, record >= 4, "record %d", record)
+	assert.Equal(t, sakio.Position{File: path, Offset: int64(2 * record), Record: record}, checkpoint.Position)
+	assert.Equal(t, int64(record), checkpoint.Stats.Read)
+
+	written := output.written[:checkpoint.Stats.Written]
+
+	// resumed from the checkpoint, the records are written once.
+	close(hold)
+
+	output = &notifyingOutput{appended: make(chan interface{}, 6)}
+
+	p = swiss_army_knife.ChannelConveyorProcessor{}
+	p.WithCodec(swiss_army_knife.PassThroughCodec{}).
+		WithCheckpoint(checkpointFile, time.Millisecond).
+		WithResume(*checkpoint)
+
+	err = p.Process(context.TODO(), sakio.NewFileInput(path), output, operation)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []interface{}{"1", "3", "4", "6"}, append(written, output.written...))
+	assert.Equal(t, swiss_army_knife.Stats{Read: 6, Written: 4, Dropped: 1, Failed: 1}, p.Stats())
+
+	checkpoint, err = swiss_army_knife.LoadCheckpoint(checkpointFile)
+	assert.NoError(t, err)
+	assert.Equal(t, &swiss_army_knife.Checkpoint{
+		Position: sakio.Position{File: path, Offset: 12, Record: 6},
+		Stats:    swiss_army_knife.Stats{Read: 6, Written: 4, Dropped: 1, Failed: 1},
+	}, checkpoint)
+}
+
+func TestChannelConveyorProcessorCheckpointNotResumable(t *testing.T) {
+	p := swiss_army_knife.ChannelConveyorProcessor{}
+	p.WithCheckpoint("checkpoint.json", 0)
+
+	err := p.Process(context.TODO(), &sliceInput{}, &collectingOutput{})
+	assert.Equal(t, swiss_army_knife.ErrNotResumable, err)
+}
+
+func TestLoadCheckpoint(t *testing.T) {
+	checkpoint, err := swiss_army_knife.LoadCheckpoint("not-found.json")
+	assert.NoError(t, err)
+	assert.Nil(t, checkpoint)
+
+	_, err = swiss_army_knife.LoadCheckpoint("processor_test.go")
+	assert.IsType(t, &os.PathError{}, err)
+}
+
+// ackingInput returns the records and then io.EOF, keeping the receipts acknowledged, the records being their
+// receipt. The records of type error are returned as *sakio.InvalidRecordError.
+type ackingInput struct {
+	records []interface{}
+	receipt interface{}
+
+	mu     sync.Mutex
+	acked  []interface{}
+	nacked map[interface{}]error
+	// acking is called with the receipt of every record acknowledged, if set.
+	acking func(receipt interface{})
+}
+
+func (i *ackingInput) Next(_ context.Context) (interface{}, error) {
+	if len(i.records) == 0 {
+		return nil, io.EOF
+	}
+
+	r := i.records[0]
+	i.records = i.records[1:]
+	i.receipt = r
+
+	if err, ok := r.(error); ok {
+		return nil, &sakio.InvalidRecordError{Err: err}
+	}
+
+	return r, nil
+}
+
+func (i *ackingInput) Receipt() interface{} {
+	return i.receipt
+}
+
+func (i *ackingInput) Ack(_ context.Context, receipt interface{}) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.acking != nil {
+		i.acking(receipt)
+	}
+
+	i.acked = append(i.acked, receipt)
+}
+
+func (i *ackingInput) Nack(_ context.Context, receipt interface{}, err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.nacked == nil {
+		i.nacked = make(map[interface{}]error)
+	}
+
+	i.nacked[receipt] = err
+}
+
+func TestChannelConveyorProcessorAck(t *testing.T) {
+	ctx := context.TODO()
+
+	invalid := errors.New("invalid record")
+
+	input := &ackingInput{records: []interface{}{1, 2, 3, invalid, 4, 5, 6}}
+	output := &collectingOutput{}
+
+	p := swiss_army_knife.ChannelConveyorProcessor{}
+	p.WithCodec(swiss_army_knife.PassThroughCodec{}).
+		WithWorkers(4)
+
+	err := p.Process(ctx, input, output, func(_ context.Context, value interface{}) (interface{}, error) {
+		switch value.(int) % 3 {
+		case 0:
+			return nil, swiss_army_knife.ErrDoNotEmit
+		case 1:
+			return nil, errors.New("operation fails")
+		default:
+			return value, nil
+		}
+	})
+	assert.NoError(t, err)
+
+	// the records written and dropped are acknowledged, the ones that failed negatively acknowledged.
+	assert.ElementsMatch(t, []interface{}{2, 3, 5, 6}, input.acked)
+	assert.Len(t, input.nacked, 3)
+	assert.Equal(t, &swiss_army_knife.RecordError{
+		OperationIndex: swiss_army_knife.InputStage,
+		OperationName:  "input",
+		Err:            invalid,
+	}, input.nacked[invalid])
+
+	for _, receipt := range []interface{}{1, 4} {
+		rerr, ok := input.nacked[receipt].(*swiss_army_knife.RecordError)
+		if assert.True(t, ok) {
+			assert.Equal(t, receipt, rerr.Record)
+			assert.EqualError(t, rerr.Err, "operation fails")
+		}
+	}
+}
+
+// committingOutput collects the output appended, keeping the one committed apart.
+type committingOutput struct {
+	mu        sync.Mutex
+	output    []interface{}
+	committed []interface{}
+}
+
+func (o *committingOutput) Append(_ context.Context, output interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.output = append(o.output, output)
+}
+
+func (o *committingOutput) Write(_ context.Context) error {
+	return nil
+}
+
+func (o *committingOutput) Flush(_ context.Context) error {
+	return nil
+}
+
+func (o *committingOutput) Commit(_ context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.committed = append(o.committed, o.output...)
+	o.output = nil
+
+	return nil
+}
+
+func (o *committingOutput) Close(_ context.Context) error {
+	return nil
+}
+
+func (o *committingOutput) isCommitted(record interface{}) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, r := range o.committed {
+		if r == record {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestChannelConveyorProcessorAckCommitted(t *testing.T) {
+	output := &committingOutput{}
+
+	input := &ackingInput{records: []interface{}{1, 2, 3, 4, 5, 6}}
+	input.acking = func(receipt interface{}) {
+		assert.True(t, output.isCommitted(receipt), "record %v acknowledged before being committed", receipt)
+	}
+
+	p := swiss_army_knife.ChannelConveyorProcessor{}
+	p.WithCodec(swiss_army_knife.PassThroughCodec{}).
+		WithCheckpoint("", time.Millisecond)
+
+	err := p.Process(context.TODO(), input, output, func(_ context.Context, value interface{}) (interface{}, error) {
+		time.Sleep(time.Millisecond)
+
+		return value, nil
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []interface{}{1, 2, 3, 4, 5, 6}, output.committed)
+	assert.ElementsMatch(t, []interface{}{1, 2, 3, 4, 5, 6}, input.acked)
+}
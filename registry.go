@@ -0,0 +1,546 @@
+package swissarmyknife
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownOperation is returned when no operation factory is registered under the name.
+	ErrUnknownOperation = errors.New("unknown operation")
+
+	// ErrOperationRegistered is returned when an operation factory is already registered under the name.
+	ErrOperationRegistered = errors.New("operation already registered")
+
+	// ErrInvalidOperationFactory is returned when the operation factory is not valid.
+	ErrInvalidOperationFactory = errors.New("invalid operation factory")
+)
+
+// OperationFactory creates an Operation from its configuration.
+//
+// The configuration is a struct whose exported fields tagged with `config` are the parameters of the operation.
+// The tag holds the parameter name, followed by `,required` when the parameter is required, and the `description`
+// tag describes it. Parameters can be string, bool, int, int64, float64, time.Duration, []string, map[string]string
+// or [][2]string, the key/value pairs of a mapping in order.
+//
+// Common initialization example:
+//
+//      type RenameConfig struct {
+//			From string `config:"from,required" description:"Key to rename."`
+//			To   string `config:"to,required" description:"New key name."`
+//		}
+//
+//      factory := OperationFactory{
+//			Name:        "rename",
+//			Description: "Renames a key.",
+//			Config:      RenameConfig{},
+//			New: func(ctx context.Context, config interface{}) (Operation, error) {
+//				c := config.(*RenameConfig)
+//
+//				return newRenameOperation(ctx, c.From, c.To), nil
+//			},
+//		}
+//
+type OperationFactory struct {
+	// Name is the name the operation is instantiated by.
+	Name string
+	// Description describes what the operation does.
+	Description string
+	// Config is the zero value of the configuration struct.
+	Config interface{}
+	// New creates the Operation, config is a pointer to a configuration struct filled with the parameters.
+	New func(ctx context.Context, config interface{}) (Operation, error)
+}
+
+// OperationParam describes a parameter of an operation.
+type OperationParam struct {
+	Name        string
+	Type        string
+	Required    bool
+	Description string
+
+	field int
+}
+
+// Params returns the parameters of the operation, as declared by its configuration struct.
+func (f OperationFactory) Params() []OperationParam {
+	params, _ := configParams(f.Config) // nolint:errcheck
+
+	return params
+}
+
+// KeyValue is an entry of a mapping given as parameter value in order (see OperationRegistry.NewOperation), i.e.
+// decoded from a YAML mapping, so the [][2]string parameters keep the order of the pairs.
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// OperationConfigError is returned when the parameters of an operation are not valid.
+type OperationConfigError struct {
+	Operation string
+	// Param is the parameter that is not valid, empty if the error is not related to one parameter.
+	Param string
+	Err   error
+}
+
+// Error returns the error message.
+func (e *OperationConfigError) Error() string {
+	if e.Param == "" {
+		return fmt.Sprintf("%s: %s", e.Operation, e.Err)
+	}
+
+	return fmt.Sprintf("%s: parameter %q: %s", e.Operation, e.Param, e.Err)
+}
+
+// Cause returns the error behind the configuration error.
+func (e *OperationConfigError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the error behind the configuration error.
+func (e *OperationConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ParamError returns an OperationConfigError for the parameter, to be returned by OperationFactory.New when the
+// value of a parameter is not valid.
+func ParamError(param string, err error) error {
+	return &OperationConfigError{Param: param, Err: err}
+}
+
+// OperationRegistry holds operation factories by name. It is safe for concurrent use.
+type OperationRegistry struct {
+	mu        sync.RWMutex
+	factories map[string]OperationFactory
+}
+
+// NewOperationRegistry creates an empty OperationRegistry.
+func NewOperationRegistry() *OperationRegistry {
+	return &OperationRegistry{factories: make(map[string]OperationFactory)}
+}
+
+// Register registers the operation factory under its name.
+//
+// ErrInvalidOperationFactory is returned if the factory has no name, no New function or its configuration is not
+// a struct with valid parameters.
+// ErrOperationRegistered is returned if a factory is already registered under the name.
+func (r *OperationRegistry) Register(f OperationFactory) error {
+	if f.Name == "" || f.New == nil {
+		return ErrInvalidOperationFactory
+	}
+
+	if _, err := configParams(f.Config); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.factories[f.Name]; ok {
+		return ErrOperationRegistered
+	}
+
+	r.factories[f.Name] = f
+
+	return nil
+}
+
+// Factory returns the operation factory registered under the name, false if there is none.
+func (r *OperationRegistry) Factory(name string) (OperationFactory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	f, ok := r.factories[name]
+
+	return f, ok
+}
+
+// Factories returns the operation factories registered, sorted by name.
+func (r *OperationRegistry) Factories() []OperationFactory {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	factories := make([]OperationFactory, 0, len(r.factories))
+	for _, f := range r.factories {
+		factories = append(factories, f)
+	}
+
+	sort.Slice(factories, func(i, j int) bool {
+		return factories[i].Name < factories[j].Name
+	})
+
+	return factories
+}
+
+// NewOperation creates the Operation registered under the name, configured with the parameters. The mappings are
+// given as map[string]interface{} or, to keep their order, as []KeyValue.
+//
+// ErrUnknownOperation is returned if no operation factory is registered under the name.
+// OperationConfigError is returned if the parameters are not valid.
+func (r *OperationRegistry) NewOperation(ctx context.Context, name string, params map[string]interface{}) (Operation, error) {
+	f, ok := r.Factory(name)
+	if !ok {
+		return nil, ErrUnknownOperation
+	}
+
+	config, err := decodeConfig(f.Config, params)
+	if err != nil {
+		err.(*OperationConfigError).Operation = name
+
+		return nil, err
+	}
+
+	op, err := f.New(ctx, config)
+	if err != nil {
+		cerr, ok := err.(*OperationConfigError)
+		if !ok {
+			cerr = &OperationConfigError{Err: err}
+		}
+
+		cerr.Operation = name
+
+		return nil, cerr
+	}
+
+	return op, nil
+}
+
+// DefaultOperationRegistry is the registry holding the built-in operations, the CLI and the pipeline definition
+// files instantiate operations from it.
+// nolint:gochecknoglobals
+var DefaultOperationRegistry = NewOperationRegistry()
+
+// RegisterOperation registers the operation factory in the DefaultOperationRegistry.
+func RegisterOperation(f OperationFactory) error {
+	return DefaultOperationRegistry.Register(f)
+}
+
+// NewRegisteredOperation creates the Operation registered in the DefaultOperationRegistry under the name.
+func NewRegisteredOperation(ctx context.Context, name string, params map[string]interface{}) (Operation, error) {
+	return DefaultOperationRegistry.NewOperation(ctx, name, params)
+}
+
+// configParams returns the parameters declared by the configuration struct.
+func configParams(config interface{}) ([]OperationParam, error) {
+	t := reflect.TypeOf(config)
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, ErrInvalidOperationFactory
+	}
+
+	var params []OperationParam
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag, ok := field.Tag.Lookup("config")
+		if !ok || field.PkgPath != "" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+
+		typ := paramType(field.Type)
+		if parts[0] == "" || typ == "" {
+			return nil, ErrInvalidOperationFactory
+		}
+
+		params = append(params, OperationParam{
+			Name:        parts[0],
+			Type:        typ,
+			Required:    len(parts) > 1 && parts[1] == "required",
+			Description: field.Tag.Get("description"),
+			field:       i,
+		})
+	}
+
+	return params, nil
+}
+
+// durationType is the reflect type of time.Duration.
+// nolint:gochecknoglobals
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// paramType returns the name of the parameter type, empty if the type is not supported.
+func paramType(t reflect.Type) string {
+	if t == durationType {
+		return "duration"
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+		return t.Kind().String()
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			return "[]string"
+		}
+
+		if isPairs(t) {
+			return "[][2]string"
+		}
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String {
+			return "map[string]string"
+		}
+	}
+
+	return ""
+}
+
+// decodeConfig returns a pointer to a copy of the configuration struct filled with the parameters.
+//
+// OperationConfigError is returned if a parameter is unknown, required and missing, or its value does not fit.
+func decodeConfig(config interface{}, values map[string]interface{}) (interface{}, error) {
+	params, err := configParams(config)
+	if err != nil {
+		return nil, &OperationConfigError{Err: err}
+	}
+
+	known := make(map[string]bool, len(params))
+	for _, p := range params {
+		known[p.Name] = true
+	}
+
+	for name := range values {
+		if !known[name] {
+			return nil, &OperationConfigError{Param: name, Err: errors.New("unknown parameter")}
+		}
+	}
+
+	c := reflect.New(reflect.TypeOf(config))
+	c.Elem().Set(reflect.ValueOf(config))
+
+	for _, p := range params {
+		value, ok := values[p.Name]
+		if !ok || value == nil {
+			if p.Required {
+				return nil, &OperationConfigError{Param: p.Name, Err: errors.New("missing required parameter")}
+			}
+
+			continue
+		}
+
+		if err := setParam(c.Elem().Field(p.field), value); err != nil {
+			return nil, &OperationConfigError{Param: p.Name, Err: err}
+		}
+	}
+
+	return c.Interface(), nil
+}
+
+// setParam sets the field to the value, converting it to the field type.
+func setParam(field reflect.Value, value interface{}) error {
+	mismatch := fmt.Errorf("%T does not fit %s", value, paramType(field.Type()))
+
+	if field.Type() == durationType {
+		s, ok := value.(string)
+		if !ok {
+			return mismatch
+		}
+
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		s, ok := scalarString(value)
+		if !ok {
+			return mismatch
+		}
+
+		field.SetString(s)
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return mismatch
+		}
+
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, ok := numberValue(value)
+		if !ok || n != math.Trunc(n) {
+			return mismatch
+		}
+
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		n, ok := numberValue(value)
+		if !ok {
+			return mismatch
+		}
+
+		field.SetFloat(n)
+	case reflect.Slice:
+		if isPairs(field.Type()) {
+			return setPairs(field, value, mismatch)
+		}
+
+		items, ok := value.([]interface{})
+		if !ok {
+			if ss, isStrings := value.([]string); isStrings {
+				field.Set(reflect.ValueOf(ss))
+
+				return nil
+			}
+
+			return mismatch
+		}
+
+		ss := make([]string, len(items))
+		for i, item := range items {
+			if ss[i], ok = scalarString(item); !ok {
+				return fmt.Errorf("item %d must be a string, number or bool, got %T", i, item)
+			}
+		}
+
+		field.Set(reflect.ValueOf(ss))
+	case reflect.Map:
+		if kvs, isOrdered := value.([]KeyValue); isOrdered {
+			pairs, err := orderedPairs(kvs)
+			if err != nil {
+				return err
+			}
+
+			ms := make(map[string]string, len(pairs))
+			for _, pair := range pairs {
+				ms[pair[0]] = pair[1]
+			}
+
+			field.Set(reflect.ValueOf(ms))
+
+			return nil
+		}
+
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			if ms, isStrings := value.(map[string]string); isStrings {
+				field.Set(reflect.ValueOf(ms))
+
+				return nil
+			}
+
+			return mismatch
+		}
+
+		ms := make(map[string]string, len(m))
+		for k, v := range m {
+			if ms[k], ok = scalarString(v); !ok {
+				return fmt.Errorf("value of %q must be a string, number or bool, got %T", k, v)
+			}
+		}
+
+		field.Set(reflect.ValueOf(ms))
+	}
+
+	return nil
+}
+
+// isPairs reports whether the type is [][2]string.
+func isPairs(t reflect.Type) bool {
+	e := t.Elem()
+
+	return e.Kind() == reflect.Array && e.Len() == 2 && e.Elem().Kind() == reflect.String
+}
+
+// setPairs sets the [][2]string field to the pairs of the value, [][2]string or a mapping in order ([]KeyValue).
+// A map, having no order, gives the pairs sorted by key.
+//
+// Error is returned if a key is duplicated or a value is not a string, number or bool.
+func setPairs(field reflect.Value, value interface{}, mismatch error) error {
+	var kvs []KeyValue
+
+	switch v := value.(type) {
+	case [][2]string:
+		for _, pair := range v {
+			kvs = append(kvs, KeyValue{Key: pair[0], Value: pair[1]})
+		}
+	case []KeyValue:
+		kvs = v
+	case map[string]interface{}:
+		for _, k := range sortedMapKeys(v) {
+			kvs = append(kvs, KeyValue{Key: k, Value: v[k]})
+		}
+	case map[string]string:
+		for k, s := range v {
+			kvs = append(kvs, KeyValue{Key: k, Value: s})
+		}
+
+		sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	default:
+		return mismatch
+	}
+
+	pairs, err := orderedPairs(kvs)
+	if err != nil {
+		return err
+	}
+
+	field.Set(reflect.ValueOf(pairs))
+
+	return nil
+}
+
+// orderedPairs returns the key/value pairs of the mapping in order, the values as strings.
+//
+// Error is returned if a key is duplicated or a value is not a string, number or bool.
+func orderedPairs(kvs []KeyValue) ([][2]string, error) {
+	pairs := make([][2]string, len(kvs))
+	seen := make(map[string]bool, len(kvs))
+
+	for i, kv := range kvs {
+		if seen[kv.Key] {
+			return nil, fmt.Errorf("duplicate key %q", kv.Key)
+		}
+
+		seen[kv.Key] = true
+
+		s, ok := scalarString(kv.Value)
+		if !ok {
+			return nil, fmt.Errorf("value of %q must be a string, number or bool, got %T", kv.Key, kv.Value)
+		}
+
+		pairs[i] = [2]string{kv.Key, s}
+	}
+
+	return pairs, nil
+}
+
+// sortedMapKeys returns the keys of the map sorted.
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// scalarString returns the string representation of a string, number or bool, false for other values.
+func scalarString(v interface{}) (string, bool) {
+	switch v.(type) {
+	case string, bool:
+		return fmt.Sprint(v), true
+	}
+
+	if _, ok := numberValue(v); ok {
+		return stringValue(v), true
+	}
+
+	return "", false
+}
@@ -0,0 +1,165 @@
+package swissarmyknife_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	swiss_army_knife "github.com/dohernandez/swiss-army-knife"
+	"github.com/stretchr/testify/assert"
+)
+
+type testConfig struct {
+	Name     string            `config:"name,required" description:"Name."`
+	Enabled  bool              `config:"enabled"`
+	Count    int               `config:"count"`
+	Limit    int64             `config:"limit"`
+	Rate     float64           `config:"rate"`
+	Interval time.Duration     `config:"interval"`
+	Keys     []string          `config:"keys"`
+	Pairs    map[string]string `config:"pairs"`
+	Ordered  [][2]string       `config:"ordered"`
+	Ignored  string
+}
+
+func testFactory(name string, configs chan<- *testConfig) swiss_army_knife.OperationFactory {
+	return swiss_army_knife.OperationFactory{
+		Name:        name,
+		Description: "Test operation.",
+		Config:      testConfig{Count: 1},
+		New: func(ctx context.Context, config interface{}) (swiss_army_knife.Operation, error) {
+			c := config.(*testConfig)
+			if c.Name == "invalid" {
+				return nil, swiss_army_knife.ParamError("name", errors.New("invalid name"))
+			}
+
+			if c.Name == "broken" {
+				return nil, errors.New("broken")
+			}
+
+			configs <- c
+
+			return func(ctx context.Context, value interface{}) (interface{}, error) {
+				return value, nil
+			}, nil
+		},
+	}
+}
+
+func TestOperationRegistryRegister(t *testing.T) {
+	r := swiss_army_knife.NewOperationRegistry()
+
+	assert.NoError(t, r.Register(testFactory("b", nil)))
+	assert.NoError(t, r.Register(testFactory("a", nil)))
+	assert.Equal(t, swiss_army_knife.ErrOperationRegistered, r.Register(testFactory("a", nil)))
+
+	invalid := testFactory("", nil)
+	assert.Equal(t, swiss_army_knife.ErrInvalidOperationFactory, r.Register(invalid))
+
+	invalid = testFactory("c", nil)
+	invalid.Config = "not a struct"
+	assert.Equal(t, swiss_army_knife.ErrInvalidOperationFactory, r.Register(invalid))
+
+	invalid.Config = struct {
+		Unsupported []int `config:"unsupported"`
+	}{}
+	assert.Equal(t, swiss_army_knife.ErrInvalidOperationFactory, r.Register(invalid))
+
+	factories := r.Factories()
+	assert.Len(t, factories, 2)
+	assert.Equal(t, "a", factories[0].Name)
+	assert.Equal(t, "b", factories[1].Name)
+
+	f, ok := r.Factory("a")
+	assert.True(t, ok)
+	assert.Equal(t, []swiss_army_knife.OperationParam{
+		{Name: "name", Type: "string", Required: true, Description: "Name."},
+		{Name: "enabled", Type: "bool"},
+		{Name: "count", Type: "int"},
+		{Name: "limit", Type: "int64"},
+		{Name: "rate", Type: "float64"},
+		{Name: "interval", Type: "duration"},
+		{Name: "keys", Type: "[]string"},
+		{Name: "pairs", Type: "map[string]string"},
+		{Name: "ordered", Type: "[][2]string"},
+	}, stripFields(f.Params()))
+
+	_, ok = r.Factory("c")
+	assert.False(t, ok)
+}
+
+// stripFields returns the params comparable with the ones declared in the tests.
+func stripFields(params []swiss_army_knife.OperationParam) []swiss_army_knife.OperationParam {
+	r := make([]swiss_army_knife.OperationParam, len(params))
+	for i, p := range params {
+		r[i] = swiss_army_knife.OperationParam{Name: p.Name, Type: p.Type, Required: p.Required, Description: p.Description}
+	}
+
+	return r
+}
+
+func TestOperationRegistryNewOperation(t *testing.T) {
+	configs := make(chan *testConfig, 1)
+
+	r := swiss_army_knife.NewOperationRegistry()
+	assert.NoError(t, r.Register(testFactory("test", configs)))
+
+	ctx := context.TODO()
+
+	op, err := r.NewOperation(ctx, "test", map[string]interface{}{
+		"name":     "op",
+		"enabled":  true,
+		"limit":    10,
+		"rate":     0.5,
+		"interval": "1s",
+		"keys":     []interface{}{"a", 1},
+		"pairs":    map[string]interface{}{"a": "b", "c": 2.5},
+		"ordered":  []swiss_army_knife.KeyValue{{Key: "c", Value: 2.5}, {Key: "a", Value: "b"}},
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, op)
+
+	assert.Equal(t, &testConfig{
+		Name:     "op",
+		Enabled:  true,
+		Count:    1,
+		Limit:    10,
+		Rate:     0.5,
+		Interval: time.Second,
+		Keys:     []string{"a", "1"},
+		Pairs:    map[string]string{"a": "b", "c": "2.5"},
+		Ordered:  [][2]string{{"c", "2.5"}, {"a", "b"}},
+	}, <-configs)
+}
+
+func TestOperationRegistryNewOperationInvalid(t *testing.T) {
+	r := swiss_army_knife.NewOperationRegistry()
+	assert.NoError(t, r.Register(testFactory("test", nil)))
+
+	testCases := []struct {
+		scenario string
+		name     string
+		params   map[string]interface{}
+		err      string
+	}{
+		{scenario: "Unknown operation", name: "unknown", err: "unknown operation"},
+		{scenario: "Unknown parameter", name: "test", params: map[string]interface{}{"name": "a", "other": 1}, err: `test: parameter "other": unknown parameter`},
+		{scenario: "Missing required parameter", name: "test", params: map[string]interface{}{"count": 1}, err: `test: parameter "name": missing required parameter`},
+		{scenario: "String mismatch", name: "test", params: map[string]interface{}{"name": []interface{}{}}, err: `test: parameter "name": []interface {} does not fit string`},
+		{scenario: "Bool mismatch", name: "test", params: map[string]interface{}{"name": "a", "enabled": "yes"}, err: `test: parameter "enabled": string does not fit bool`},
+		{scenario: "Int mismatch", name: "test", params: map[string]interface{}{"name": "a", "count": 1.5}, err: `test: parameter "count": float64 does not fit int`},
+		{scenario: "Invalid duration", name: "test", params: map[string]interface{}{"name": "a", "interval": "soon"}, err: `test: parameter "interval": time: invalid duration "soon"`},
+		{scenario: "Slice item mismatch", name: "test", params: map[string]interface{}{"name": "a", "keys": []interface{}{[]interface{}{}}}, err: `test: parameter "keys": item 0 must be a string, number or bool, got []interface {}`},
+		{scenario: "Parameter error", name: "test", params: map[string]interface{}{"name": "invalid"}, err: `test: parameter "name": invalid name`},
+		{scenario: "Factory error", name: "test", params: map[string]interface{}{"name": "broken"}, err: "test: broken"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // Pinning ranged variable, more info: https://github.com/kyoh86/scopelint.
+		t.Run(tc.scenario, func(t *testing.T) {
+			_, err := r.NewOperation(context.TODO(), tc.name, tc.params)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}